@@ -2,56 +2,94 @@ package appstore
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/url"
+	"time"
+
+	"github.com/brainleap/iap"
 )
 
 const (
-	productionBaseURL = "https://buy.itunes.apple.com/verifyReceipt"
-	sandboxBaseURL    = "https://sandbox.itunes.apple.com/verifyReceipt"
+	productionBaseURL   = "https://buy.itunes.apple.com"
+	sandboxBaseURL      = "https://sandbox.itunes.apple.com"
+	verifyReceiptPath   = "/verifyReceipt"
+	defaultMaxRetries   = 2
+	defaultRetryBackoff = 500 * time.Millisecond
 )
 
 // Mode is the data type for verification mode.
 type Mode int
 
-// List of verification modes.
+// List of verification modes. AutoMode verifies against production first and
+// falls back to sandbox for sandbox receipts, as Apple recommends.
 const (
 	ProductionMode Mode = 0
 	SandboxMode    Mode = 1
+	AutoMode       Mode = 2
 )
 
 // NewClient creates a new AppStore client.
-func NewClient(mode Mode) (*Client, error) {
-	return NewClientWithProxy(mode, "")
+func NewClient(mode Mode, opts ...ClientOption) (*Client, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Client{
+		Client:        o.Client(),
+		Mode:          mode,
+		MaxRetries:    defaultMaxRetries,
+		RetryBackoff:  defaultRetryBackoff,
+		ProductionURL: o.productionURL,
+		SandboxURL:    o.sandboxURL,
+	}, nil
 }
 
 // NewClientWithProxy creates a new AppStore client with a proxy.
+//
+// Deprecated: Use NewClient with WithProxy.
 func NewClientWithProxy(mode Mode, proxy string) (*Client, error) {
-	c := &http.Client{}
-
 	if proxy == "" {
-		proxyURL, err := url.Parse(proxy)
-		if err != nil {
-			return nil, err
-		}
+		return NewClient(mode)
+	}
 
-		c.Transport = &http.Transport{
-			Proxy: http.ProxyURL(proxyURL),
-		}
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return nil, err
 	}
 
-	return &Client{Client: c, Mode: mode}, nil
+	return NewClient(mode, WithProxy(proxyURL))
 }
 
 // Client provides AppStore in-app billing API.
+//
+// Verification is retried up to MaxRetries times while the App Store reports
+// a retryable error or the request fails with a network error, a 5xx or 429
+// status or an undecodable body, waiting RetryBackoff before the first retry
+// and twice as long before each following one.
+//
+// ProductionURL and SandboxURL default to the App Store's verifyReceipt
+// hosts.
 type Client struct {
-	Client *http.Client
-	Mode   Mode
+	Client        *http.Client
+	Mode          Mode
+	MaxRetries    int
+	RetryBackoff  time.Duration
+	ProductionURL string
+	SandboxURL    string
 }
 
-// Verify validates a purchase receipt.
+// Verify validates a purchase receipt. The mode of the environment that
+// handled the receipt is reported in Response.Mode.
 func (c *Client) Verify(receipt, password string) (*Response, error) {
+	return c.VerifyContext(context.Background(), receipt, password)
+}
+
+// VerifyContext is like Verify but takes a context.
+func (c *Client) VerifyContext(ctx context.Context, receipt, password string) (*Response, error) {
 	body := struct {
 		ReceiptData            string `json:"receipt-data"`
 		Password               string `json:"password"`
@@ -67,14 +105,82 @@ func (c *Client) Verify(receipt, password string) (*Response, error) {
 		return nil, err
 	}
 
-	var url string
-	if c.Mode == ProductionMode {
+	if c.Mode != AutoMode {
+		return c.verify(ctx, c.Mode, reqBody)
+	}
+
+	r, err := c.verify(ctx, ProductionMode, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Status == statusSandboxReceipt {
+		return c.verify(ctx, SandboxMode, reqBody)
+	}
+
+	return r, nil
+}
+
+func (c *Client) verify(ctx context.Context, mode Mode, reqBody []byte) (*Response, error) {
+	backoff := c.RetryBackoff
+
+	for attempt := 0; ; attempt++ {
+		r, err := c.post(ctx, mode, reqBody)
+		if attempt >= c.MaxRetries {
+			return r, err
+		}
+
+		if err != nil {
+			if !retryable(ctx, err) {
+				return nil, err
+			}
+		} else if !r.retryable() {
+			return r, nil
+		}
+
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+
+		backoff *= 2
+	}
+}
+
+// retryable reports whether a failed verifyReceipt request may be retried.
+// Errors other than store errors are network or decode errors.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var e *iap.Error
+	if errors.As(err, &e) {
+		return e.Retryable
+	}
+
+	return true
+}
+
+func (c *Client) post(ctx context.Context, mode Mode, reqBody []byte) (*Response, error) {
+	url := c.ProductionURL
+	if url == "" {
 		url = productionBaseURL
-	} else {
-		url = sandboxBaseURL
 	}
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(reqBody))
+	if mode == SandboxMode {
+		url = c.SandboxURL
+		if url == "" {
+			url = sandboxBaseURL
+		}
+	}
+
+	url += verifyReceiptPath
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(reqBody))
 	if err != nil {
 		return nil, err
 	}
@@ -87,7 +193,11 @@ func (c *Client) Verify(receipt, password string) (*Response, error) {
 	}
 	defer res.Body.Close()
 
-	var r Response
+	if res.StatusCode != http.StatusOK {
+		return nil, newError(res)
+	}
+
+	r := Response{Mode: mode}
 
 	decoder := json.NewDecoder(res.Body)
 	if err := decoder.Decode(&r); err != nil {
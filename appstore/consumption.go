@@ -0,0 +1,273 @@
+package appstore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// AccountTenure is the data type for customer account age ranges.
+type AccountTenure int
+
+// List of account tenures.
+const (
+	ATUndeclared  AccountTenure = 0
+	ATUpTo3Days   AccountTenure = 1
+	ATUpTo10Days  AccountTenure = 2
+	ATUpTo30Days  AccountTenure = 3
+	ATUpTo90Days  AccountTenure = 4
+	ATUpTo180Days AccountTenure = 5
+	ATUpTo365Days AccountTenure = 6
+	ATOver365Days AccountTenure = 7
+)
+
+// ConsumptionStatus is the data type for in-app purchase consumption
+// statuses.
+type ConsumptionStatus int
+
+// List of consumption statuses.
+const (
+	CSUndeclared        ConsumptionStatus = 0
+	CSNotConsumed       ConsumptionStatus = 1
+	CSPartiallyConsumed ConsumptionStatus = 2
+	CSFullyConsumed     ConsumptionStatus = 3
+)
+
+// DeliveryStatus is the data type for in-app purchase delivery statuses.
+type DeliveryStatus int
+
+// List of delivery statuses.
+const (
+	DSDelivered      DeliveryStatus = 0
+	DSQualityIssue   DeliveryStatus = 1
+	DSWrongItem      DeliveryStatus = 2
+	DSServerOutage   DeliveryStatus = 3
+	DSCurrencyChange DeliveryStatus = 4
+	DSOther          DeliveryStatus = 5
+)
+
+// LifetimeDollars is the data type for ranges of customer lifetime spending
+// in USD.
+type LifetimeDollars int
+
+// List of lifetime dollar ranges.
+const (
+	LDUndeclared LifetimeDollars = 0
+	LDZero       LifetimeDollars = 1
+	LDUpTo50     LifetimeDollars = 2
+	LDUpTo100    LifetimeDollars = 3
+	LDUpTo500    LifetimeDollars = 4
+	LDUpTo1000   LifetimeDollars = 5
+	LDUpTo2000   LifetimeDollars = 6
+	LDOver2000   LifetimeDollars = 7
+)
+
+// Platform is the data type for the platforms a customer used.
+type Platform int
+
+// List of platforms.
+const (
+	PFUndeclared Platform = 0
+	PFApple      Platform = 1
+	PFNonApple   Platform = 2
+)
+
+// PlayTime is the data type for ranges of customer app usage time.
+type PlayTime int
+
+// List of play times.
+const (
+	PTUndeclared  PlayTime = 0
+	PTUpTo5Min    PlayTime = 1
+	PTUpTo1Hour   PlayTime = 2
+	PTUpTo6Hours  PlayTime = 3
+	PTUpTo24Hours PlayTime = 4
+	PTUpTo4Days   PlayTime = 5
+	PTUpTo16Days  PlayTime = 6
+	PTOver16Days  PlayTime = 7
+)
+
+// RefundPreference is the data type for the developer's refund preferences.
+type RefundPreference int
+
+// List of refund preferences.
+const (
+	RPUndeclared   RefundPreference = 0
+	RPGrant        RefundPreference = 1
+	RPDecline      RefundPreference = 2
+	RPNoPreference RefundPreference = 3
+)
+
+// UserStatus is the data type for customer account statuses.
+type UserStatus int
+
+// List of user statuses.
+const (
+	USUndeclared    UserStatus = 0
+	USActive        UserStatus = 1
+	USSuspended     UserStatus = 2
+	USTerminated    UserStatus = 3
+	USLimitedAccess UserStatus = 4
+)
+
+// ConsumptionRequest is the consumption information sent in response to a
+// CONSUMPTION_REQUEST notification.
+type ConsumptionRequest struct {
+	AccountTenure            AccountTenure     `json:"accountTenure"`
+	AppAccountToken          string            `json:"appAccountToken"`
+	ConsumptionStatus        ConsumptionStatus `json:"consumptionStatus"`
+	CustomerConsented        bool              `json:"customerConsented"`
+	DeliveryStatus           DeliveryStatus    `json:"deliveryStatus"`
+	LifetimeDollarsPurchased LifetimeDollars   `json:"lifetimeDollarsPurchased"`
+	LifetimeDollarsRefunded  LifetimeDollars   `json:"lifetimeDollarsRefunded"`
+	Platform                 Platform          `json:"platform"`
+	PlayTime                 PlayTime          `json:"playTime"`
+	RefundPreference         RefundPreference  `json:"refundPreference"`
+	SampleContentProvided    bool              `json:"sampleContentProvided"`
+	UserStatus               UserStatus        `json:"userStatus"`
+}
+
+// AccountTenureOf returns the account tenure of an account of the given age.
+func AccountTenureOf(age time.Duration) AccountTenure {
+	day := 24 * time.Hour
+
+	switch {
+	case age < 0:
+		return ATUndeclared
+	case age <= 3*day:
+		return ATUpTo3Days
+	case age <= 10*day:
+		return ATUpTo10Days
+	case age <= 30*day:
+		return ATUpTo30Days
+	case age <= 90*day:
+		return ATUpTo90Days
+	case age <= 180*day:
+		return ATUpTo180Days
+	case age <= 365*day:
+		return ATUpTo365Days
+	}
+
+	return ATOver365Days
+}
+
+// PlayTimeOf returns the play time of the given app usage time.
+func PlayTimeOf(d time.Duration) PlayTime {
+	day := 24 * time.Hour
+
+	switch {
+	case d < 0:
+		return PTUndeclared
+	case d <= 5*time.Minute:
+		return PTUpTo5Min
+	case d <= time.Hour:
+		return PTUpTo1Hour
+	case d <= 6*time.Hour:
+		return PTUpTo6Hours
+	case d <= day:
+		return PTUpTo24Hours
+	case d <= 4*day:
+		return PTUpTo4Days
+	case d <= 16*day:
+		return PTUpTo16Days
+	}
+
+	return PTOver16Days
+}
+
+// LifetimeDollarsOf returns the lifetime dollar range of the given amount in
+// USD cents.
+func LifetimeDollarsOf(cents int64) LifetimeDollars {
+	switch {
+	case cents < 0:
+		return LDUndeclared
+	case cents == 0:
+		return LDZero
+	case cents < 5000:
+		return LDUpTo50
+	case cents < 10000:
+		return LDUpTo100
+	case cents < 50000:
+		return LDUpTo500
+	case cents < 100000:
+		return LDUpTo1000
+	case cents < 200000:
+		return LDUpTo2000
+	}
+
+	return LDOver2000
+}
+
+// SendConsumptionInfo sends consumption information about a consumable
+// in-app purchase or auto-renewable subscription to the App Store, after it
+// requested it with a CONSUMPTION_REQUEST notification. It must be sent
+// within 12 hours of the notification.
+func (c *ServerClient) SendConsumptionInfo(transactionID string, r *ConsumptionRequest) error {
+	return c.SendConsumptionInfoContext(context.Background(), transactionID, r)
+}
+
+// SendConsumptionInfoContext is like SendConsumptionInfo but takes a context.
+func (c *ServerClient) SendConsumptionInfoContext(ctx context.Context, transactionID string, r *ConsumptionRequest) error {
+	path := "/inApps/v1/transactions/consumption/" + url.PathEscape(transactionID)
+
+	return c.do(ctx, http.MethodPut, path, nil, r, nil)
+}
+
+// ConsumptionProvider supplies the consumption information of the
+// transactions the App Store requests it for.
+type ConsumptionProvider interface {
+	ConsumptionInfo(ctx context.Context, tx *TransactionInfo, reason string) (*ConsumptionRequest, error)
+}
+
+// ConsumptionProviderFunc adapts a function to the ConsumptionProvider
+// interface.
+type ConsumptionProviderFunc func(ctx context.Context, tx *TransactionInfo, reason string) (*ConsumptionRequest, error)
+
+// ConsumptionInfo calls f.
+func (f ConsumptionProviderFunc) ConsumptionInfo(ctx context.Context, tx *TransactionInfo, reason string) (*ConsumptionRequest, error) {
+	return f(ctx, tx, reason)
+}
+
+// HandleConsumptionRequest answers a CONSUMPTION_REQUEST notification with the
+// consumption information from p. Other notifications are ignored. The app
+// account token of the transaction is used when p leaves it empty.
+//
+// Consumption information may only be shared with the customer's consent. If
+// p returns a request without CustomerConsented, nothing is sent.
+//
+// It is meant to be called from NotificationHandler.OnNotification.
+func (c *ServerClient) HandleConsumptionRequest(ctx context.Context, n *Notification, p ConsumptionProvider) error {
+	if n.NotificationType != NTConsumptionRequest {
+		return nil
+	}
+
+	if n.Transaction == nil {
+		return errors.New("consumption request has no transaction")
+	}
+
+	var reason string
+	if n.Data != nil {
+		reason = n.Data.ConsumptionRequestReason
+	}
+
+	r, err := p.ConsumptionInfo(ctx, n.Transaction, reason)
+	if err != nil {
+		return err
+	}
+
+	if r == nil {
+		return errors.New("consumption provider returned no request")
+	}
+
+	if !r.CustomerConsented {
+		return nil
+	}
+
+	if r.AppAccountToken == "" {
+		r.AppAccountToken = n.Transaction.AppAccountToken
+	}
+
+	return c.SendConsumptionInfoContext(ctx, n.Transaction.TransactionID, r)
+}
@@ -0,0 +1,139 @@
+package appstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/brainleap/iap"
+	"github.com/brainleap/iap/appstore"
+	"github.com/brainleap/iap/iaptest"
+)
+
+func TestHandleConsumptionRequest(t *testing.T) {
+	errProvider := errors.New("provider failed")
+
+	consumptionRequest := func(transactionID string) *appstore.Notification {
+		return &appstore.Notification{
+			NotificationType: appstore.NTConsumptionRequest,
+			Data:             &appstore.NotificationData{ConsumptionRequestReason: "UNINTENDED_PURCHASE"},
+			Transaction:      &appstore.TransactionInfo{TransactionID: transactionID, AppAccountToken: "account"},
+		}
+	}
+
+	tests := []struct {
+		name             string
+		n                *appstore.Notification
+		req              *appstore.ConsumptionRequest
+		providerErr      error
+		wantCalled       bool
+		wantErr          error
+		wantAnyErr       bool
+		wantSent         bool
+		wantAccountToken string
+	}{
+		{
+			name:             "consented",
+			n:                consumptionRequest("1000"),
+			req:              &appstore.ConsumptionRequest{CustomerConsented: true},
+			wantCalled:       true,
+			wantSent:         true,
+			wantAccountToken: "account",
+		},
+		{
+			name:             "provider account token",
+			n:                consumptionRequest("1000"),
+			req:              &appstore.ConsumptionRequest{CustomerConsented: true, AppAccountToken: "other"},
+			wantCalled:       true,
+			wantSent:         true,
+			wantAccountToken: "other",
+		},
+		{
+			name:       "not consented",
+			n:          consumptionRequest("1000"),
+			req:        &appstore.ConsumptionRequest{},
+			wantCalled: true,
+		},
+		{
+			name:        "provider error",
+			n:           consumptionRequest("1000"),
+			providerErr: errProvider,
+			wantCalled:  true,
+			wantErr:     errProvider,
+		},
+		{
+			name:       "no request",
+			n:          consumptionRequest("1000"),
+			wantCalled: true,
+			wantAnyErr: true,
+		},
+		{
+			name:       "unknown transaction",
+			n:          consumptionRequest("2000"),
+			req:        &appstore.ConsumptionRequest{CustomerConsented: true},
+			wantCalled: true,
+			wantErr:    iap.ErrPurchaseNotFound,
+		},
+		{
+			name:       "no transaction",
+			n:          &appstore.Notification{NotificationType: appstore.NTConsumptionRequest},
+			wantAnyErr: true,
+		},
+		{
+			name: "other notification",
+			n:    &appstore.Notification{NotificationType: appstore.NTDidRenew, Transaction: &appstore.TransactionInfo{TransactionID: "1000"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := iaptest.NewAppStore()
+			defer store.Close()
+
+			store.AddTransaction(&appstore.TransactionInfo{
+				TransactionID:         "1000",
+				OriginalTransactionID: "1000",
+				BundleID:              store.BundleID,
+				ProductID:             "coins_100",
+				AppAccountToken:       "account",
+			}, nil)
+
+			c, err := store.ServerClient(appstore.ProductionMode)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			called := false
+			p := appstore.ConsumptionProviderFunc(func(ctx context.Context, tx *appstore.TransactionInfo, reason string) (*appstore.ConsumptionRequest, error) {
+				called = true
+				if tx != tt.n.Transaction || reason != "UNINTENDED_PURCHASE" {
+					t.Errorf("ConsumptionInfo() called with %+v, %q", tx, reason)
+				}
+				return tt.req, tt.providerErr
+			})
+
+			err = c.HandleConsumptionRequest(context.Background(), tt.n, p)
+			switch {
+			case tt.wantAnyErr:
+				if err == nil {
+					t.Fatal("HandleConsumptionRequest() succeeded, want error")
+				}
+			case !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil):
+				t.Fatalf("HandleConsumptionRequest() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if called != tt.wantCalled {
+				t.Errorf("provider called = %v, want %v", called, tt.wantCalled)
+			}
+
+			sent := store.Consumption("1000")
+			if (sent != nil) != tt.wantSent {
+				t.Fatalf("consumption sent = %+v, want sent %v", sent, tt.wantSent)
+			}
+
+			if sent != nil && (!sent.CustomerConsented || sent.AppAccountToken != tt.wantAccountToken) {
+				t.Errorf("consumption sent = %+v", sent)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package appstore
+
+import "time"
+
+// Entitlement is the access a receipt grants to a product.
+//
+// ExpiresTime is zero for products that do not expire. For a subscription in
+// its billing grace period, it is the end of the grace period.
+type Entitlement struct {
+	ProductID    string
+	Transaction  *InApp
+	ExpiresTime  time.Time
+	GracePeriod  bool
+	FamilyShared bool
+}
+
+// Entitlement returns the access the receipt grants to a product at the given
+// time, or nil if it grants none.
+//
+// Refunded transactions and subscriptions the user upgraded from grant no
+// access. Subscriptions that failed to renew grant access until their billing
+// grace period ends. Transactions shared through Family Sharing grant access
+// and are reported as such.
+func (r *Response) Entitlement(productID string, now time.Time) *Entitlement {
+	latest := r.latestTransaction(productID)
+	if latest == nil || latest.Canceled() || latest.Upgraded() {
+		return nil
+	}
+
+	e := &Entitlement{
+		ProductID:    productID,
+		Transaction:  latest,
+		ExpiresTime:  latest.ExpiresTime(),
+		FamilyShared: latest.FamilyShared(),
+	}
+
+	if e.ExpiresTime.IsZero() || e.ExpiresTime.After(now) {
+		return e
+	}
+
+	if grace := r.gracePeriodExpiresTime(latest); grace.After(now) {
+		e.ExpiresTime = grace
+		e.GracePeriod = true
+
+		return e
+	}
+
+	return nil
+}
+
+// gracePeriodExpiresTime returns the end of the billing grace period of a
+// subscription transaction, or the zero time if it is not in one.
+func (r *Response) gracePeriodExpiresTime(tx *InApp) time.Time {
+	renewal := r.pendingRenewalInfo(tx.OriginalTransactionID)
+	if renewal == nil || !renewal.InBillingRetry() {
+		return time.Time{}
+	}
+
+	return renewal.GracePeriodExpiresTime()
+}
@@ -0,0 +1,190 @@
+package appstore
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestResponseEntitlement(t *testing.T) {
+	now := time.Now()
+	ms := func(d time.Duration) string {
+		return strconv.FormatInt(now.Add(d).UnixNano()/int64(time.Millisecond), 10)
+	}
+
+	future, past := ms(24*time.Hour), ms(-time.Hour)
+
+	// tx returns a transaction of the product purchased at the given offset
+	// from now.
+	tx := func(productID string, purchased time.Duration, expires string) InApp {
+		return InApp{
+			ProductID:             productID,
+			TransactionID:         "1000",
+			OriginalTransactionID: "1000",
+			PurchaseDateMS:        ms(purchased),
+			ExpiresDateMS:         expires,
+		}
+	}
+
+	tests := []struct {
+		name             string
+		txs              []InApp
+		renewal          *PendingRenewalInfo
+		productID        string
+		wantNil          bool
+		wantExpires      string
+		wantGracePeriod  bool
+		wantFamilyShared bool
+	}{
+		{
+			name:      "product",
+			txs:       []InApp{tx("coins_100", -time.Hour, "")},
+			productID: "coins_100",
+		},
+		{
+			name:        "active subscription",
+			txs:         []InApp{tx("monthly", -time.Hour, future)},
+			wantExpires: future,
+		},
+		{
+			name: "latest transaction",
+			txs: []InApp{
+				tx("monthly", -48*time.Hour, past),
+				tx("monthly", -time.Hour, future),
+			},
+			wantExpires: future,
+		},
+		{
+			name:    "expired subscription",
+			txs:     []InApp{tx("monthly", -48*time.Hour, past)},
+			wantNil: true,
+		},
+		{
+			name:            "grace period",
+			txs:             []InApp{tx("monthly", -48*time.Hour, past)},
+			renewal:         &PendingRenewalInfo{SubscriptionRetryFlag: "1", GracePeriodExpiresDateMS: future},
+			wantExpires:     future,
+			wantGracePeriod: true,
+		},
+		{
+			name:    "grace period ended",
+			txs:     []InApp{tx("monthly", -48*time.Hour, past)},
+			renewal: &PendingRenewalInfo{SubscriptionRetryFlag: "1", GracePeriodExpiresDateMS: ms(-time.Minute)},
+			wantNil: true,
+		},
+		{
+			name:    "billing retry without grace period",
+			txs:     []InApp{tx("monthly", -48*time.Hour, past)},
+			renewal: &PendingRenewalInfo{SubscriptionRetryFlag: "1"},
+			wantNil: true,
+		},
+		{
+			name:    "grace period without billing retry",
+			txs:     []InApp{tx("monthly", -48*time.Hour, past)},
+			renewal: &PendingRenewalInfo{GracePeriodExpiresDateMS: future},
+			wantNil: true,
+		},
+		{
+			name: "family shared",
+			txs: []InApp{func() InApp {
+				in := tx("monthly", -time.Hour, future)
+				in.InAppOwnershipType = OTFamilyShared
+				return in
+			}()},
+			wantExpires:      future,
+			wantFamilyShared: true,
+		},
+		{
+			name: "family shared in grace period",
+			txs: []InApp{func() InApp {
+				in := tx("monthly", -48*time.Hour, past)
+				in.InAppOwnershipType = OTFamilyShared
+				return in
+			}()},
+			renewal:          &PendingRenewalInfo{SubscriptionRetryFlag: "1", GracePeriodExpiresDateMS: future},
+			wantExpires:      future,
+			wantGracePeriod:  true,
+			wantFamilyShared: true,
+		},
+		{
+			name: "revoked",
+			txs: []InApp{func() InApp {
+				in := tx("monthly", -time.Hour, future)
+				in.CancellationDateMS = ms(-time.Minute)
+				in.CancellationReason = "0"
+				return in
+			}()},
+			wantNil: true,
+		},
+		{
+			name: "revoked product",
+			txs: []InApp{func() InApp {
+				in := tx("coins_100", -time.Hour, "")
+				in.CancellationDateMS = ms(-time.Minute)
+				return in
+			}()},
+			productID: "coins_100",
+			wantNil:   true,
+		},
+		{
+			name: "upgraded",
+			txs: []InApp{func() InApp {
+				in := tx("monthly", -time.Hour, future)
+				in.CancellationDateMS = ms(-time.Minute)
+				in.IsUpgraded = "true"
+				return in
+			}()},
+			wantNil: true,
+		},
+		{
+			name:      "other product",
+			txs:       []InApp{tx("yearly", -time.Hour, future)},
+			productID: "monthly",
+			wantNil:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Response{LatestReceiptInfo: tt.txs}
+			if tt.renewal != nil {
+				ri := *tt.renewal
+				ri.OriginalTransactionID = "1000"
+				r.PendingRenewalInfo = []PendingRenewalInfo{ri}
+			}
+
+			productID := tt.productID
+			if productID == "" {
+				productID = "monthly"
+			}
+
+			e := r.Entitlement(productID, now)
+			if tt.wantNil {
+				if e != nil {
+					t.Fatalf("Entitlement() = %+v, want nil", e)
+				}
+				return
+			}
+
+			if e == nil {
+				t.Fatal("Entitlement() = nil")
+			}
+
+			if e.ProductID != productID || e.Transaction == nil || e.Transaction.ProductID != productID {
+				t.Errorf("Entitlement() = %+v", e)
+			}
+
+			if want := millis(tt.wantExpires); !e.ExpiresTime.Equal(want) {
+				t.Errorf("ExpiresTime = %v, want %v", e.ExpiresTime, want)
+			}
+
+			if e.GracePeriod != tt.wantGracePeriod {
+				t.Errorf("GracePeriod = %v, want %v", e.GracePeriod, tt.wantGracePeriod)
+			}
+
+			if e.FamilyShared != tt.wantFamilyShared {
+				t.Errorf("FamilyShared = %v, want %v", e.FamilyShared, tt.wantFamilyShared)
+			}
+		})
+	}
+}
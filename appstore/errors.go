@@ -0,0 +1,65 @@
+package appstore
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/brainleap/iap"
+)
+
+// maxErrorBody limits how much of an error response is kept.
+const maxErrorBody = 64 << 10
+
+// App Store Server API error codes the API asks to retry.
+var retryableErrorCodes = map[int]bool{
+	4040002: true, // AccountNotFoundRetryable
+	4040004: true, // AppNotFoundRetryable
+	4040006: true, // OriginalTransactionIdNotFoundRetryable
+	5000001: true, // GeneralInternalRetryable
+}
+
+// APIError is the error body returned by the App Store Server API.
+type APIError struct {
+	ErrorCode    int    `json:"errorCode"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+// newError creates an *iap.Error from a failed verifyReceipt response.
+func newError(res *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+
+	return iap.NewHTTPError(iap.AppStore, res.StatusCode, body)
+}
+
+// newServerError creates an *iap.Error from a failed App Store Server API
+// response. The parsed error is kept in Details.
+func newServerError(res *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+
+	e := iap.NewHTTPError(iap.AppStore, res.StatusCode, body)
+
+	var apiErr APIError
+	if err := json.Unmarshal(body, &apiErr); err != nil || apiErr.ErrorCode == 0 {
+		return e
+	}
+
+	e.Details = &apiErr
+	e.Code = apiErr.ErrorCode
+	e.Message = apiErr.ErrorMessage
+
+	switch {
+	case apiErr.ErrorCode/1000 == 4040:
+		e.Err = iap.ErrPurchaseNotFound
+	case apiErr.ErrorCode == 4000006 || apiErr.ErrorCode == 4000008:
+		// InvalidTransactionId and InvalidOriginalTransactionId.
+		e.Err = iap.ErrInvalidToken
+	}
+
+	if retryableErrorCodes[apiErr.ErrorCode] {
+		e.Retryable = true
+	}
+
+	return e
+}
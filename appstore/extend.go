@@ -0,0 +1,222 @@
+package appstore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/brainleap/iap"
+)
+
+// ExtendReasonCode is the data type for renewal date extension reasons.
+type ExtendReasonCode int
+
+// List of extension reason codes.
+const (
+	ERUndeclared           ExtendReasonCode = 0
+	ERCustomerSatisfaction ExtendReasonCode = 1
+	EROther                ExtendReasonCode = 2
+	ERServiceIssue         ExtendReasonCode = 3
+)
+
+// defaultMassExtendInterval is the polling interval of
+// WaitMassExtendRenewalDate when none is given.
+const defaultMassExtendInterval = time.Minute
+
+// ExtendRenewalDateRequest extends the renewal date of a subscription by up
+// to 90 days. A random RequestIdentifier is used when it is empty.
+type ExtendRenewalDateRequest struct {
+	ExtendByDays      int              `json:"extendByDays"`
+	ExtendReasonCode  ExtendReasonCode `json:"extendReasonCode"`
+	RequestIdentifier string           `json:"requestIdentifier"`
+}
+
+// ExtendRenewalDateResponse is the result of a renewal date extension.
+// EffectiveDate is the new renewal date in milliseconds since epoch.
+type ExtendRenewalDateResponse struct {
+	OriginalTransactionID string `json:"originalTransactionId"`
+	WebOrderLineItemID    string `json:"webOrderLineItemId"`
+	Success               bool   `json:"success"`
+	EffectiveDate         int64  `json:"effectiveDate"`
+}
+
+// MassExtendRenewalDateRequest extends the renewal date of all active
+// subscribers of a product by up to 90 days. StorefrontCountryCodes limits the
+// extension to the given storefronts; all storefronts are included when it is
+// empty. A random RequestIdentifier is used when it is empty.
+type MassExtendRenewalDateRequest struct {
+	ExtendByDays           int              `json:"extendByDays"`
+	ExtendReasonCode       ExtendReasonCode `json:"extendReasonCode"`
+	RequestIdentifier      string           `json:"requestIdentifier"`
+	StorefrontCountryCodes []string         `json:"storefrontCountryCodes,omitempty"`
+	ProductID              string           `json:"productId"`
+}
+
+// MassExtendRenewalDateStatus is the status of a mass renewal date extension.
+// CompleteDate is in milliseconds since epoch.
+type MassExtendRenewalDateStatus struct {
+	RequestIdentifier string `json:"requestIdentifier"`
+	Complete          bool   `json:"complete"`
+	CompleteDate      int64  `json:"completeDate"`
+	SucceededCount    int64  `json:"succeededCount"`
+	FailedCount       int64  `json:"failedCount"`
+}
+
+// ExtendRenewalDate extends the renewal date of a customer's active
+// subscription.
+func (c *ServerClient) ExtendRenewalDate(originalTransactionID string, r *ExtendRenewalDateRequest) (*ExtendRenewalDateResponse, error) {
+	return c.ExtendRenewalDateContext(context.Background(), originalTransactionID, r)
+}
+
+// ExtendRenewalDateContext is like ExtendRenewalDate but takes a context.
+func (c *ServerClient) ExtendRenewalDateContext(ctx context.Context, originalTransactionID string, r *ExtendRenewalDateRequest) (*ExtendRenewalDateResponse, error) {
+	body := *r
+	if body.RequestIdentifier == "" {
+		id, err := newUUID()
+		if err != nil {
+			return nil, err
+		}
+
+		body.RequestIdentifier = id
+	}
+
+	var res ExtendRenewalDateResponse
+
+	path := "/inApps/v1/subscriptions/extend/" + url.PathEscape(originalTransactionID)
+	if err := c.do(ctx, http.MethodPut, path, nil, &body, &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// MassExtendRenewalDate starts extending the renewal date of all active
+// subscribers of a product and returns the request identifier. The App Store
+// processes the request asynchronously; see GetMassExtendRenewalDateStatus.
+func (c *ServerClient) MassExtendRenewalDate(r *MassExtendRenewalDateRequest) (string, error) {
+	return c.MassExtendRenewalDateContext(context.Background(), r)
+}
+
+// MassExtendRenewalDateContext is like MassExtendRenewalDate but takes a
+// context.
+func (c *ServerClient) MassExtendRenewalDateContext(ctx context.Context, r *MassExtendRenewalDateRequest) (string, error) {
+	body := *r
+	if body.RequestIdentifier == "" {
+		id, err := newUUID()
+		if err != nil {
+			return "", err
+		}
+
+		body.RequestIdentifier = id
+	}
+
+	var res struct {
+		RequestIdentifier string `json:"requestIdentifier"`
+	}
+
+	if err := c.do(ctx, http.MethodPost, "/inApps/v1/subscriptions/extend/mass", nil, &body, &res); err != nil {
+		return "", err
+	}
+
+	return res.RequestIdentifier, nil
+}
+
+// GetMassExtendRenewalDateStatus gets the status of a mass renewal date
+// extension.
+func (c *ServerClient) GetMassExtendRenewalDateStatus(productID, requestIdentifier string) (*MassExtendRenewalDateStatus, error) {
+	return c.GetMassExtendRenewalDateStatusContext(context.Background(), productID, requestIdentifier)
+}
+
+// GetMassExtendRenewalDateStatusContext is like
+// GetMassExtendRenewalDateStatus but takes a context.
+func (c *ServerClient) GetMassExtendRenewalDateStatusContext(ctx context.Context, productID, requestIdentifier string) (*MassExtendRenewalDateStatus, error) {
+	var res MassExtendRenewalDateStatus
+
+	path := "/inApps/v1/subscriptions/extend/mass/" + url.PathEscape(productID) + "/" + url.PathEscape(requestIdentifier)
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// WaitMassExtendRenewalDate polls the status of a mass renewal date extension
+// at the given interval until it completes. A non-positive interval polls
+// once a minute.
+func (c *ServerClient) WaitMassExtendRenewalDate(productID, requestIdentifier string, interval time.Duration) (*MassExtendRenewalDateStatus, error) {
+	return c.WaitMassExtendRenewalDateContext(context.Background(), productID, requestIdentifier, interval)
+}
+
+// WaitMassExtendRenewalDateContext is like WaitMassExtendRenewalDate but
+// takes a context.
+func (c *ServerClient) WaitMassExtendRenewalDateContext(ctx context.Context, productID, requestIdentifier string, interval time.Duration) (*MassExtendRenewalDateStatus, error) {
+	if interval <= 0 {
+		interval = defaultMassExtendInterval
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		s, err := c.GetMassExtendRenewalDateStatusContext(ctx, productID, requestIdentifier)
+		if err != nil {
+			return nil, err
+		}
+
+		if s.Complete {
+			return s, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Extender adapts ServerClient to the iap.Extender interface. Requests must
+// set OriginalTransactionID, e.g. from the purchase the Verifier returned;
+// the token is not used.
+type Extender struct {
+	Client *ServerClient
+	Reason ExtendReasonCode
+}
+
+// NewExtender creates a new AppStore extender reporting the given reason.
+func NewExtender(c *ServerClient, reason ExtendReasonCode) *Extender {
+	return &Extender{Client: c, Reason: reason}
+}
+
+// Extend extends the renewal date of a subscription by the given number of
+// days and returns the new renewal date.
+func (e *Extender) Extend(r *iap.Request, days int) (time.Time, error) {
+	return e.ExtendContext(context.Background(), r, days)
+}
+
+// ExtendContext is like Extend but takes a context.
+func (e *Extender) ExtendContext(ctx context.Context, r *iap.Request, days int) (time.Time, error) {
+	if r.OriginalTransactionID == "" {
+		return time.Time{}, errors.New("request has no original transaction id")
+	}
+
+	res, err := e.Client.ExtendRenewalDateContext(ctx, r.OriginalTransactionID, &ExtendRenewalDateRequest{
+		ExtendByDays:     days,
+		ExtendReasonCode: e.Reason,
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if !res.Success {
+		return time.Time{}, &iap.Error{
+			Store:   iap.AppStore,
+			Message: "renewal date extension failed",
+			Details: res,
+		}
+	}
+
+	return iap.Millis(res.EffectiveDate), nil
+}
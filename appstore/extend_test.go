@@ -0,0 +1,183 @@
+package appstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/brainleap/iap"
+	"github.com/brainleap/iap/appstore"
+	"github.com/brainleap/iap/iaptest"
+)
+
+const day = int64(24 * time.Hour / time.Millisecond)
+
+func newMassExtendStore(t *testing.T) (*iaptest.AppStore, *appstore.ServerClient, int64) {
+	t.Helper()
+
+	store := iaptest.NewAppStore()
+	t.Cleanup(store.Close)
+
+	expires := time.Now().Add(24*time.Hour).UnixNano() / int64(time.Millisecond)
+
+	for _, tx := range []appstore.TransactionInfo{
+		{TransactionID: "1000", OriginalTransactionID: "1000", ProductID: "monthly", Storefront: "USA"},
+		{TransactionID: "2000", OriginalTransactionID: "2000", ProductID: "monthly", Storefront: "DEU"},
+		{TransactionID: "3000", OriginalTransactionID: "3000", ProductID: "yearly", Storefront: "USA"},
+	} {
+		tx := tx
+		tx.BundleID = store.BundleID
+		tx.ExpiresDate = expires
+		store.AddTransaction(&tx, nil)
+	}
+
+	c, err := store.ServerClient(appstore.ProductionMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return store, c, expires
+}
+
+func TestMassExtendRenewalDate(t *testing.T) {
+	tests := []struct {
+		name         string
+		storefronts  []string
+		wantExtended []string
+	}{
+		{name: "all storefronts", wantExtended: []string{"1000", "2000"}},
+		{name: "one storefront", storefronts: []string{"USA"}, wantExtended: []string{"1000"}},
+		{name: "several storefronts", storefronts: []string{"DEU", "USA"}, wantExtended: []string{"1000", "2000"}},
+		{name: "other storefront", storefronts: []string{"FRA"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, c, expires := newMassExtendStore(t)
+
+			id, err := c.MassExtendRenewalDate(&appstore.MassExtendRenewalDateRequest{
+				ExtendByDays:           3,
+				ExtendReasonCode:       appstore.ERServiceIssue,
+				StorefrontCountryCodes: tt.storefronts,
+				ProductID:              "monthly",
+			})
+			if err != nil {
+				t.Fatalf("MassExtendRenewalDate() error = %v", err)
+			}
+
+			s, err := c.WaitMassExtendRenewalDate("monthly", id, time.Millisecond)
+			if err != nil {
+				t.Fatalf("WaitMassExtendRenewalDate() error = %v", err)
+			}
+
+			if !s.Complete || s.RequestIdentifier != id || s.SucceededCount != int64(len(tt.wantExtended)) {
+				t.Errorf("WaitMassExtendRenewalDate() = %+v", s)
+			}
+
+			extended := make(map[string]bool)
+			for _, id := range tt.wantExtended {
+				extended[id] = true
+			}
+
+			for _, id := range []string{"1000", "2000", "3000"} {
+				want := expires
+				if extended[id] {
+					want += 3 * day
+				}
+
+				if got := store.Transaction(id).ExpiresDate; got != want {
+					t.Errorf("transaction %s expires = %d, want %d", id, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestWaitMassExtendRenewalDate(t *testing.T) {
+	tests := []struct {
+		name     string
+		polls    int
+		ctx      func() (context.Context, context.CancelFunc)
+		id       string
+		wantErr  error
+		wantCode int
+	}{
+		{name: "complete", polls: 0},
+		{name: "complete after polling", polls: 3},
+		{
+			name:  "timeout",
+			polls: 1 << 20,
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 20*time.Millisecond)
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name:  "canceled",
+			polls: 1 << 20,
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				go func() {
+					time.Sleep(10 * time.Millisecond)
+					cancel()
+				}()
+				return ctx, cancel
+			},
+			wantErr: context.Canceled,
+		},
+		{name: "unknown request", id: "unknown", wantCode: 4040009},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, c, _ := newMassExtendStore(t)
+			store.MassExtendPolls = tt.polls
+
+			id, err := c.MassExtendRenewalDate(&appstore.MassExtendRenewalDateRequest{ExtendByDays: 1, ProductID: "monthly"})
+			if err != nil {
+				t.Fatalf("MassExtendRenewalDate() error = %v", err)
+			}
+
+			if tt.polls > 0 {
+				s, err := c.GetMassExtendRenewalDateStatus("monthly", id)
+				if err != nil || s.Complete {
+					t.Fatalf("GetMassExtendRenewalDateStatus() = %+v, %v, want incomplete status", s, err)
+				}
+			}
+
+			if tt.id != "" {
+				id = tt.id
+			}
+
+			ctx, cancel := context.Background(), context.CancelFunc(func() {})
+			if tt.ctx != nil {
+				ctx, cancel = tt.ctx()
+			}
+			defer cancel()
+
+			s, err := c.WaitMassExtendRenewalDateContext(ctx, "monthly", id, time.Millisecond)
+			switch {
+			case tt.wantCode != 0:
+				var e *iap.Error
+				if !errors.As(err, &e) || e.Code != tt.wantCode {
+					t.Fatalf("WaitMassExtendRenewalDateContext() error = %v, want error code %d", err, tt.wantCode)
+				}
+				return
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("WaitMassExtendRenewalDateContext() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("WaitMassExtendRenewalDateContext() error = %v", err)
+			}
+
+			if !s.Complete || s.CompleteDate == 0 || s.SucceededCount != 2 {
+				t.Errorf("WaitMassExtendRenewalDateContext() = %+v", s)
+			}
+		})
+	}
+}
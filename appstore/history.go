@@ -0,0 +1,155 @@
+package appstore
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// SendAttemptResult is the data type for the results of notification send
+// attempts.
+type SendAttemptResult string
+
+// List of send attempt results.
+const (
+	SARSuccess                      SendAttemptResult = "SUCCESS"
+	SARTimedOut                     SendAttemptResult = "TIMED_OUT"
+	SARTLSIssue                     SendAttemptResult = "TLS_ISSUE"
+	SARCircularRedirect             SendAttemptResult = "CIRCULAR_REDIRECT"
+	SARNoResponse                   SendAttemptResult = "NO_RESPONSE"
+	SARSocketIssue                  SendAttemptResult = "SOCKET_ISSUE"
+	SARUnsupportedCharset           SendAttemptResult = "UNSUPPORTED_CHARSET"
+	SARInvalidResponse              SendAttemptResult = "INVALID_RESPONSE"
+	SARPrematureClose               SendAttemptResult = "PREMATURE_CLOSE"
+	SARUnsuccessfulHTTPResponseCode SendAttemptResult = "UNSUCCESSFUL_HTTP_RESPONSE_CODE"
+	SAROther                        SendAttemptResult = "OTHER"
+)
+
+// NotificationHistoryRequest filters a notification history request. The
+// dates are in milliseconds since epoch and must be set; the App Store keeps
+// notifications for 180 days. The other filters are omitted when zero.
+type NotificationHistoryRequest struct {
+	StartDate           int64               `json:"startDate"`
+	EndDate             int64               `json:"endDate"`
+	NotificationType    NotificationType    `json:"notificationType,omitempty"`
+	NotificationSubtype NotificationSubtype `json:"notificationSubtype,omitempty"`
+	TransactionID       string              `json:"transactionId,omitempty"`
+	OnlyFailures        bool                `json:"onlyFailures,omitempty"`
+}
+
+// NotificationHistoryResponse is a page of notification history. Pass
+// PaginationToken to the next call to fetch the following page while HasMore
+// is set.
+type NotificationHistoryResponse struct {
+	NotificationHistory []NotificationHistoryItem `json:"notificationHistory"`
+	HasMore             bool                      `json:"hasMore"`
+	PaginationToken     string                    `json:"paginationToken"`
+}
+
+// NotificationHistoryItem is a notification the App Store sent, with the
+// results of its send attempts. Notification is decoded from SignedPayload.
+type NotificationHistoryItem struct {
+	SignedPayload      string        `json:"signedPayload"`
+	SendAttemptResults []SendAttempt `json:"sendAttemptResults"`
+
+	Notification *Notification `json:"-"`
+}
+
+// SendAttempt is an attempt to send a notification. AttemptDate is in
+// milliseconds since epoch.
+type SendAttempt struct {
+	AttemptDate       int64             `json:"attemptDate"`
+	SendAttemptResult SendAttemptResult `json:"sendAttemptResult"`
+}
+
+// GetNotificationHistory gets a page of the notifications the App Store sent
+// to the app's server.
+func (c *ServerClient) GetNotificationHistory(r *NotificationHistoryRequest, paginationToken string) (*NotificationHistoryResponse, error) {
+	return c.GetNotificationHistoryContext(context.Background(), r, paginationToken)
+}
+
+// GetNotificationHistoryContext is like GetNotificationHistory but takes a
+// context.
+func (c *ServerClient) GetNotificationHistoryContext(ctx context.Context, r *NotificationHistoryRequest, paginationToken string) (*NotificationHistoryResponse, error) {
+	q := url.Values{}
+	if paginationToken != "" {
+		q.Set("paginationToken", paginationToken)
+	}
+
+	var res NotificationHistoryResponse
+	if err := c.do(ctx, http.MethodPost, "/inApps/v1/notifications/history", q, r, &res); err != nil {
+		return nil, err
+	}
+
+	for i := range res.NotificationHistory {
+		item := &res.NotificationHistory[i]
+
+		n, err := c.Verifier.ParseNotification(item.SignedPayload)
+		if err != nil {
+			return nil, err
+		}
+
+		item.Notification = n
+	}
+
+	return &res, nil
+}
+
+// GetAllNotificationHistory gets the notifications the App Store sent to the
+// app's server, following the pagination tokens until all pages are fetched.
+func (c *ServerClient) GetAllNotificationHistory(r *NotificationHistoryRequest) ([]NotificationHistoryItem, error) {
+	return c.GetAllNotificationHistoryContext(context.Background(), r)
+}
+
+// GetAllNotificationHistoryContext is like GetAllNotificationHistory but
+// takes a context.
+func (c *ServerClient) GetAllNotificationHistoryContext(ctx context.Context, r *NotificationHistoryRequest) ([]NotificationHistoryItem, error) {
+	var all []NotificationHistoryItem
+
+	token := ""
+	for {
+		res, err := c.GetNotificationHistoryContext(ctx, r, token)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, res.NotificationHistory...)
+
+		if !res.HasMore || res.PaginationToken == "" {
+			return all, nil
+		}
+
+		token = res.PaginationToken
+	}
+}
+
+// ReplayNotificationHistory passes the notifications of the history to fn in
+// the order the App Store sent them, page by page. It stops at the first
+// error. Fn can be the OnNotification callback of a NotificationHandler.
+func (c *ServerClient) ReplayNotificationHistory(r *NotificationHistoryRequest, fn func(ctx context.Context, n *Notification) error) error {
+	return c.ReplayNotificationHistoryContext(context.Background(), r, fn)
+}
+
+// ReplayNotificationHistoryContext is like ReplayNotificationHistory but
+// takes a context.
+func (c *ServerClient) ReplayNotificationHistoryContext(ctx context.Context, r *NotificationHistoryRequest, fn func(ctx context.Context, n *Notification) error) error {
+	token := ""
+	for {
+		res, err := c.GetNotificationHistoryContext(ctx, r, token)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range res.NotificationHistory {
+			if err := fn(ctx, item.Notification); err != nil {
+				return err
+			}
+		}
+
+		if !res.HasMore || res.PaginationToken == "" {
+			return nil
+		}
+
+		token = res.PaginationToken
+	}
+}
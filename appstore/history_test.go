@@ -0,0 +1,84 @@
+package appstore_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/brainleap/iap/appstore"
+	"github.com/brainleap/iap/iaptest"
+)
+
+func TestReplayNotificationHistory(t *testing.T) {
+	store := iaptest.NewAppStore()
+	defer store.Close()
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+
+	// More than two pages of the fake's page size of 20.
+	var uuids []string
+	for i := 0; i < 45; i++ {
+		uuid := "uuid-" + strconv.Itoa(i)
+		uuids = append(uuids, uuid)
+
+		err := store.AddNotification(&appstore.Notification{
+			NotificationType: appstore.NTDidRenew,
+			NotificationUUID: uuid,
+			SignedDate:       now - int64(45-i),
+		}, appstore.SARSuccess)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, err := store.ServerClient(appstore.ProductionMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &appstore.NotificationHistoryRequest{StartDate: now - 1000, EndDate: now + 1000}
+	errStop := errors.New("stop")
+
+	tests := []struct {
+		name    string
+		stopAt  int
+		want    []string
+		wantErr error
+	}{
+		{name: "all pages", stopAt: -1, want: uuids},
+		{name: "callback error on first page", stopAt: 5, want: uuids[:6], wantErr: errStop},
+		{name: "callback error on later page", stopAt: 30, want: uuids[:31], wantErr: errStop},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			err := c.ReplayNotificationHistory(req, func(ctx context.Context, n *appstore.Notification) error {
+				got = append(got, n.NotificationUUID)
+				if len(got)-1 == tt.stopAt {
+					return errStop
+				}
+				return nil
+			})
+			if err != tt.wantErr {
+				t.Fatalf("ReplayNotificationHistory() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("replayed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	all, err := c.GetAllNotificationHistory(req)
+	if err != nil {
+		t.Fatalf("GetAllNotificationHistory() error = %v", err)
+	}
+
+	if len(all) != len(uuids) || all[len(all)-1].Notification.NotificationUUID != uuids[len(uuids)-1] {
+		t.Errorf("GetAllNotificationHistory() returned %d notifications, want %d", len(all), len(uuids))
+	}
+}
@@ -0,0 +1,255 @@
+package appstore
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// appleRootCAG3 is the Apple Root CA - G3 certificate that App Store signed
+// data chains to.
+const appleRootCAG3 = `-----BEGIN CERTIFICATE-----
+MIICQzCCAcmgAwIBAgIILcX8iNLFS5UwCgYIKoZIzj0EAwMwZzEbMBkGA1UEAwwS
+QXBwbGUgUm9vdCBDQSAtIEczMSYwJAYDVQQLDB1BcHBsZSBDZXJ0aWZpY2F0aW9u
+IEF1dGhvcml0eTETMBEGA1UECgwKQXBwbGUgSW5jLjELMAkGA1UEBhMCVVMwHhcN
+MTQwNDMwMTgxOTA2WhcNMzkwNDMwMTgxOTA2WjBnMRswGQYDVQQDDBJBcHBsZSBS
+b290IENBIC0gRzMxJjAkBgNVBAsMHUFwcGxlIENlcnRpZmljYXRpb24gQXV0aG9y
+aXR5MRMwEQYDVQQKDApBcHBsZSBJbmMuMQswCQYDVQQGEwJVUzB2MBAGByqGSM49
+AgEGBSuBBAAiA2IABJjpLz1AcqTtkyJygRMc3RCV8cWjTnHcFBbZDuWmBSp3ZHtf
+TjjTuxxEtX/1H7YyYl3J6YRbTzBPEVoA/VhYDKX1DyxNB0cTddqXl5dvMVztK517
+IDvYuVTZXpmkOlEKMaNCMEAwHQYDVR0OBBYEFLuw3qFYM4iapIqZ3r6966/ayySr
+MA8GA1UdEwEB/wQFMAMBAf8wDgYDVR0PAQH/BAQDAgEGMAoGCCqGSM49BAMDA2gA
+MGUCMQCD6cHEFl4aXTQY2e3v9GwOAEZLuN+yRhHFD/3meoyhpmvOwgPUnPWTxnS4
+at+qIxUCMG1mihDK1A3UT82NQz60imOlM27jbdoXt2QfyFMm+YhidDkLF1vLUagM
+6BgD56KyKA==
+-----END CERTIFICATE-----
+`
+
+// Marker extensions Apple sets on the certificates signing App Store data.
+var (
+	oidLeafMarker         = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 6, 11, 1}
+	oidIntermediateMarker = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 6, 2, 1}
+)
+
+// AppleRootCA returns the pinned Apple Root CA - G3 certificate.
+func AppleRootCA() *x509.Certificate {
+	block, _ := pem.Decode([]byte(appleRootCAG3))
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		panic(err)
+	}
+
+	return cert
+}
+
+// JWSVerifier verifies data signed by the App Store in JWS compact format.
+//
+// The x5c certificate chain in the JWS header must chain to Root, which
+// defaults to the Apple Root CA - G3. The leaf and intermediate certificates
+// must carry Apple's marker extensions.
+type JWSVerifier struct {
+	Root *x509.Certificate
+}
+
+// NewJWSVerifier creates a new JWS verifier trusting the given root. A nil
+// root selects the Apple Root CA - G3.
+func NewJWSVerifier(root *x509.Certificate) *JWSVerifier {
+	if root == nil {
+		root = AppleRootCA()
+	}
+
+	return &JWSVerifier{Root: root}
+}
+
+// Verify verifies the signature and certificate chain of a JWS and decodes
+// its payload into v.
+func (jv *JWSVerifier) Verify(token string, v interface{}) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return errors.New("malformed jws")
+	}
+
+	var header struct {
+		Alg string   `json:"alg"`
+		X5C []string `json:"x5c"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return err
+	}
+
+	if header.Alg != "ES256" {
+		return fmt.Errorf("unexpected signing algorithm: %s", header.Alg)
+	}
+
+	leaf, err := jv.verifyChain(header.X5C)
+	if err != nil {
+		return err
+	}
+
+	pub, ok := leaf.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return errors.New("signing certificate has no ecdsa key")
+	}
+
+	if err := verifyES256(pub, parts); err != nil {
+		return err
+	}
+
+	return decodeSegment(parts[1], v)
+}
+
+// verifyES256 verifies the ES256 signature of the parts of a JWS in compact
+// format.
+func verifyES256(pub *ecdsa.PublicKey, parts []string) error {
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return err
+	}
+
+	if len(sig) != 64 {
+		return errors.New("malformed jws signature")
+	}
+
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if !ecdsa.Verify(pub, digest[:], r, s) {
+		return errors.New("invalid jws signature")
+	}
+
+	return nil
+}
+
+func (jv *JWSVerifier) verifyChain(x5c []string) (*x509.Certificate, error) {
+	if len(x5c) < 2 {
+		return nil, errors.New("incomplete certificate chain")
+	}
+
+	certs := make([]*x509.Certificate, len(x5c))
+	for i, c := range x5c {
+		der, err := base64.StdEncoding.DecodeString(c)
+		if err != nil {
+			return nil, err
+		}
+
+		certs[i], err = x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	root := jv.Root
+	if root == nil {
+		root = AppleRootCA()
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+
+	leaf := certs[0]
+
+	chains, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !hasExtension(leaf, oidLeafMarker) {
+		return nil, errors.New("certificate chain is not issued for app store")
+	}
+
+	// Check the intermediate that actually issued the leaf, not whatever was
+	// sent next to it in x5c.
+	for _, chain := range chains {
+		if len(chain) > 2 && hasExtension(chain[1], oidIntermediateMarker) {
+			return leaf, nil
+		}
+	}
+
+	return nil, errors.New("certificate chain is not issued for app store")
+}
+
+// ParsePrivateKey parses an App Store Connect .p8 private key.
+func ParsePrivateKey(p8 []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(p8)
+	if block == nil {
+		return nil, errors.New("private key is not pem encoded")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an ecdsa key")
+	}
+
+	return ecKey, nil
+}
+
+// signJWS signs the header and payload with ES256 in JWS compact format.
+func signJWS(key *ecdsa.PrivateKey, header, payload interface{}) (string, error) {
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+
+	p, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(p)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	sig := make([]byte, 64)
+	rb, sb := r.Bytes(), s.Bytes()
+	copy(sig[32-len(rb):32], rb)
+	copy(sig[64-len(sb):], sb)
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func hasExtension(c *x509.Certificate, oid asn1.ObjectIdentifier) bool {
+	for _, e := range c.Extensions {
+		if e.Id.Equal(oid) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
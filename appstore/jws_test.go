@@ -0,0 +1,348 @@
+package appstore
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// certTemplate describes a certificate issued by issueCertificate.
+type certTemplate struct {
+	cn        string
+	ca        bool
+	marker    asn1.ObjectIdentifier
+	notBefore time.Time
+	notAfter  time.Time
+}
+
+// issueCertificate issues a certificate for pub signed by parent, or a
+// self-signed one when parent is nil.
+func issueCertificate(t *testing.T, tmpl certTemplate, pub interface{}, parent *x509.Certificate, parentKey crypto.Signer) *x509.Certificate {
+	t.Helper()
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tmpl.notBefore.IsZero() {
+		tmpl.notBefore = time.Now().Add(-time.Hour)
+	}
+	if tmpl.notAfter.IsZero() {
+		tmpl.notAfter = time.Now().Add(24 * time.Hour)
+	}
+
+	c := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: tmpl.cn},
+		NotBefore:             tmpl.notBefore,
+		NotAfter:              tmpl.notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+
+	if tmpl.ca {
+		c.KeyUsage = x509.KeyUsageCertSign
+		c.IsCA = true
+	}
+
+	if tmpl.marker != nil {
+		c.ExtraExtensions = []pkix.Extension{{Id: tmpl.marker, Value: []byte{0x05, 0x00}}}
+	}
+
+	if parent == nil {
+		parent = c
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, c, parent, pub, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+func generateECKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+// testChain is an App Store style signing chain.
+type testChain struct {
+	root *x509.Certificate
+	x5c  []string
+	key  *ecdsa.PrivateKey
+}
+
+func newTestChain(t *testing.T, leafMarker, intermediateMarker asn1.ObjectIdentifier, leafNotAfter time.Time) *testChain {
+	t.Helper()
+
+	rootKey := generateECKey(t)
+	root := issueCertificate(t, certTemplate{cn: "Test Root CA", ca: true}, &rootKey.PublicKey, nil, rootKey)
+
+	intermediateKey := generateECKey(t)
+	intermediate := issueCertificate(t, certTemplate{cn: "Test Intermediate CA", ca: true, marker: intermediateMarker}, &intermediateKey.PublicKey, root, rootKey)
+
+	key := generateECKey(t)
+	leaf := issueCertificate(t, certTemplate{cn: "Test Signing", marker: leafMarker, notAfter: leafNotAfter}, &key.PublicKey, intermediate, intermediateKey)
+
+	return &testChain{
+		root: root,
+		x5c: []string{
+			base64.StdEncoding.EncodeToString(leaf.Raw),
+			base64.StdEncoding.EncodeToString(intermediate.Raw),
+			base64.StdEncoding.EncodeToString(root.Raw),
+		},
+		key: key,
+	}
+}
+
+func (c *testChain) sign(t *testing.T, v interface{}) string {
+	t.Helper()
+
+	token, err := signJWS(c.key, map[string]interface{}{"alg": "ES256", "x5c": c.x5c}, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func TestJWSVerifierVerify(t *testing.T) {
+	chain := newTestChain(t, oidLeafMarker, oidIntermediateMarker, time.Time{})
+	payload := map[string]string{"transactionId": "1000"}
+
+	// sign signs the payload with the header fields of c, overriding alg or
+	// x5c when given.
+	sign := func(t *testing.T, c *testChain, key *ecdsa.PrivateKey, alg string, x5c []string) string {
+		if key == nil {
+			key = c.key
+		}
+		if alg == "" {
+			alg = "ES256"
+		}
+		if x5c == nil {
+			x5c = c.x5c
+		}
+
+		token, err := signJWS(key, map[string]interface{}{"alg": alg, "x5c": x5c}, payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return token
+	}
+
+	tests := []struct {
+		name    string
+		chain   func(t *testing.T) *testChain
+		root    *x509.Certificate
+		token   func(t *testing.T, c *testChain) string
+		wantErr bool
+	}{
+		{name: "valid"},
+		{name: "untrusted root", root: AppleRootCA(), wantErr: true},
+		{
+			name:    "missing leaf marker",
+			chain:   func(t *testing.T) *testChain { return newTestChain(t, nil, oidIntermediateMarker, time.Time{}) },
+			wantErr: true,
+		},
+		{
+			name:    "missing intermediate marker",
+			chain:   func(t *testing.T) *testChain { return newTestChain(t, oidLeafMarker, nil, time.Time{}) },
+			wantErr: true,
+		},
+		{
+			name:  "intermediate marker on unrelated certificate",
+			chain: func(t *testing.T) *testChain { return newTestChain(t, oidLeafMarker, nil, time.Time{}) },
+			token: func(t *testing.T, c *testChain) string {
+				key := generateECKey(t)
+				decoy := issueCertificate(t, certTemplate{cn: "Test Decoy CA", ca: true, marker: oidIntermediateMarker}, &key.PublicKey, nil, key)
+
+				x5c := []string{c.x5c[0], base64.StdEncoding.EncodeToString(decoy.Raw), c.x5c[1], c.x5c[2]}
+				return sign(t, c, nil, "", x5c)
+			},
+			wantErr: true,
+		},
+		{
+			name: "expired leaf",
+			chain: func(t *testing.T) *testChain {
+				return newTestChain(t, oidLeafMarker, oidIntermediateMarker, time.Now().Add(-time.Minute))
+			},
+			wantErr: true,
+		},
+		{
+			name:    "incomplete chain",
+			token:   func(t *testing.T, c *testChain) string { return sign(t, c, nil, "", c.x5c[:1]) },
+			wantErr: true,
+		},
+		{
+			name:    "unexpected algorithm",
+			token:   func(t *testing.T, c *testChain) string { return sign(t, c, nil, "none", nil) },
+			wantErr: true,
+		},
+		{
+			name:    "signed by other key",
+			token:   func(t *testing.T, c *testChain) string { return sign(t, c, generateECKey(t), "", nil) },
+			wantErr: true,
+		},
+		{
+			name: "tampered payload",
+			token: func(t *testing.T, c *testChain) string {
+				parts := strings.Split(sign(t, c, nil, "", nil), ".")
+				parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"transactionId":"2000"}`))
+				return strings.Join(parts, ".")
+			},
+			wantErr: true,
+		},
+		{
+			name:    "malformed",
+			token:   func(t *testing.T, c *testChain) string { return "a.b" },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := chain
+			if tt.chain != nil {
+				c = tt.chain(t)
+			}
+
+			root := c.root
+			if tt.root != nil {
+				root = tt.root
+			}
+
+			token := sign(t, c, nil, "", nil)
+			if tt.token != nil {
+				token = tt.token(t, c)
+			}
+
+			var got map[string]string
+			err := NewJWSVerifier(root).Verify(token, &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Verify() succeeded, want error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+
+			if got["transactionId"] != "1000" {
+				t.Errorf("Verify() payload = %v", got)
+			}
+		})
+	}
+}
+
+func TestJWSVerifierParseNotification(t *testing.T) {
+	chain := newTestChain(t, oidLeafMarker, oidIntermediateMarker, time.Time{})
+
+	n := &Notification{
+		NotificationType: NTDidRenew,
+		NotificationUUID: "uuid",
+		Version:          "2.0",
+		Data: &NotificationData{
+			BundleID:              "com.example.app",
+			Environment:           EnvironmentSandbox,
+			SignedTransactionInfo: chain.sign(t, &TransactionInfo{TransactionID: "1001", OriginalTransactionID: "1000"}),
+			SignedRenewalInfo:     chain.sign(t, &RenewalInfo{OriginalTransactionID: "1000", AutoRenewStatus: 1}),
+		},
+	}
+
+	got, err := NewJWSVerifier(chain.root).ParseNotification(chain.sign(t, n))
+	if err != nil {
+		t.Fatalf("ParseNotification() error = %v", err)
+	}
+
+	if got.NotificationType != NTDidRenew || got.Transaction == nil || got.Transaction.TransactionID != "1001" {
+		t.Errorf("ParseNotification() = %+v, transaction %+v", got, got.Transaction)
+	}
+
+	if got.RenewalInfo == nil || got.RenewalInfo.OriginalTransactionID != "1000" {
+		t.Errorf("ParseNotification() renewal info = %+v", got.RenewalInfo)
+	}
+
+	other := newTestChain(t, oidLeafMarker, oidIntermediateMarker, time.Time{})
+	n.Data.SignedTransactionInfo = other.sign(t, &TransactionInfo{TransactionID: "1001"})
+
+	if _, err := NewJWSVerifier(chain.root).ParseNotification(chain.sign(t, n)); err == nil {
+		t.Error("ParseNotification() with untrusted transaction succeeded, want error")
+	}
+}
+
+func TestNotificationHandler(t *testing.T) {
+	chain := newTestChain(t, oidLeafMarker, oidIntermediateMarker, time.Time{})
+	other := newTestChain(t, oidLeafMarker, oidIntermediateMarker, time.Time{})
+
+	n := &Notification{NotificationType: NTTest, NotificationUUID: "uuid", Version: "2.0"}
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+		wantCalled bool
+	}{
+		{name: "valid", method: http.MethodPost, body: chain.sign(t, n), wantStatus: http.StatusOK, wantCalled: true},
+		{name: "untrusted", method: http.MethodPost, body: other.sign(t, n), wantStatus: http.StatusBadRequest},
+		{name: "method", method: http.MethodGet, wantStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			h := &NotificationHandler{
+				Verifier: NewJWSVerifier(chain.root),
+				OnNotification: func(ctx context.Context, got *Notification) error {
+					called = got.NotificationType == NTTest
+					return nil
+				},
+			}
+
+			body, err := json.Marshal(map[string]string{"signedPayload": tt.body})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(tt.method, "/notifications", strings.NewReader(string(body))))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+
+			if called != tt.wantCalled {
+				t.Errorf("OnNotification called = %v, want %v", called, tt.wantCalled)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package appstore
+
+import (
+	"time"
+
+	"github.com/brainleap/iap"
+)
+
+// ClassifySubscription maps the latest transaction of a subscription product
+// in a receipt validation response to its lifecycle state at the given time.
+// It returns iap.ErrPurchaseNotFound when the response has no transaction of
+// the product. A subscription the user upgraded from is reported as expired.
+func ClassifySubscription(r *Response, productID string, now time.Time) (iap.SubscriptionStatus, error) {
+	latest := r.latestTransaction(productID)
+	if latest == nil {
+		return iap.SubscriptionStatus{}, iap.ErrPurchaseNotFound
+	}
+
+	if latest.Upgraded() {
+		return iap.SubscriptionStatus{Lifecycle: iap.LifecycleExpired}, nil
+	}
+
+	if latest.Canceled() {
+		return iap.SubscriptionStatus{Lifecycle: iap.LifecycleRefunded}, nil
+	}
+
+	renewal := r.pendingRenewalInfo(latest.OriginalTransactionID)
+
+	if expiry := latest.ExpiresTime(); expiry.After(now) {
+		st := iap.SubscriptionStatus{NextChange: expiry}
+
+		switch {
+		case renewal != nil && autoRenewOff(renewal):
+			st.Lifecycle = iap.LifecycleCanceled
+		case latest.TrialPeriod():
+			st.Lifecycle = iap.LifecycleTrial
+		case latest.IntroOfferPeriod():
+			st.Lifecycle = iap.LifecycleIntroOffer
+		default:
+			st.Lifecycle = iap.LifecycleActive
+		}
+
+		return st, nil
+	}
+
+	if renewal != nil && renewal.InBillingRetry() {
+		if grace := renewal.GracePeriodExpiresTime(); grace.After(now) {
+			return iap.SubscriptionStatus{Lifecycle: iap.LifecycleGracePeriod, NextChange: grace}, nil
+		}
+
+		return iap.SubscriptionStatus{Lifecycle: iap.LifecycleAccountHold}, nil
+	}
+
+	return iap.SubscriptionStatus{Lifecycle: iap.LifecycleExpired}, nil
+}
+
+// latestTransaction returns the most recent transaction of a product in the
+// response, or nil if there is none.
+func (r *Response) latestTransaction(productID string) *InApp {
+	var txs []InApp
+	txs = append(txs, r.LatestReceiptInfo...)
+	if r.Receipt != nil {
+		txs = append(txs, r.Receipt.InApp...)
+	}
+
+	var latest *InApp
+	for i := range txs {
+		tx := &txs[i]
+		if tx.ProductID != productID {
+			continue
+		}
+
+		if latest == nil || tx.PurchaseTime().After(latest.PurchaseTime()) {
+			latest = tx
+		}
+	}
+
+	return latest
+}
+
+func (r *Response) pendingRenewalInfo(originalTransactionID string) *PendingRenewalInfo {
+	for i := range r.PendingRenewalInfo {
+		if r.PendingRenewalInfo[i].OriginalTransactionID == originalTransactionID {
+			return &r.PendingRenewalInfo[i]
+		}
+	}
+
+	return nil
+}
+
+func autoRenewOff(p *PendingRenewalInfo) bool {
+	s, ok := p.AutoRenewStatus()
+	return ok && s == ARSOff
+}
@@ -0,0 +1,152 @@
+package appstore
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/brainleap/iap"
+)
+
+func TestClassifySubscription(t *testing.T) {
+	now := time.Now()
+	ms := func(d time.Duration) string {
+		return strconv.FormatInt(now.Add(d).UnixNano()/int64(time.Millisecond), 10)
+	}
+
+	future, past := ms(24*time.Hour), ms(-time.Hour)
+
+	// tx returns a transaction of the subscription purchased at the given
+	// offset from now.
+	tx := func(purchased time.Duration, expires string) InApp {
+		return InApp{
+			ProductID:             "monthly",
+			TransactionID:         "1000",
+			OriginalTransactionID: "1000",
+			PurchaseDateMS:        ms(purchased),
+			ExpiresDateMS:         expires,
+		}
+	}
+
+	tests := []struct {
+		name           string
+		txs            []InApp
+		renewal        *PendingRenewalInfo
+		wantLifecycle  iap.Lifecycle
+		wantNextChange string
+		wantErr        error
+	}{
+		{
+			name:           "active",
+			txs:            []InApp{tx(-time.Hour, future)},
+			renewal:        &PendingRenewalInfo{SubscriptionAutoRenewStatus: "1"},
+			wantLifecycle:  iap.LifecycleActive,
+			wantNextChange: future,
+		},
+		{
+			name: "trial",
+			txs: []InApp{func() InApp {
+				in := tx(-time.Hour, future)
+				in.IsTrialPeriod = "true"
+				return in
+			}()},
+			wantLifecycle:  iap.LifecycleTrial,
+			wantNextChange: future,
+		},
+		{
+			name: "intro offer",
+			txs: []InApp{func() InApp {
+				in := tx(-time.Hour, future)
+				in.IsInIntroOfferPeriod = "true"
+				return in
+			}()},
+			wantLifecycle:  iap.LifecycleIntroOffer,
+			wantNextChange: future,
+		},
+		{
+			name:           "canceled",
+			txs:            []InApp{tx(-time.Hour, future)},
+			renewal:        &PendingRenewalInfo{SubscriptionAutoRenewStatus: "0"},
+			wantLifecycle:  iap.LifecycleCanceled,
+			wantNextChange: future,
+		},
+		{
+			name: "latest transaction",
+			txs: []InApp{
+				tx(-48*time.Hour, past),
+				tx(-time.Hour, future),
+			},
+			wantLifecycle:  iap.LifecycleActive,
+			wantNextChange: future,
+		},
+		{
+			name:           "grace period",
+			txs:            []InApp{tx(-48*time.Hour, past)},
+			renewal:        &PendingRenewalInfo{SubscriptionRetryFlag: "1", GracePeriodExpiresDateMS: future},
+			wantLifecycle:  iap.LifecycleGracePeriod,
+			wantNextChange: future,
+		},
+		{
+			name:          "account hold",
+			txs:           []InApp{tx(-48*time.Hour, past)},
+			renewal:       &PendingRenewalInfo{SubscriptionRetryFlag: "1"},
+			wantLifecycle: iap.LifecycleAccountHold,
+		},
+		{
+			name:          "expired",
+			txs:           []InApp{tx(-48*time.Hour, past)},
+			renewal:       &PendingRenewalInfo{SubscriptionExpirationIntent: "1"},
+			wantLifecycle: iap.LifecycleExpired,
+		},
+		{
+			name: "refunded",
+			txs: []InApp{func() InApp {
+				in := tx(-time.Hour, future)
+				in.CancellationDateMS = ms(-time.Minute)
+				in.CancellationReason = "0"
+				return in
+			}()},
+			wantLifecycle: iap.LifecycleRefunded,
+		},
+		{
+			name: "upgraded",
+			txs: []InApp{func() InApp {
+				in := tx(-time.Hour, future)
+				in.CancellationDateMS = ms(-time.Minute)
+				in.IsUpgraded = "true"
+				return in
+			}()},
+			wantLifecycle: iap.LifecycleExpired,
+		},
+		{
+			name:    "other product",
+			txs:     []InApp{{ProductID: "yearly", ExpiresDateMS: future}},
+			wantErr: iap.ErrPurchaseNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Response{LatestReceiptInfo: tt.txs}
+			if tt.renewal != nil {
+				ri := *tt.renewal
+				ri.OriginalTransactionID = "1000"
+				r.PendingRenewalInfo = []PendingRenewalInfo{ri}
+			}
+
+			got, err := ClassifySubscription(r, "monthly", now)
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Fatalf("ClassifySubscription() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if got.Lifecycle != tt.wantLifecycle {
+				t.Errorf("Lifecycle = %s, want %s", got.Lifecycle, tt.wantLifecycle)
+			}
+
+			if want := millis(tt.wantNextChange); !got.NextChange.Equal(want) {
+				t.Errorf("NextChange = %v, want %v", got.NextChange, want)
+			}
+		})
+	}
+}
@@ -1,6 +1,66 @@
 package appstore
 
-import "errors"
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brainleap/iap"
+)
+
+// Receipt validation statuses handled by the client.
+const (
+	statusServerUnavailable = 21005
+	statusSandboxReceipt    = 21007
+)
+
+// OwnershipType is the data type for in-app purchase ownership types.
+type OwnershipType string
+
+// List of ownership types.
+const (
+	OTPurchased    OwnershipType = "PURCHASED"
+	OTFamilyShared OwnershipType = "FAMILY_SHARED"
+)
+
+// ExpirationIntent is the data type for subscription expiration intents.
+type ExpirationIntent int
+
+// List of expiration intents.
+const (
+	EICanceled           ExpirationIntent = 1
+	EIBillingError       ExpirationIntent = 2
+	EIPriceIncrease      ExpirationIntent = 3
+	EIProductUnavailable ExpirationIntent = 4
+	EIUnknown            ExpirationIntent = 5
+)
+
+// AutoRenewStatus is the data type for subscription auto-renew statuses.
+type AutoRenewStatus int
+
+// List of auto-renew statuses.
+const (
+	ARSOff AutoRenewStatus = 0
+	ARSOn  AutoRenewStatus = 1
+)
+
+// PriceConsentStatus is the data type for price increase consent statuses.
+type PriceConsentStatus int
+
+// List of price consent statuses.
+const (
+	PCSNotConsented PriceConsentStatus = 0
+	PCSConsented    PriceConsentStatus = 1
+)
+
+// CancellationReason is the data type for cancellation reasons.
+type CancellationReason int
+
+// List of cancellation reasons.
+const (
+	CROther    CancellationReason = 0
+	CRAppIssue CancellationReason = 1
+)
 
 // Response contains the status of a receipt validation.
 type Response struct {
@@ -10,6 +70,8 @@ type Response struct {
 	LatestReceiptInfo  []InApp              `json:"latest_receipt_info"`
 	PendingRenewalInfo []PendingRenewalInfo `json:"pending_renewal_info"`
 	IsRetryable        bool                 `json:"is-retryable"`
+	Environment        Environment          `json:"environment"`
+	Mode               Mode                 `json:"-"`
 }
 
 // Receipt is the receipt that was sent for verification.
@@ -32,72 +94,268 @@ type Receipt struct {
 	OriginalPurchaseDateMS     string  `json:"original_purchase_date_ms"`
 	OriginalPurchaseDatePST    string  `json:"original_purchase_date_pst"`
 	OriginalApplicationVersion string  `json:"original_application_version"`
+	ExpirationDate             string  `json:"expiration_date"`
+	ExpirationDateMS           string  `json:"expiration_date_ms"`
+	ExpirationDatePST          string  `json:"expiration_date_pst"`
 }
 
 // InApp is the receipt for an in-app purchase.
 type InApp struct {
-	Quantity                string `json:"quantity"`
-	ProductID               string `json:"product_id"`
-	TransactionID           string `json:"transaction_id"`
-	OriginalTransactionID   string `json:"original_transaction_id"`
-	WebOrderLineItemID      string `json:"web_order_line_item_id"`
-	IsTrialPeriod           string `json:"is_trial_period"`
-	IsInIntroOfferPeriod    string `json:"is_in_intro_offer_period"`
-	ExpiresDate             string `json:"expires_date"`
-	ExpiresDateMS           string `json:"expires_date_ms"`
-	ExpiresDatePST          string `json:"expires_date_pst"`
-	ExpiresDateFormatted    string `json:"expires_date_formatted"`
-	ExpiresDateFormattedPST string `json:"expires_date_formatted_pst"`
-	PurchaseDate            string `json:"purchase_date"`
-	PurchaseDateMS          string `json:"purchase_date_ms"`
-	PurchaseDatePST         string `json:"purchase_date_pst"`
-	OriginalPurchaseDate    string `json:"original_purchase_date"`
-	OriginalPurchaseDateMS  string `json:"original_purchase_date_ms"`
-	OriginalPurchaseDatePST string `json:"original_purchase_date_pst"`
-	CancellationDate        string `json:"cancellation_date"`
-	CancellationDateMS      string `json:"cancellation_date_ms"`
-	CancellationDatePST     string `json:"cancellation_date_pst"`
-	CancellationReason      string `json:"cancellation_reason"`
+	Quantity                    string        `json:"quantity"`
+	ProductID                   string        `json:"product_id"`
+	TransactionID               string        `json:"transaction_id"`
+	OriginalTransactionID       string        `json:"original_transaction_id"`
+	WebOrderLineItemID          string        `json:"web_order_line_item_id"`
+	IsTrialPeriod               string        `json:"is_trial_period"`
+	IsInIntroOfferPeriod        string        `json:"is_in_intro_offer_period"`
+	ExpiresDate                 string        `json:"expires_date"`
+	ExpiresDateMS               string        `json:"expires_date_ms"`
+	ExpiresDatePST              string        `json:"expires_date_pst"`
+	ExpiresDateFormatted        string        `json:"expires_date_formatted"`
+	ExpiresDateFormattedPST     string        `json:"expires_date_formatted_pst"`
+	PurchaseDate                string        `json:"purchase_date"`
+	PurchaseDateMS              string        `json:"purchase_date_ms"`
+	PurchaseDatePST             string        `json:"purchase_date_pst"`
+	OriginalPurchaseDate        string        `json:"original_purchase_date"`
+	OriginalPurchaseDateMS      string        `json:"original_purchase_date_ms"`
+	OriginalPurchaseDatePST     string        `json:"original_purchase_date_pst"`
+	CancellationDate            string        `json:"cancellation_date"`
+	CancellationDateMS          string        `json:"cancellation_date_ms"`
+	CancellationDatePST         string        `json:"cancellation_date_pst"`
+	CancellationReason          string        `json:"cancellation_reason"`
+	InAppOwnershipType          OwnershipType `json:"in_app_ownership_type"`
+	PromotionalOfferID          string        `json:"promotional_offer_id"`
+	OfferCodeRefName            string        `json:"offer_code_ref_name"`
+	SubscriptionGroupIdentifier string        `json:"subscription_group_identifier"`
+	AppAccountToken             string        `json:"app_account_token"`
+	IsUpgraded                  string        `json:"is_upgraded"`
 }
 
 // PendingRenewalInfo contains the pending renewal info for each auto-renewable
 // subscription
 type PendingRenewalInfo struct {
-	SubscriptionExpirationIntent   string `json:"expiration_intent"`
-	SubscriptionAutoRenewProductID string `json:"auto_renew_product_id"`
-	SubscriptionRetryFlag          string `json:"is_in_billing_retry_period"`
-	SubscriptionAutoRenewStatus    string `json:"auto_renew_status"`
-	SubscriptionPriceConsentStatus string `json:"price_consent_status"`
-	ProductID                      string `json:"product_id"`
-	OriginalTransactionID          string `json:"original_transaction_id"`
+	SubscriptionExpirationIntent    string `json:"expiration_intent"`
+	SubscriptionAutoRenewProductID  string `json:"auto_renew_product_id"`
+	SubscriptionRetryFlag           string `json:"is_in_billing_retry_period"`
+	SubscriptionAutoRenewStatus     string `json:"auto_renew_status"`
+	SubscriptionPriceConsentStatus  string `json:"price_consent_status"`
+	SubscriptionPriceIncreaseStatus string `json:"price_increase_status"`
+	GracePeriodExpiresDate          string `json:"grace_period_expires_date"`
+	GracePeriodExpiresDateMS        string `json:"grace_period_expires_date_ms"`
+	GracePeriodExpiresDatePST       string `json:"grace_period_expires_date_pst"`
+	PromotionalOfferID              string `json:"promotional_offer_id"`
+	OfferCodeRefName                string `json:"offer_code_ref_name"`
+	ProductID                       string `json:"product_id"`
+	OriginalTransactionID           string `json:"original_transaction_id"`
 }
 
-// Err returns receipt validation error.
+func (r *Response) retryable() bool {
+	return r.IsRetryable || r.Status == statusServerUnavailable
+}
+
+// Err returns receipt validation error as an *iap.Error carrying the status
+// in Code.
 func (r *Response) Err() error {
-	switch r.Status {
-	case 0:
+	if r.Status == 0 {
 		return nil
+	}
+
+	e := &iap.Error{
+		Store:     iap.AppStore,
+		Code:      r.Status,
+		Retryable: r.retryable(),
+		Details:   r,
+	}
+
+	switch r.Status {
 	case 21000:
-		return errors.New("could not read the JSON object you provided")
+		e.Message = "could not read the JSON object you provided"
+		e.Err = iap.ErrInvalidToken
 	case 21002:
-		return errors.New("data in the receipt-data property was malformed or missing")
+		e.Message = "data in the receipt-data property was malformed or missing"
+		e.Err = iap.ErrInvalidToken
 	case 21003:
-		return errors.New("receipt could not be authenticated")
+		e.Message = "receipt could not be authenticated"
+		e.Err = iap.ErrInvalidToken
 	case 21004:
-		return errors.New("shared secret you provided does not match the shared secret on file for your account")
+		e.Message = "shared secret you provided does not match the shared secret on file for your account"
+		e.Err = iap.ErrAuth
 	case 21005:
-		return errors.New("receipt server is not currently available")
+		e.Message = "receipt server is not currently available"
+		e.Err = iap.ErrUnavailable
 	case 21007:
-		return errors.New("receipt is from the test environment, but it was sent to the production environment for verification. Send it to the test environment instead")
+		e.Message = "receipt is from the test environment, but it was sent to the production environment for verification. Send it to the test environment instead"
+		e.Err = iap.ErrSandboxReceipt
 	case 21008:
-		return errors.New("receipt is from the production environment, but it was sent to the test environment for verification. Send it to the production environment instead")
+		e.Message = "receipt is from the production environment, but it was sent to the test environment for verification. Send it to the production environment instead"
+		e.Err = iap.ErrProductionReceipt
 	case 21010:
-		return errors.New("receipt could not be authorized. Treat this the same as if a purchase was never made")
+		e.Message = "receipt could not be authorized. Treat this the same as if a purchase was never made"
+		e.Err = iap.ErrPurchaseNotFound
 	default:
 		if r.Status >= 21100 && r.Status <= 21199 {
-			return errors.New("internal data access error")
+			e.Message = "internal data access error"
+			e.Err = iap.ErrUnavailable
+		} else {
+			e.Message = "unknown error occurred"
 		}
+	}
+
+	return e
+}
+
+// CreationTime returns the time the receipt was created.
+func (r *Receipt) CreationTime() time.Time {
+	return receiptTime(r.ReceiptCreationDateMS, r.ReceiptCreationDate)
+}
+
+// RequestTime returns the time the receipt validation request was processed.
+func (r *Receipt) RequestTime() time.Time {
+	return receiptTime(r.RequestDateMS, r.RequestDate)
+}
+
+// OriginalPurchaseTime returns the time the app was originally purchased.
+func (r *Receipt) OriginalPurchaseTime() time.Time {
+	return receiptTime(r.OriginalPurchaseDateMS, r.OriginalPurchaseDate)
+}
+
+// ExpirationTime returns the time a Volume Purchase Program receipt expires,
+// or the zero time if it does not.
+func (r *Receipt) ExpirationTime() time.Time {
+	return receiptTime(r.ExpirationDateMS, r.ExpirationDate)
+}
+
+// QuantityInt returns the number of items purchased.
+func (i *InApp) QuantityInt() int {
+	n, _ := strconv.Atoi(i.Quantity)
+	return n
+}
+
+// PurchaseTime returns the time of the transaction.
+func (i *InApp) PurchaseTime() time.Time {
+	return receiptTime(i.PurchaseDateMS, i.PurchaseDate)
+}
+
+// OriginalPurchaseTime returns the time of the original transaction.
+func (i *InApp) OriginalPurchaseTime() time.Time {
+	return receiptTime(i.OriginalPurchaseDateMS, i.OriginalPurchaseDate)
+}
 
-		return errors.New("unknown error occurred")
+// ExpiresTime returns the time a subscription expires or renews, or the zero
+// time for other products.
+func (i *InApp) ExpiresTime() time.Time {
+	return receiptTime(i.ExpiresDateMS, i.ExpiresDate)
+}
+
+// CancellationTime returns the time Apple customer support refunded the
+// transaction, or the zero time if it was not refunded.
+func (i *InApp) CancellationTime() time.Time {
+	return receiptTime(i.CancellationDateMS, i.CancellationDate)
+}
+
+// Canceled reports whether the transaction has a cancellation date. Apple sets
+// it when customer support refunds the transaction and when the user upgrades
+// to another subscription of the same group; see Upgraded.
+func (i *InApp) Canceled() bool {
+	return i.CancellationDateMS != "" || i.CancellationDate != ""
+}
+
+// CancellationReasonCode returns the reason of a refund. The boolean is false
+// if the transaction was not refunded.
+func (i *InApp) CancellationReasonCode() (CancellationReason, bool) {
+	n, ok := receiptCode(i.CancellationReason)
+	return CancellationReason(n), ok
+}
+
+// TrialPeriod reports whether the transaction is in a free trial period.
+func (i *InApp) TrialPeriod() bool {
+	return receiptBool(i.IsTrialPeriod)
+}
+
+// IntroOfferPeriod reports whether the transaction is in an introductory price
+// period.
+func (i *InApp) IntroOfferPeriod() bool {
+	return receiptBool(i.IsInIntroOfferPeriod)
+}
+
+// Upgraded reports whether the subscription transaction was canceled because
+// the user upgraded to another subscription of the same group.
+func (i *InApp) Upgraded() bool {
+	return receiptBool(i.IsUpgraded)
+}
+
+// FamilyShared reports whether the user has access to the transaction through
+// Family Sharing rather than by purchasing it.
+func (i *InApp) FamilyShared() bool {
+	return i.InAppOwnershipType == OTFamilyShared
+}
+
+// ExpirationIntent returns the reason a subscription expired. The boolean is
+// false if the subscription has not expired.
+func (p *PendingRenewalInfo) ExpirationIntent() (ExpirationIntent, bool) {
+	n, ok := receiptCode(p.SubscriptionExpirationIntent)
+	return ExpirationIntent(n), ok
+}
+
+// InBillingRetry reports whether Apple is still trying to renew an expired
+// subscription.
+func (p *PendingRenewalInfo) InBillingRetry() bool {
+	return p.SubscriptionRetryFlag == "1"
+}
+
+// AutoRenewStatus returns the renewal status of a subscription. The boolean
+// is false if the status is missing.
+func (p *PendingRenewalInfo) AutoRenewStatus() (AutoRenewStatus, bool) {
+	n, ok := receiptCode(p.SubscriptionAutoRenewStatus)
+	return AutoRenewStatus(n), ok
+}
+
+// PriceConsentStatus returns whether the user consented to a subscription
+// price increase. The boolean is false if there is no pending price increase.
+func (p *PendingRenewalInfo) PriceConsentStatus() (PriceConsentStatus, bool) {
+	n, ok := receiptCode(p.SubscriptionPriceConsentStatus)
+	return PriceConsentStatus(n), ok
+}
+
+// PriceIncreaseStatus returns whether the user accepted a subscription price
+// increase that requires consent. The boolean is false if there is no pending
+// price increase.
+func (p *PendingRenewalInfo) PriceIncreaseStatus() (PriceConsentStatus, bool) {
+	n, ok := receiptCode(p.SubscriptionPriceIncreaseStatus)
+	return PriceConsentStatus(n), ok
+}
+
+// GracePeriodExpiresTime returns the time the billing grace period of a
+// subscription ends, or the zero time if it is not in one.
+func (p *PendingRenewalInfo) GracePeriodExpiresTime() time.Time {
+	return receiptTime(p.GracePeriodExpiresDateMS, p.GracePeriodExpiresDate)
+}
+
+// receiptTime returns the time of a receipt date, preferring the millisecond
+// timestamp over the formatted date.
+func receiptTime(ms, date string) time.Time {
+	if t := millis(ms); !t.IsZero() {
+		return t
 	}
+
+	t, err := time.Parse(receiptDateFormat, strings.TrimSuffix(date, " Etc/GMT"))
+	if err != nil {
+		return time.Time{}
+	}
+
+	return t
+}
+
+func receiptBool(s string) bool {
+	b, _ := strconv.ParseBool(s)
+	return b
+}
+
+func receiptCode(s string) (int, bool) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, false
+	}
+
+	return n, true
 }
@@ -0,0 +1,266 @@
+package appstore
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// NotificationType is the data type for App Store Server Notification V2
+// types.
+type NotificationType string
+
+// List of notification types.
+const (
+	NTConsumptionRequest     NotificationType = "CONSUMPTION_REQUEST"
+	NTDidChangeRenewalPref   NotificationType = "DID_CHANGE_RENEWAL_PREF"
+	NTDidChangeRenewalStatus NotificationType = "DID_CHANGE_RENEWAL_STATUS"
+	NTDidFailToRenew         NotificationType = "DID_FAIL_TO_RENEW"
+	NTDidRenew               NotificationType = "DID_RENEW"
+	NTExpired                NotificationType = "EXPIRED"
+	NTExternalPurchaseToken  NotificationType = "EXTERNAL_PURCHASE_TOKEN"
+	NTGracePeriodExpired     NotificationType = "GRACE_PERIOD_EXPIRED"
+	NTOfferRedeemed          NotificationType = "OFFER_REDEEMED"
+	NTOneTimeCharge          NotificationType = "ONE_TIME_CHARGE"
+	NTPriceIncrease          NotificationType = "PRICE_INCREASE"
+	NTRefund                 NotificationType = "REFUND"
+	NTRefundDeclined         NotificationType = "REFUND_DECLINED"
+	NTRefundReversed         NotificationType = "REFUND_REVERSED"
+	NTRenewalExtended        NotificationType = "RENEWAL_EXTENDED"
+	NTRenewalExtension       NotificationType = "RENEWAL_EXTENSION"
+	NTRevoke                 NotificationType = "REVOKE"
+	NTSubscribed             NotificationType = "SUBSCRIBED"
+	NTTest                   NotificationType = "TEST"
+)
+
+// NotificationSubtype is the data type for notification subtypes.
+type NotificationSubtype string
+
+// List of notification subtypes.
+const (
+	NSTAccepted          NotificationSubtype = "ACCEPTED"
+	NSTAutoRenewDisabled NotificationSubtype = "AUTO_RENEW_DISABLED"
+	NSTAutoRenewEnabled  NotificationSubtype = "AUTO_RENEW_ENABLED"
+	NSTBillingRecovery   NotificationSubtype = "BILLING_RECOVERY"
+	NSTBillingRetry      NotificationSubtype = "BILLING_RETRY"
+	NSTDowngrade         NotificationSubtype = "DOWNGRADE"
+	NSTFailure           NotificationSubtype = "FAILURE"
+	NSTGracePeriod       NotificationSubtype = "GRACE_PERIOD"
+	NSTInitialBuy        NotificationSubtype = "INITIAL_BUY"
+	NSTPending           NotificationSubtype = "PENDING"
+	NSTPriceIncrease     NotificationSubtype = "PRICE_INCREASE"
+	NSTProductNotForSale NotificationSubtype = "PRODUCT_NOT_FOR_SALE"
+	NSTResubscribe       NotificationSubtype = "RESUBSCRIBE"
+	NSTSummary           NotificationSubtype = "SUMMARY"
+	NSTUnreported        NotificationSubtype = "UNREPORTED"
+	NSTUpgrade           NotificationSubtype = "UPGRADE"
+	NSTVoluntary         NotificationSubtype = "VOLUNTARY"
+)
+
+// Environment is the data type for server environments.
+type Environment string
+
+// List of server environments.
+const (
+	EnvironmentProduction   Environment = "Production"
+	EnvironmentSandbox      Environment = "Sandbox"
+	EnvironmentXcode        Environment = "Xcode"
+	EnvironmentLocalTesting Environment = "LocalTesting"
+)
+
+// Notification is a decoded App Store Server Notification V2.
+//
+// Transaction and RenewalInfo are decoded from the signed fields of Data when
+// present.
+type Notification struct {
+	NotificationType NotificationType     `json:"notificationType"`
+	Subtype          NotificationSubtype  `json:"subtype"`
+	NotificationUUID string               `json:"notificationUUID"`
+	Version          string               `json:"version"`
+	SignedDate       int64                `json:"signedDate"`
+	Data             *NotificationData    `json:"data"`
+	Summary          *NotificationSummary `json:"summary"`
+
+	Transaction *TransactionInfo `json:"-"`
+	RenewalInfo *RenewalInfo     `json:"-"`
+}
+
+// NotificationData is the app metadata and signed transaction data of a
+// notification.
+type NotificationData struct {
+	AppAppleID               int64       `json:"appAppleId"`
+	BundleID                 string      `json:"bundleId"`
+	BundleVersion            string      `json:"bundleVersion"`
+	Environment              Environment `json:"environment"`
+	SignedTransactionInfo    string      `json:"signedTransactionInfo"`
+	SignedRenewalInfo        string      `json:"signedRenewalInfo"`
+	Status                   int         `json:"status"`
+	ConsumptionRequestReason string      `json:"consumptionRequestReason"`
+}
+
+// NotificationSummary is the summary of a renewal date extension request
+// sent to all eligible subscribers.
+type NotificationSummary struct {
+	RequestIdentifier      string      `json:"requestIdentifier"`
+	Environment            Environment `json:"environment"`
+	AppAppleID             int64       `json:"appAppleId"`
+	BundleID               string      `json:"bundleId"`
+	ProductID              string      `json:"productId"`
+	StorefrontCountryCodes []string    `json:"storefrontCountryCodes"`
+	FailedCount            int64       `json:"failedCount"`
+	SucceededCount         int64       `json:"succeededCount"`
+}
+
+// TransactionInfo is a decoded signed transaction.
+type TransactionInfo struct {
+	AppAccountToken             string        `json:"appAccountToken"`
+	BundleID                    string        `json:"bundleId"`
+	Currency                    string        `json:"currency"`
+	Environment                 Environment   `json:"environment"`
+	ExpiresDate                 int64         `json:"expiresDate"`
+	InAppOwnershipType          OwnershipType `json:"inAppOwnershipType"`
+	IsUpgraded                  bool          `json:"isUpgraded"`
+	OfferDiscountType           string        `json:"offerDiscountType"`
+	OfferIdentifier             string        `json:"offerIdentifier"`
+	OfferType                   int           `json:"offerType"`
+	OriginalPurchaseDate        int64         `json:"originalPurchaseDate"`
+	OriginalTransactionID       string        `json:"originalTransactionId"`
+	Price                       int64         `json:"price"`
+	ProductID                   string        `json:"productId"`
+	PurchaseDate                int64         `json:"purchaseDate"`
+	Quantity                    int           `json:"quantity"`
+	RevocationDate              int64         `json:"revocationDate"`
+	RevocationReason            *int          `json:"revocationReason"`
+	SignedDate                  int64         `json:"signedDate"`
+	Storefront                  string        `json:"storefront"`
+	StorefrontID                string        `json:"storefrontId"`
+	SubscriptionGroupIdentifier string        `json:"subscriptionGroupIdentifier"`
+	TransactionID               string        `json:"transactionId"`
+	TransactionReason           string        `json:"transactionReason"`
+	Type                        string        `json:"type"`
+	WebOrderLineItemID          string        `json:"webOrderLineItemId"`
+}
+
+// RenewalInfo is a decoded signed subscription renewal info.
+type RenewalInfo struct {
+	AutoRenewProductID          string      `json:"autoRenewProductId"`
+	AutoRenewStatus             int         `json:"autoRenewStatus"`
+	Currency                    string      `json:"currency"`
+	EligibleWinBackOfferIDs     []string    `json:"eligibleWinBackOfferIds"`
+	Environment                 Environment `json:"environment"`
+	ExpirationIntent            int         `json:"expirationIntent"`
+	GracePeriodExpiresDate      int64       `json:"gracePeriodExpiresDate"`
+	IsInBillingRetryPeriod      bool        `json:"isInBillingRetryPeriod"`
+	OfferIdentifier             string      `json:"offerIdentifier"`
+	OfferType                   int         `json:"offerType"`
+	OriginalTransactionID       string      `json:"originalTransactionId"`
+	PriceIncreaseStatus         int         `json:"priceIncreaseStatus"`
+	ProductID                   string      `json:"productId"`
+	RecentSubscriptionStartDate int64       `json:"recentSubscriptionStartDate"`
+	RenewalDate                 int64       `json:"renewalDate"`
+	RenewalPrice                int64       `json:"renewalPrice"`
+	SignedDate                  int64       `json:"signedDate"`
+}
+
+// ParseNotification verifies a notification's signed payload and decodes it
+// together with its signed transaction and renewal info.
+func (jv *JWSVerifier) ParseNotification(signedPayload string) (*Notification, error) {
+	var n Notification
+	if err := jv.Verify(signedPayload, &n); err != nil {
+		return nil, err
+	}
+
+	if n.Data == nil {
+		return &n, nil
+	}
+
+	if n.Data.SignedTransactionInfo != "" {
+		t, err := jv.ParseTransaction(n.Data.SignedTransactionInfo)
+		if err != nil {
+			return nil, err
+		}
+
+		n.Transaction = t
+	}
+
+	if n.Data.SignedRenewalInfo != "" {
+		r, err := jv.ParseRenewalInfo(n.Data.SignedRenewalInfo)
+		if err != nil {
+			return nil, err
+		}
+
+		n.RenewalInfo = r
+	}
+
+	return &n, nil
+}
+
+// ParseTransaction verifies and decodes a signed transaction.
+func (jv *JWSVerifier) ParseTransaction(signed string) (*TransactionInfo, error) {
+	var t TransactionInfo
+	if err := jv.Verify(signed, &t); err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// ParseRenewalInfo verifies and decodes a signed renewal info.
+func (jv *JWSVerifier) ParseRenewalInfo(signed string) (*RenewalInfo, error) {
+	var r RenewalInfo
+	if err := jv.Verify(signed, &r); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// NotificationHandler receives App Store Server Notifications V2 and
+// dispatches the verified notifications to OnNotification.
+//
+// Verifier defaults to a verifier trusting the Apple Root CA - G3. Callback
+// errors are reported with a 500 status so that the App Store retries the
+// notification.
+type NotificationHandler struct {
+	Verifier       *JWSVerifier
+	OnNotification func(ctx context.Context, n *Notification) error
+}
+
+// ServeHTTP implements http.Handler.
+func (h *NotificationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		SignedPayload string `json:"signedPayload"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	jv := h.Verifier
+	if jv == nil {
+		jv = NewJWSVerifier(nil)
+	}
+
+	n, err := jv.ParseNotification(body.SignedPayload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if h.OnNotification != nil {
+		if err := h.OnNotification(r.Context(), n); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
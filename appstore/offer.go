@@ -0,0 +1,223 @@
+package appstore
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// offerSeparator separates the fields of a promotional offer payload.
+	offerSeparator = "\u2063"
+
+	// offerAudience is the audience of JWS promotional offer signatures.
+	offerAudience = "promotional-offer"
+)
+
+// OfferSigner signs subscription offers with an in-app purchase key from App
+// Store Connect.
+type OfferSigner struct {
+	KeyID    string
+	IssuerID string
+	BundleID string
+
+	key *ecdsa.PrivateKey
+}
+
+// NewOfferSigner creates a new offer signer with the given .p8 in-app purchase
+// key. IssuerID is only needed for JWS signatures.
+func NewOfferSigner(p8 []byte, keyID, issuerID, bundleID string) (*OfferSigner, error) {
+	key, err := ParsePrivateKey(p8)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OfferSigner{
+		KeyID:    keyID,
+		IssuerID: issuerID,
+		BundleID: bundleID,
+		key:      key,
+	}, nil
+}
+
+// OfferSignature is a signed promotional offer, as passed to StoreKit's
+// SKPaymentDiscount or Product.PurchaseOption.promotionalOffer. Timestamp is
+// in milliseconds since epoch and Signature is base64 encoded.
+type OfferSignature struct {
+	KeyID     string
+	Nonce     string
+	Timestamp int64
+	Signature string
+}
+
+// Sign signs a promotional offer of a subscription product. AppAccountToken
+// is the applicationUsername of the payment, or the app account token, and
+// may be empty.
+func (s *OfferSigner) Sign(productID, offerID, appAccountToken string) (*OfferSignature, error) {
+	nonce, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	sig := &OfferSignature{
+		KeyID:     s.KeyID,
+		Nonce:     nonce,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+	}
+
+	digest := sha256.Sum256([]byte(offerPayload(s.BundleID, productID, offerID, appAccountToken, sig)))
+
+	r, ss, err := ecdsa.Sign(rand.Reader, s.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := asn1.Marshal(ecdsaSignature{R: r, S: ss})
+	if err != nil {
+		return nil, err
+	}
+
+	sig.Signature = base64.StdEncoding.EncodeToString(der)
+
+	return sig, nil
+}
+
+// SignJWS signs a promotional offer of a subscription product in the JWS
+// format of StoreKit 2. TransactionID is the original transaction ID of the
+// customer's subscription and may be empty.
+func (s *OfferSigner) SignJWS(productID, offerID, transactionID string) (string, error) {
+	nonce, err := newUUID()
+	if err != nil {
+		return "", err
+	}
+
+	header := map[string]string{
+		"alg": "ES256",
+		"kid": s.KeyID,
+		"typ": "JWT",
+	}
+	claims := OfferClaims{
+		Issuer:          s.IssuerID,
+		IssuedAt:        time.Now().Unix(),
+		Audience:        offerAudience,
+		BundleID:        s.BundleID,
+		Nonce:           nonce,
+		ProductID:       productID,
+		OfferIdentifier: offerID,
+		TransactionID:   transactionID,
+	}
+
+	return signJWS(s.key, header, &claims)
+}
+
+// OfferClaims are the claims of a JWS promotional offer signature.
+type OfferClaims struct {
+	Issuer          string `json:"iss"`
+	IssuedAt        int64  `json:"iat"`
+	Audience        string `json:"aud"`
+	BundleID        string `json:"bid"`
+	Nonce           string `json:"nonce"`
+	ProductID       string `json:"productId"`
+	OfferIdentifier string `json:"offerIdentifier"`
+	TransactionID   string `json:"transactionId,omitempty"`
+}
+
+// VerifyOfferSignature verifies a promotional offer signature with the public
+// key of the in-app purchase key it was signed with.
+func VerifyOfferSignature(pub *ecdsa.PublicKey, bundleID, productID, offerID, appAccountToken string, sig *OfferSignature) error {
+	der, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return err
+	}
+
+	var es ecdsaSignature
+	if rest, err := asn1.Unmarshal(der, &es); err != nil || len(rest) > 0 {
+		return errors.New("malformed offer signature")
+	}
+
+	digest := sha256.Sum256([]byte(offerPayload(bundleID, productID, offerID, appAccountToken, sig)))
+	if !ecdsa.Verify(pub, digest[:], es.R, es.S) {
+		return errors.New("invalid offer signature")
+	}
+
+	return nil
+}
+
+// VerifyOfferJWS verifies a JWS promotional offer signature with the public
+// key of the in-app purchase key with the given ID and returns its claims.
+func VerifyOfferJWS(pub *ecdsa.PublicKey, keyID, token string) (*OfferClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed jws")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	if header.Alg != "ES256" {
+		return nil, fmt.Errorf("unexpected signing algorithm: %s", header.Alg)
+	}
+
+	if header.Kid != keyID {
+		return nil, fmt.Errorf("unexpected key id: %s", header.Kid)
+	}
+
+	if err := verifyES256(pub, parts); err != nil {
+		return nil, err
+	}
+
+	var c OfferClaims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return nil, err
+	}
+
+	if c.Audience != offerAudience {
+		return nil, fmt.Errorf("unexpected audience: %s", c.Audience)
+	}
+
+	return &c, nil
+}
+
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// offerPayload returns the payload of a promotional offer signature.
+func offerPayload(bundleID, productID, offerID, appAccountToken string, sig *OfferSignature) string {
+	return strings.Join([]string{
+		bundleID,
+		sig.KeyID,
+		productID,
+		offerID,
+		appAccountToken,
+		strings.ToLower(sig.Nonce),
+		strconv.FormatInt(sig.Timestamp, 10),
+	}, offerSeparator)
+}
+
+// newUUID returns a random version 4 UUID.
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := io.ReadFull(rand.Reader, b[:]); err != nil {
+		return "", err
+	}
+
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
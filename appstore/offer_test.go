@@ -0,0 +1,200 @@
+package appstore
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func newTestOfferSigner(t *testing.T) (*OfferSigner, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key := generateECKey(t)
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p8 := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	s, err := NewOfferSigner(p8, "KEY123", "issuer", "com.example.app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s, key
+}
+
+func TestNewOfferSignerMalformedKey(t *testing.T) {
+	if _, err := NewOfferSigner([]byte("key"), "KEY123", "issuer", "com.example.app"); err == nil {
+		t.Error("NewOfferSigner() succeeded, want error")
+	}
+}
+
+func TestOfferSignerSign(t *testing.T) {
+	s, key := newTestOfferSigner(t)
+
+	sig, err := s.Sign("monthly", "offer", "account")
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	if sig.KeyID != "KEY123" || !uuidPattern.MatchString(sig.Nonce) || sig.Timestamp == 0 {
+		t.Errorf("Sign() = %+v", sig)
+	}
+
+	tests := []struct {
+		name            string
+		pub             *ecdsa.PublicKey
+		bundleID        string
+		productID       string
+		offerID         string
+		appAccountToken string
+		sig             func(sig OfferSignature) *OfferSignature
+		wantErr         bool
+	}{
+		{name: "valid"},
+		{name: "upper case nonce", sig: func(sig OfferSignature) *OfferSignature {
+			sig.Nonce = strings.ToUpper(sig.Nonce)
+			return &sig
+		}},
+		{name: "other key", pub: &generateECKey(t).PublicKey, wantErr: true},
+		{name: "other bundle", bundleID: "com.example.other", wantErr: true},
+		{name: "other product", productID: "yearly", wantErr: true},
+		{name: "other offer", offerID: "other", wantErr: true},
+		{name: "other account", appAccountToken: "other", wantErr: true},
+		{name: "other timestamp", sig: func(sig OfferSignature) *OfferSignature {
+			sig.Timestamp++
+			return &sig
+		}, wantErr: true},
+		{name: "malformed signature", sig: func(sig OfferSignature) *OfferSignature {
+			sig.Signature = base64.StdEncoding.EncodeToString([]byte("signature"))
+			return &sig
+		}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub, bundleID, productID, offerID, appAccountToken := &key.PublicKey, "com.example.app", "monthly", "offer", "account"
+			if tt.pub != nil {
+				pub = tt.pub
+			}
+			if tt.bundleID != "" {
+				bundleID = tt.bundleID
+			}
+			if tt.productID != "" {
+				productID = tt.productID
+			}
+			if tt.offerID != "" {
+				offerID = tt.offerID
+			}
+			if tt.appAccountToken != "" {
+				appAccountToken = tt.appAccountToken
+			}
+
+			got := sig
+			if tt.sig != nil {
+				got = tt.sig(*sig)
+			}
+
+			err := VerifyOfferSignature(pub, bundleID, productID, offerID, appAccountToken, got)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyOfferSignature() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOfferSignerSignJWS(t *testing.T) {
+	s, key := newTestOfferSigner(t)
+
+	token, err := s.SignJWS("monthly", "offer", "1000")
+	if err != nil {
+		t.Fatalf("SignJWS() error = %v", err)
+	}
+
+	// resign signs the claims of token again with the given header.
+	resign := func(t *testing.T, header map[string]interface{}, mutate func(c *OfferClaims)) string {
+		var c OfferClaims
+		if err := decodeSegment(strings.Split(token, ".")[1], &c); err != nil {
+			t.Fatal(err)
+		}
+		if mutate != nil {
+			mutate(&c)
+		}
+
+		tok, err := signJWS(key, header, &c)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return tok
+	}
+
+	tests := []struct {
+		name    string
+		pub     *ecdsa.PublicKey
+		keyID   string
+		token   func(t *testing.T) string
+		wantErr bool
+	}{
+		{name: "valid"},
+		{name: "other key", pub: &generateECKey(t).PublicKey, wantErr: true},
+		{name: "other key id", keyID: "OTHER", wantErr: true},
+		{
+			name: "unexpected algorithm",
+			token: func(t *testing.T) string {
+				return resign(t, map[string]interface{}{"alg": "ES384", "kid": "KEY123"}, nil)
+			},
+			wantErr: true,
+		},
+		{
+			name: "unexpected audience",
+			token: func(t *testing.T) string {
+				return resign(t, map[string]interface{}{"alg": "ES256", "kid": "KEY123"}, func(c *OfferClaims) { c.Audience = "appstoreconnect-v1" })
+			},
+			wantErr: true,
+		},
+		{name: "malformed", token: func(t *testing.T) string { return "a.b" }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub, keyID, tok := &key.PublicKey, "KEY123", token
+			if tt.pub != nil {
+				pub = tt.pub
+			}
+			if tt.keyID != "" {
+				keyID = tt.keyID
+			}
+			if tt.token != nil {
+				tok = tt.token(t)
+			}
+
+			c, err := VerifyOfferJWS(pub, keyID, tok)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("VerifyOfferJWS() succeeded, want error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("VerifyOfferJWS() error = %v", err)
+			}
+
+			if c.Audience != offerAudience || c.Issuer != "issuer" || c.BundleID != "com.example.app" ||
+				c.ProductID != "monthly" || c.OfferIdentifier != "offer" || c.TransactionID != "1000" ||
+				!uuidPattern.MatchString(c.Nonce) || c.IssuedAt == 0 {
+				t.Errorf("VerifyOfferJWS() = %+v", c)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package appstore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/brainleap/iap/internal/httpclient"
+)
+
+type options struct {
+	httpclient.Config
+
+	productionURL string
+	sandboxURL    string
+}
+
+// ClientOption configures a client.
+type ClientOption func(*options)
+
+// WithBaseURLs overrides the production and sandbox base URLs, e.g. to point
+// the client at a local fake. The mode of the client selects which one is
+// used.
+func WithBaseURLs(production, sandbox string) ClientOption {
+	return func(o *options) {
+		o.productionURL = production
+		o.sandboxURL = sandbox
+	}
+}
+
+// WithTransport sets the transport requests are sent with.
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return configOption(httpclient.WithTransport(rt))
+}
+
+// WithTimeout sets the timeout of requests.
+func WithTimeout(d time.Duration) ClientOption {
+	return configOption(httpclient.WithTimeout(d))
+}
+
+// WithUserAgent sets the User-Agent header of requests.
+func WithUserAgent(ua string) ClientOption {
+	return configOption(httpclient.WithUserAgent(ua))
+}
+
+// WithProxy sends requests through a proxy. It is ignored when a custom
+// transport is set.
+func WithProxy(proxy *url.URL) ClientOption {
+	return configOption(httpclient.WithProxy(proxy))
+}
+
+// WithTLSConfig sets the TLS configuration of requests. It is ignored when a
+// custom transport is set.
+func WithTLSConfig(cfg *tls.Config) ClientOption {
+	return configOption(httpclient.WithTLSConfig(cfg))
+}
+
+// WithRootCAs sets the root certificates server certificates are verified
+// against. A TLS configuration set with WithTLSConfig is cloned, not
+// modified. It is ignored when a custom transport is set.
+func WithRootCAs(roots *x509.CertPool) ClientOption {
+	return configOption(httpclient.WithRootCAs(roots))
+}
+
+func configOption(opt httpclient.Option) ClientOption {
+	return func(o *options) {
+		opt(&o.Config)
+	}
+}
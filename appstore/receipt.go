@@ -0,0 +1,572 @@
+package appstore
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+// Receipt attribute types.
+const (
+	attrReceiptType                = 0
+	attrBundleID                   = 2
+	attrApplicationVersion         = 3
+	attrOpaqueValue                = 4
+	attrSHA1Hash                   = 5
+	attrReceiptCreationDate        = 12
+	attrInApp                      = 17
+	attrOriginalApplicationVersion = 19
+	attrExpirationDate             = 21
+)
+
+// In-app purchase attribute types.
+const (
+	attrQuantity              = 1701
+	attrProductID             = 1702
+	attrTransactionID         = 1703
+	attrPurchaseDate          = 1704
+	attrOriginalTransactionID = 1705
+	attrOriginalPurchaseDate  = 1706
+	attrExpiresDate           = 1708
+	attrWebOrderLineItemID    = 1711
+	attrCancellationDate      = 1712
+	attrIsTrialPeriod         = 1713
+	attrIsInIntroOfferPeriod  = 1719
+)
+
+var (
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSHA1          = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+)
+
+const (
+	receiptDateFormat = "2006-01-02 15:04:05"
+	pstLocation       = "America/Los_Angeles"
+
+	// maxBERDepth limits the nesting of BER elements; receipts nest far
+	// less deeply.
+	maxBERDepth = 32
+)
+
+// LocalReceipt is an app receipt decoded without calling the App Store.
+//
+// BundleIDData is the DER encoded bundle ID which, together with Opaque and
+// the device identifier, is hashed into SHA1Hash.
+type LocalReceipt struct {
+	*Receipt
+
+	BundleIDData []byte
+	Opaque       []byte
+	SHA1Hash     []byte
+}
+
+// ValidateHash reports whether the receipt was issued for the device with the
+// given identifier, i.e. identifierForVendor on iOS or the GUID on macOS.
+func (r *LocalReceipt) ValidateHash(deviceID []byte) bool {
+	h := sha1.New()
+	h.Write(deviceID)
+	h.Write(r.Opaque)
+	h.Write(r.BundleIDData)
+
+	return bytes.Equal(h.Sum(nil), r.SHA1Hash)
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      contentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type signerInfo struct {
+	Version                   int
+	IssuerAndSerialNumber     issuerAndSerialNumber
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	AuthenticatedAttributes   asn1.RawValue `asn1:"optional,tag:0"`
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+	UnauthenticatedAttributes asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type attribute struct {
+	Type  asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"set"`
+}
+
+type receiptAttribute struct {
+	Type    int
+	Version int
+	Value   []byte
+}
+
+// ParseReceipt decodes a base64 encoded app receipt locally.
+//
+// When root is given, the PKCS#7 signature is verified and the signing
+// certificate must chain to root, which should be the Apple Inc. Root
+// certificate. The chain is validated at the current time; only once the
+// signature checks out, a chain that has expired since is accepted if it was
+// valid at the receipt creation date.
+func ParseReceipt(receipt string, root *x509.Certificate) (*LocalReceipt, error) {
+	data, err := base64.StdEncoding.DecodeString(receipt)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = berToDER(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(data, &ci); err != nil {
+		return nil, err
+	}
+
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, errors.New("receipt is not pkcs7 signed data")
+	}
+
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, err
+	}
+
+	var payload []byte
+	if _, err := asn1.Unmarshal(sd.ContentInfo.Content.Bytes, &payload); err != nil {
+		return nil, err
+	}
+
+	r, err := parsePayload(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if root != nil {
+		if err := sd.verify(payload, root, millis(r.ReceiptCreationDateMS)); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+func (sd *signedData) verify(content []byte, root *x509.Certificate, at time.Time) error {
+	if len(sd.SignerInfos) != 1 {
+		return errors.New("receipt must have exactly one signer")
+	}
+
+	certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil {
+		return err
+	}
+
+	si := &sd.SignerInfos[0]
+
+	var signer *x509.Certificate
+	intermediates := x509.NewCertPool()
+	for _, c := range certs {
+		if signer == nil && si.IssuerAndSerialNumber.matches(c) {
+			signer = c
+			continue
+		}
+
+		intermediates.AddCert(c)
+	}
+
+	if signer == nil {
+		return errors.New("receipt signing certificate not found")
+	}
+
+	if err := si.verifySignature(signer, content); err != nil {
+		return err
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+
+	_, err = signer.Verify(opts)
+	if e, ok := err.(x509.CertificateInvalidError); ok && e.Reason == x509.Expired && !at.IsZero() {
+		opts.CurrentTime = at
+		_, err = signer.Verify(opts)
+	}
+
+	return err
+}
+
+func (is *issuerAndSerialNumber) matches(c *x509.Certificate) bool {
+	return c.SerialNumber.Cmp(is.SerialNumber) == 0 && bytes.Equal(c.RawIssuer, is.Issuer.FullBytes)
+}
+
+func (si *signerInfo) verifySignature(signer *x509.Certificate, content []byte) error {
+	var hash crypto.Hash
+	switch {
+	case si.DigestAlgorithm.Algorithm.Equal(oidSHA1):
+		hash = crypto.SHA1
+	case si.DigestAlgorithm.Algorithm.Equal(oidSHA256):
+		hash = crypto.SHA256
+	default:
+		return fmt.Errorf("unsupported digest algorithm: %s", si.DigestAlgorithm.Algorithm)
+	}
+
+	signed := content
+	if len(si.AuthenticatedAttributes.FullBytes) > 0 {
+		if err := si.checkMessageDigest(hash, content); err != nil {
+			return err
+		}
+
+		// The signature covers the attributes encoded as a SET rather than
+		// with the implicit tag they are transmitted with.
+		signed = append([]byte{0x31}, si.AuthenticatedAttributes.FullBytes[1:]...)
+	}
+
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch pub := signer.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(pub, hash, digest, si.EncryptedDigest)
+	case *ecdsa.PublicKey:
+		var sig struct{ R, S *big.Int }
+		if _, err := asn1.Unmarshal(si.EncryptedDigest, &sig); err != nil {
+			return err
+		}
+
+		if !ecdsa.Verify(pub, digest, sig.R, sig.S) {
+			return errors.New("invalid receipt signature")
+		}
+
+		return nil
+	default:
+		return errors.New("unsupported signing key")
+	}
+}
+
+func (si *signerInfo) checkMessageDigest(hash crypto.Hash, content []byte) error {
+	var attrs []attribute
+	if _, err := asn1.UnmarshalWithParams(si.AuthenticatedAttributes.FullBytes, &attrs, "set,tag:0"); err != nil {
+		return err
+	}
+
+	h := hash.New()
+	h.Write(content)
+	digest := h.Sum(nil)
+
+	for _, a := range attrs {
+		if !a.Type.Equal(oidMessageDigest) {
+			continue
+		}
+
+		var md []byte
+		if _, err := asn1.Unmarshal(a.Value.Bytes, &md); err != nil {
+			return err
+		}
+
+		if !bytes.Equal(md, digest) {
+			return errors.New("receipt content digest mismatch")
+		}
+
+		return nil
+	}
+
+	return errors.New("receipt has no message digest")
+}
+
+func parsePayload(payload []byte) (*LocalReceipt, error) {
+	var attrs []receiptAttribute
+	if _, err := asn1.UnmarshalWithParams(payload, &attrs, "set"); err != nil {
+		return nil, err
+	}
+
+	r := &LocalReceipt{Receipt: &Receipt{}}
+
+	for _, a := range attrs {
+		var err error
+
+		switch a.Type {
+		case attrReceiptType:
+			r.ReceiptType, err = asn1String(a.Value)
+		case attrBundleID:
+			r.BundleIDData = a.Value
+			r.BundleID, err = asn1String(a.Value)
+		case attrApplicationVersion:
+			r.ApplicationVersion, err = asn1String(a.Value)
+		case attrOpaqueValue:
+			r.Opaque = a.Value
+		case attrSHA1Hash:
+			r.SHA1Hash = a.Value
+		case attrReceiptCreationDate:
+			r.ReceiptCreationDate, r.ReceiptCreationDateMS, r.ReceiptCreationDatePST, err = asn1Date(a.Value)
+		case attrOriginalApplicationVersion:
+			r.OriginalApplicationVersion, err = asn1String(a.Value)
+		case attrExpirationDate:
+			r.ExpirationDate, r.ExpirationDateMS, r.ExpirationDatePST, err = asn1Date(a.Value)
+		case attrInApp:
+			var p *InApp
+			p, err = parseInApp(a.Value)
+			if err == nil {
+				r.InApp = append(r.InApp, *p)
+			}
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("receipt attribute %d: %v", a.Type, err)
+		}
+	}
+
+	return r, nil
+}
+
+func parseInApp(data []byte) (*InApp, error) {
+	var attrs []receiptAttribute
+	if _, err := asn1.UnmarshalWithParams(data, &attrs, "set"); err != nil {
+		return nil, err
+	}
+
+	var p InApp
+
+	for _, a := range attrs {
+		var err error
+
+		switch a.Type {
+		case attrQuantity:
+			p.Quantity, err = asn1Int(a.Value)
+		case attrProductID:
+			p.ProductID, err = asn1String(a.Value)
+		case attrTransactionID:
+			p.TransactionID, err = asn1String(a.Value)
+		case attrOriginalTransactionID:
+			p.OriginalTransactionID, err = asn1String(a.Value)
+		case attrPurchaseDate:
+			p.PurchaseDate, p.PurchaseDateMS, p.PurchaseDatePST, err = asn1Date(a.Value)
+		case attrOriginalPurchaseDate:
+			p.OriginalPurchaseDate, p.OriginalPurchaseDateMS, p.OriginalPurchaseDatePST, err = asn1Date(a.Value)
+		case attrExpiresDate:
+			p.ExpiresDate, p.ExpiresDateMS, p.ExpiresDatePST, err = asn1Date(a.Value)
+		case attrWebOrderLineItemID:
+			p.WebOrderLineItemID, err = asn1Int(a.Value)
+		case attrCancellationDate:
+			p.CancellationDate, p.CancellationDateMS, p.CancellationDatePST, err = asn1Date(a.Value)
+		case attrIsTrialPeriod:
+			p.IsTrialPeriod, err = asn1Bool(a.Value)
+		case attrIsInIntroOfferPeriod:
+			p.IsInIntroOfferPeriod, err = asn1Bool(a.Value)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("in-app attribute %d: %v", a.Type, err)
+		}
+	}
+
+	return &p, nil
+}
+
+func asn1String(data []byte) (string, error) {
+	var v asn1.RawValue
+	if _, err := asn1.Unmarshal(data, &v); err != nil {
+		return "", err
+	}
+
+	if v.Class != asn1.ClassUniversal || v.IsCompound {
+		return "", errors.New("value is not a string")
+	}
+
+	return string(v.Bytes), nil
+}
+
+func asn1Int(data []byte) (string, error) {
+	var v int64
+	if _, err := asn1.Unmarshal(data, &v); err != nil {
+		return "", err
+	}
+
+	return strconv.FormatInt(v, 10), nil
+}
+
+func asn1Bool(data []byte) (string, error) {
+	var v int64
+	if _, err := asn1.Unmarshal(data, &v); err != nil {
+		return "", err
+	}
+
+	return strconv.FormatBool(v != 0), nil
+}
+
+// asn1Date converts an RFC 3339 date into the date, milliseconds and PST
+// representations used by verifyReceipt. Empty dates are left empty.
+func asn1Date(data []byte) (date, ms, pst string, err error) {
+	s, err := asn1String(data)
+	if err != nil || s == "" {
+		return "", "", "", err
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	date = t.UTC().Format(receiptDateFormat) + " Etc/GMT"
+	ms = strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+
+	if loc, err := time.LoadLocation(pstLocation); err == nil {
+		pst = t.In(loc).Format(receiptDateFormat) + " " + pstLocation
+	}
+
+	return date, ms, pst, nil
+}
+
+// berToDER converts BER encoded data, as found in some receipts, into DER by
+// replacing indefinite lengths and flattening constructed octet strings.
+func berToDER(data []byte) ([]byte, error) {
+	der, _, err := berElement(data, 0)
+	return der, err
+}
+
+func berElement(data []byte, depth int) (der, rest []byte, err error) {
+	if depth > maxBERDepth {
+		return nil, nil, errors.New("ber elements nested too deeply")
+	}
+
+	if len(data) < 2 {
+		return nil, nil, errors.New("truncated ber element")
+	}
+
+	i := 1
+	if data[0]&0x1f == 0x1f {
+		for i < len(data) && data[i]&0x80 != 0 {
+			i++
+		}
+		i++
+	}
+
+	if i >= len(data) {
+		return nil, nil, errors.New("truncated ber tag")
+	}
+
+	tag := data[:i]
+	constructed := data[0]&0x20 != 0
+
+	l := int(data[i])
+	i++
+
+	var body []byte
+	indefinite := false
+
+	switch {
+	case l == 0x80:
+		if !constructed {
+			return nil, nil, errors.New("indefinite length of primitive ber element")
+		}
+
+		indefinite = true
+		body = data[i:]
+	case l > 0x80:
+		n := l & 0x7f
+		if n > 4 || i+n > len(data) {
+			return nil, nil, errors.New("invalid ber length")
+		}
+
+		l = 0
+		for _, b := range data[i : i+n] {
+			l = l<<8 | int(b)
+		}
+		i += n
+
+		fallthrough
+	default:
+		if l < 0 || i+l > len(data) {
+			return nil, nil, errors.New("truncated ber element")
+		}
+
+		body = data[i : i+l]
+		rest = data[i+l:]
+	}
+
+	if !constructed {
+		return encodeDER(tag, body), rest, nil
+	}
+
+	var content []byte
+	for {
+		if indefinite && len(body) >= 2 && body[0] == 0 && body[1] == 0 {
+			rest = body[2:]
+			break
+		}
+
+		if !indefinite && len(body) == 0 {
+			break
+		}
+
+		child, r, err := berElement(body, depth+1)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Constructed octet strings are flattened into a primitive one.
+		if len(tag) == 1 && tag[0] == 0x24 {
+			var v asn1.RawValue
+			if _, err := asn1.Unmarshal(child, &v); err != nil {
+				return nil, nil, err
+			}
+			child = v.Bytes
+		}
+
+		content = append(content, child...)
+		body = r
+	}
+
+	if len(tag) == 1 && tag[0] == 0x24 {
+		return encodeDER([]byte{0x04}, content), rest, nil
+	}
+
+	return encodeDER(tag, content), rest, nil
+}
+
+func encodeDER(tag, content []byte) []byte {
+	out := append([]byte{}, tag...)
+
+	l := len(content)
+	if l < 0x80 {
+		out = append(out, byte(l))
+	} else {
+		var lb []byte
+		for ; l > 0; l >>= 8 {
+			lb = append([]byte{byte(l)}, lb...)
+		}
+		out = append(out, 0x80|byte(len(lb)))
+		out = append(out, lb...)
+	}
+
+	return append(out, content...)
+}
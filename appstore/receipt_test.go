@@ -0,0 +1,543 @@
+package appstore
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"math/big"
+	"testing"
+	"time"
+)
+
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidRSAEncryption = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+)
+
+type testAttribute struct {
+	Type  asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+type testContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue
+}
+
+type testSignedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      testContentInfo
+	Certificates     asn1.RawValue
+	SignerInfos      []testSignerInfo `asn1:"set"`
+}
+
+type testSignerInfo struct {
+	Version                   int
+	IssuerAndSerialNumber     issuerAndSerialNumber
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	AuthenticatedAttributes   asn1.RawValue `asn1:"optional"`
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+}
+
+// receiptSigner signs receipts with an RSA certificate chain, as Apple does.
+type receiptSigner struct {
+	root         *x509.Certificate
+	intermediate *x509.Certificate
+	leaf         *x509.Certificate
+	key          *rsa.PrivateKey
+}
+
+func generateRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func newReceiptSigner(t *testing.T, leafNotAfter time.Time) *receiptSigner {
+	t.Helper()
+
+	notBefore := time.Now().Add(-72 * time.Hour)
+
+	rootKey := generateRSAKey(t)
+	root := issueCertificate(t, certTemplate{cn: "Test Root CA", ca: true, notBefore: notBefore}, &rootKey.PublicKey, nil, rootKey)
+
+	intermediateKey := generateRSAKey(t)
+	intermediate := issueCertificate(t, certTemplate{cn: "Test WWDR CA", ca: true, notBefore: notBefore}, &intermediateKey.PublicKey, root, rootKey)
+
+	key := generateRSAKey(t)
+	leaf := issueCertificate(t, certTemplate{
+		cn:        "Test Mac App Store Receipt Signing",
+		notBefore: time.Now().Add(-48 * time.Hour),
+		notAfter:  leafNotAfter,
+	}, &key.PublicKey, intermediate, intermediateKey)
+
+	return &receiptSigner{root: root, intermediate: intermediate, leaf: leaf, key: key}
+}
+
+// receiptOptions change how sign builds a receipt.
+type receiptOptions struct {
+	hash             crypto.Hash
+	noAttributes     bool
+	issuer           []byte
+	tamperedDigest   bool
+	signedPayload    []byte
+	otherCertificate *x509.Certificate
+}
+
+func (rs *receiptSigner) sign(t *testing.T, payload []byte, o receiptOptions) []byte {
+	t.Helper()
+
+	if o.hash == 0 {
+		o.hash = crypto.SHA1
+	}
+
+	digestOID := oidSHA1
+	if o.hash == crypto.SHA256 {
+		digestOID = oidSHA256
+	}
+
+	signedPayload := payload
+	if o.signedPayload != nil {
+		signedPayload = o.signedPayload
+	}
+
+	h := o.hash.New()
+	h.Write(signedPayload)
+	contentDigest := h.Sum(nil)
+
+	si := testSignerInfo{
+		Version: 1,
+		IssuerAndSerialNumber: issuerAndSerialNumber{
+			Issuer:       asn1.RawValue{FullBytes: rs.leaf.RawIssuer},
+			SerialNumber: rs.leaf.SerialNumber,
+		},
+		DigestAlgorithm:           pkix.AlgorithmIdentifier{Algorithm: digestOID},
+		DigestEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidRSAEncryption},
+	}
+
+	if o.issuer != nil {
+		si.IssuerAndSerialNumber.Issuer = asn1.RawValue{FullBytes: o.issuer}
+	}
+
+	signed := signedPayload
+	if !o.noAttributes {
+		if o.tamperedDigest {
+			contentDigest[0] ^= 0xff
+		}
+
+		attrs := []testAttribute{
+			{Type: oidContentType, Value: asn1Set(t, oidData)},
+			{Type: oidMessageDigest, Value: asn1Set(t, contentDigest)},
+		}
+
+		set, err := asn1.MarshalWithParams(attrs, "set")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		signed = set
+		si.AuthenticatedAttributes = asn1.RawValue{FullBytes: append([]byte{0xa0}, set[1:]...)}
+	}
+
+	h = o.hash.New()
+	h.Write(signed)
+
+	sig, err := rsa.SignPKCS1v15(rand.Reader, rs.key, o.hash, h.Sum(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	si.EncryptedDigest = sig
+
+	content, err := asn1.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certs := append(append([]byte{}, rs.leaf.Raw...), rs.intermediate.Raw...)
+	if o.otherCertificate != nil {
+		certs = append(o.otherCertificate.Raw, certs...)
+	}
+
+	sd, err := asn1.Marshal(testSignedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{{Algorithm: digestOID}},
+		ContentInfo: testContentInfo{
+			ContentType: oidData,
+			Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: content},
+		},
+		Certificates: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: certs},
+		SignerInfos:  []testSignerInfo{si},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := asn1.Marshal(testContentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: sd},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return der
+}
+
+func asn1Set(t *testing.T, v interface{}) asn1.RawValue {
+	t.Helper()
+
+	b, err := asn1.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: b}
+}
+
+func marshalAttr(t *testing.T, typ int, v interface{}, params string) receiptAttribute {
+	t.Helper()
+
+	b, err := asn1.MarshalWithParams(v, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return receiptAttribute{Type: typ, Version: 1, Value: b}
+}
+
+func marshalSet(t *testing.T, attrs []receiptAttribute) []byte {
+	t.Helper()
+
+	b, err := asn1.MarshalWithParams(attrs, "set")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+// testPayload returns a receipt payload created at the given time with one
+// subscription transaction.
+func testPayload(t *testing.T, created time.Time) []byte {
+	t.Helper()
+
+	inApp := marshalSet(t, []receiptAttribute{
+		marshalAttr(t, attrQuantity, 1, ""),
+		marshalAttr(t, attrProductID, "monthly", "utf8"),
+		marshalAttr(t, attrTransactionID, "1001", "utf8"),
+		marshalAttr(t, attrOriginalTransactionID, "1000", "utf8"),
+		marshalAttr(t, attrPurchaseDate, "2026-01-02T03:04:05Z", "ia5"),
+		marshalAttr(t, attrExpiresDate, "2026-02-02T03:04:05Z", "ia5"),
+		marshalAttr(t, attrCancellationDate, "", "ia5"),
+		marshalAttr(t, attrWebOrderLineItemID, 42, ""),
+		marshalAttr(t, attrIsTrialPeriod, 1, ""),
+	})
+
+	return marshalSet(t, []receiptAttribute{
+		marshalAttr(t, attrBundleID, "com.example.app", "utf8"),
+		marshalAttr(t, attrApplicationVersion, "1.2", "utf8"),
+		{Type: attrOpaqueValue, Version: 1, Value: []byte{1, 2, 3, 4}},
+		{Type: attrSHA1Hash, Version: 1, Value: bytes.Repeat([]byte{0}, sha1.Size)},
+		marshalAttr(t, attrReceiptCreationDate, created.UTC().Format(time.RFC3339), "ia5"),
+		marshalAttr(t, attrOriginalApplicationVersion, "1.0", "utf8"),
+		{Type: attrInApp, Version: 1, Value: inApp},
+	})
+}
+
+// toBER re-encodes DER with indefinite lengths and splits octet strings into
+// constructed ones, the way some receipts are encoded.
+func toBER(t *testing.T, der []byte) []byte {
+	t.Helper()
+
+	var out []byte
+	for len(der) > 0 {
+		var v asn1.RawValue
+		rest, err := asn1.Unmarshal(der, &v)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		header := v.FullBytes[:len(v.FullBytes)-len(v.Bytes)]
+		tag := header[:1]
+
+		switch {
+		case v.IsCompound:
+			out = append(out, tag[0], 0x80)
+			out = append(out, toBER(t, v.Bytes)...)
+			out = append(out, 0, 0)
+		case v.Class == asn1.ClassUniversal && v.Tag == asn1.TagOctetString && len(v.Bytes) > 1:
+			half := len(v.Bytes) / 2
+			out = append(out, 0x24, 0x80)
+			out = append(out, encodeDER([]byte{0x04}, v.Bytes[:half])...)
+			out = append(out, encodeDER([]byte{0x04}, v.Bytes[half:])...)
+			out = append(out, 0, 0)
+		default:
+			out = append(out, v.FullBytes...)
+		}
+
+		der = rest
+	}
+
+	return out
+}
+
+func TestParseReceipt(t *testing.T) {
+	now := time.Now()
+	rs := newReceiptSigner(t, now.Add(24*time.Hour))
+	expired := newReceiptSigner(t, now.Add(-time.Hour))
+	other := newReceiptSigner(t, now.Add(24*time.Hour))
+
+	payload := testPayload(t, now.Add(-2*time.Hour))
+
+	tests := []struct {
+		name    string
+		receipt func(t *testing.T) []byte
+		root    *x509.Certificate
+		wantErr bool
+	}{
+		{
+			name:    "unverified",
+			receipt: func(t *testing.T) []byte { return rs.sign(t, payload, receiptOptions{}) },
+		},
+		{
+			name:    "sha1 with attributes",
+			receipt: func(t *testing.T) []byte { return rs.sign(t, payload, receiptOptions{}) },
+			root:    rs.root,
+		},
+		{
+			name:    "sha1 without attributes",
+			receipt: func(t *testing.T) []byte { return rs.sign(t, payload, receiptOptions{noAttributes: true}) },
+			root:    rs.root,
+		},
+		{
+			name:    "sha256",
+			receipt: func(t *testing.T) []byte { return rs.sign(t, payload, receiptOptions{hash: crypto.SHA256}) },
+			root:    rs.root,
+		},
+		{
+			name:    "ber",
+			receipt: func(t *testing.T) []byte { return toBER(t, rs.sign(t, payload, receiptOptions{})) },
+			root:    rs.root,
+		},
+		{
+			name: "other certificate with the same serial",
+			receipt: func(t *testing.T) []byte {
+				key := generateRSAKey(t)
+				c := selfSignedCertificate(t, "Impostor", rs.leaf.SerialNumber, key)
+				return rs.sign(t, payload, receiptOptions{otherCertificate: c})
+			},
+			root: rs.root,
+		},
+		{
+			name:    "untrusted root",
+			receipt: func(t *testing.T) []byte { return rs.sign(t, payload, receiptOptions{}) },
+			root:    other.root,
+			wantErr: true,
+		},
+		{
+			name:    "issuer mismatch",
+			receipt: func(t *testing.T) []byte { return rs.sign(t, payload, receiptOptions{issuer: other.leaf.RawSubject}) },
+			root:    rs.root,
+			wantErr: true,
+		},
+		{
+			name:    "content digest mismatch",
+			receipt: func(t *testing.T) []byte { return rs.sign(t, payload, receiptOptions{tamperedDigest: true}) },
+			root:    rs.root,
+			wantErr: true,
+		},
+		{
+			name: "tampered payload",
+			receipt: func(t *testing.T) []byte {
+				return rs.sign(t, payload, receiptOptions{noAttributes: true, signedPayload: testPayload(t, now)})
+			},
+			root:    rs.root,
+			wantErr: true,
+		},
+		{
+			name:    "expired after creation",
+			receipt: func(t *testing.T) []byte { return expired.sign(t, payload, receiptOptions{}) },
+			root:    expired.root,
+		},
+		{
+			name:    "expired before creation",
+			receipt: func(t *testing.T) []byte { return expired.sign(t, testPayload(t, now), receiptOptions{}) },
+			root:    expired.root,
+			wantErr: true,
+		},
+		{
+			name: "expired with bad signature",
+			receipt: func(t *testing.T) []byte {
+				return expired.sign(t, payload, receiptOptions{noAttributes: true, signedPayload: testPayload(t, now)})
+			},
+			root:    expired.root,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseReceipt(base64.StdEncoding.EncodeToString(tt.receipt(t)), tt.root)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("ParseReceipt() succeeded, want error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseReceipt() error = %v", err)
+			}
+
+			if r.BundleID != "com.example.app" || r.ApplicationVersion != "1.2" || r.OriginalApplicationVersion != "1.0" {
+				t.Errorf("ParseReceipt() = %+v", r.Receipt)
+			}
+
+			if len(r.InApp) != 1 {
+				t.Fatalf("ParseReceipt() in-app = %+v", r.InApp)
+			}
+
+			in := r.InApp[0]
+			if in.ProductID != "monthly" || in.TransactionID != "1001" || in.OriginalTransactionID != "1000" ||
+				in.Quantity != "1" || in.WebOrderLineItemID != "42" || in.IsTrialPeriod != "true" || in.Canceled() {
+				t.Errorf("ParseReceipt() in-app = %+v", in)
+			}
+
+			if want := time.Date(2026, 2, 2, 3, 4, 5, 0, time.UTC); !in.ExpiresTime().Equal(want) {
+				t.Errorf("ParseReceipt() expires = %v, want %v", in.ExpiresTime(), want)
+			}
+
+			if in.ExpiresDate != "2026-02-02 03:04:05 Etc/GMT" {
+				t.Errorf("ParseReceipt() expires date = %q", in.ExpiresDate)
+			}
+		})
+	}
+}
+
+// selfSignedCertificate issues a self-signed certificate with the given
+// serial number.
+func selfSignedCertificate(t *testing.T, cn string, serial *big.Int, key *rsa.PrivateKey) *x509.Certificate {
+	t.Helper()
+
+	c := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, c, c, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+func TestParseReceiptMalformed(t *testing.T) {
+	nested := bytes.Repeat([]byte{0x30, 0x80}, 100)
+	nested = append(nested, bytes.Repeat([]byte{0, 0}, 100)...)
+
+	tests := []struct {
+		name    string
+		receipt string
+	}{
+		{name: "not base64", receipt: "!"},
+		{name: "empty", receipt: ""},
+		{name: "truncated", receipt: base64.StdEncoding.EncodeToString([]byte{0x30, 0x82, 0x01})},
+		{name: "indefinite primitive", receipt: base64.StdEncoding.EncodeToString([]byte{0x04, 0x80, 0, 0})},
+		{name: "nested too deeply", receipt: base64.StdEncoding.EncodeToString(nested)},
+		{name: "not signed data", receipt: base64.StdEncoding.EncodeToString(mustMarshal(t, testContentInfo{
+			ContentType: oidData,
+			Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: mustMarshal(t, []byte("x"))},
+		}))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseReceipt(tt.receipt, nil); err == nil {
+				t.Error("ParseReceipt() succeeded, want error")
+			}
+		})
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	t.Helper()
+
+	b, err := asn1.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+func TestBERToDER(t *testing.T) {
+	der := mustMarshal(t, struct {
+		A []byte
+		B struct{ C, D int }
+		E string `asn1:"utf8"`
+	}{A: []byte("hello world"), E: "x"})
+
+	tests := []struct {
+		name string
+		in   []byte
+	}{
+		{name: "der", in: der},
+		{name: "indefinite", in: toBER(t, der)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := berToDER(tt.in)
+			if err != nil {
+				t.Fatalf("berToDER() error = %v", err)
+			}
+
+			if !bytes.Equal(got, der) {
+				t.Errorf("berToDER() = %x, want %x", got, der)
+			}
+		})
+	}
+}
+
+func TestLocalReceiptValidateHash(t *testing.T) {
+	deviceID := []byte("device")
+
+	r := &LocalReceipt{Opaque: []byte{1, 2}, BundleIDData: []byte{3, 4}}
+
+	h := sha1.New()
+	h.Write(deviceID)
+	h.Write(r.Opaque)
+	h.Write(r.BundleIDData)
+	r.SHA1Hash = h.Sum(nil)
+
+	if !r.ValidateHash(deviceID) {
+		t.Error("ValidateHash() = false, want true")
+	}
+
+	if r.ValidateHash([]byte("other")) {
+		t.Error("ValidateHash(other) = true, want false")
+	}
+}
@@ -0,0 +1,454 @@
+package appstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	serverProductionBaseURL = "https://api.storekit.itunes.apple.com"
+	serverSandboxBaseURL    = "https://api.storekit-sandbox.itunes.apple.com"
+	tokenAudience           = "appstoreconnect-v1"
+	tokenLifetime           = 30 * time.Minute
+	tokenRenewBefore        = time.Minute
+)
+
+// SubscriptionStatus is the data type for subscription statuses.
+type SubscriptionStatus int
+
+// List of subscription statuses.
+const (
+	SSActive       SubscriptionStatus = 1
+	SSExpired      SubscriptionStatus = 2
+	SSBillingRetry SubscriptionStatus = 3
+	SSGracePeriod  SubscriptionStatus = 4
+	SSRevoked      SubscriptionStatus = 5
+)
+
+// OrderLookupStatus is the data type for order lookup statuses.
+type OrderLookupStatus int
+
+// List of order lookup statuses.
+const (
+	OrderValid   OrderLookupStatus = 0
+	OrderInvalid OrderLookupStatus = 1
+)
+
+// NewServerClient creates a new App Store Server API client authenticating
+// with the given .p8 private key.
+func NewServerClient(mode Mode, p8 []byte, keyID, issuerID, bundleID string, opts ...ClientOption) (*ServerClient, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	key, err := ParsePrivateKey(p8)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := serverProductionBaseURL
+	if o.productionURL != "" {
+		baseURL = o.productionURL
+	}
+
+	if mode == SandboxMode {
+		baseURL = serverSandboxBaseURL
+		if o.sandboxURL != "" {
+			baseURL = o.sandboxURL
+		}
+	}
+
+	return &ServerClient{
+		Client:   o.Client(),
+		BaseURL:  baseURL,
+		Verifier: NewJWSVerifier(nil),
+		tokens: &tokenSource{
+			key:      key,
+			keyID:    keyID,
+			issuerID: issuerID,
+			bundleID: bundleID,
+		},
+	}, nil
+}
+
+// ServerClient provides the App Store Server API.
+//
+// Signed transactions and renewal infos in responses are verified with
+// Verifier and returned decoded.
+type ServerClient struct {
+	Client   *http.Client
+	BaseURL  string
+	Verifier *JWSVerifier
+
+	tokens *tokenSource
+}
+
+// tokenSource signs the bearer tokens of API requests and caches them until
+// shortly before they expire.
+type tokenSource struct {
+	key      *ecdsa.PrivateKey
+	keyID    string
+	issuerID string
+	bundleID string
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func (s *tokenSource) Token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.token != "" && now.Add(tokenRenewBefore).Before(s.expires) {
+		return s.token, nil
+	}
+
+	expires := now.Add(tokenLifetime)
+
+	header := map[string]string{
+		"alg": "ES256",
+		"kid": s.keyID,
+		"typ": "JWT",
+	}
+	claims := map[string]interface{}{
+		"iss": s.issuerID,
+		"iat": now.Unix(),
+		"exp": expires.Unix(),
+		"aud": tokenAudience,
+		"bid": s.bundleID,
+	}
+
+	tok, err := signJWS(s.key, header, claims)
+	if err != nil {
+		return "", err
+	}
+
+	s.token = tok
+	s.expires = expires
+
+	return tok, nil
+}
+
+// HistoryQuery filters a transaction history request. Zero values are
+// omitted.
+type HistoryQuery struct {
+	Revision                     string
+	StartDate                    int64
+	EndDate                      int64
+	ProductIDs                   []string
+	ProductTypes                 []string
+	Sort                         string
+	SubscriptionGroupIdentifiers []string
+	InAppOwnershipType           string
+	Revoked                      *bool
+}
+
+func (q *HistoryQuery) values() url.Values {
+	v := url.Values{}
+	if q == nil {
+		return v
+	}
+
+	if q.Revision != "" {
+		v.Set("revision", q.Revision)
+	}
+	if q.StartDate != 0 {
+		v.Set("startDate", strconv.FormatInt(q.StartDate, 10))
+	}
+	if q.EndDate != 0 {
+		v.Set("endDate", strconv.FormatInt(q.EndDate, 10))
+	}
+	for _, p := range q.ProductIDs {
+		v.Add("productId", p)
+	}
+	for _, p := range q.ProductTypes {
+		v.Add("productType", p)
+	}
+	if q.Sort != "" {
+		v.Set("sort", q.Sort)
+	}
+	for _, g := range q.SubscriptionGroupIdentifiers {
+		v.Add("subscriptionGroupIdentifier", g)
+	}
+	if q.InAppOwnershipType != "" {
+		v.Set("inAppOwnershipType", q.InAppOwnershipType)
+	}
+	if q.Revoked != nil {
+		v.Set("revoked", strconv.FormatBool(*q.Revoked))
+	}
+
+	return v
+}
+
+// HistoryResponse is a page of a customer's transaction history. Pass
+// Revision in the next query to fetch the following page while HasMore is
+// set.
+type HistoryResponse struct {
+	Revision           string             `json:"revision"`
+	HasMore            bool               `json:"hasMore"`
+	BundleID           string             `json:"bundleId"`
+	AppAppleID         int64              `json:"appAppleId"`
+	Environment        Environment        `json:"environment"`
+	SignedTransactions []string           `json:"signedTransactions"`
+	Transactions       []*TransactionInfo `json:"-"`
+}
+
+// StatusResponse contains the statuses of a customer's subscriptions.
+type StatusResponse struct {
+	Environment Environment               `json:"environment"`
+	BundleID    string                    `json:"bundleId"`
+	AppAppleID  int64                     `json:"appAppleId"`
+	Data        []SubscriptionGroupStatus `json:"data"`
+}
+
+// SubscriptionGroupStatus contains the statuses of the subscriptions in a
+// subscription group.
+type SubscriptionGroupStatus struct {
+	SubscriptionGroupIdentifier string            `json:"subscriptionGroupIdentifier"`
+	LastTransactions            []LastTransaction `json:"lastTransactions"`
+}
+
+// LastTransaction is the most recent transaction of a subscription.
+type LastTransaction struct {
+	Status                SubscriptionStatus `json:"status"`
+	OriginalTransactionID string             `json:"originalTransactionId"`
+	SignedTransactionInfo string             `json:"signedTransactionInfo"`
+	SignedRenewalInfo     string             `json:"signedRenewalInfo"`
+	Transaction           *TransactionInfo   `json:"-"`
+	RenewalInfo           *RenewalInfo       `json:"-"`
+}
+
+// OrderLookupResponse contains the transactions of an order.
+type OrderLookupResponse struct {
+	Status             OrderLookupStatus  `json:"status"`
+	SignedTransactions []string           `json:"signedTransactions"`
+	Transactions       []*TransactionInfo `json:"-"`
+}
+
+// RefundHistoryResponse is a page of a customer's refunded transactions.
+// Pass Revision to the next call to fetch the following page while HasMore
+// is set.
+type RefundHistoryResponse struct {
+	Revision           string             `json:"revision"`
+	HasMore            bool               `json:"hasMore"`
+	SignedTransactions []string           `json:"signedTransactions"`
+	Transactions       []*TransactionInfo `json:"-"`
+}
+
+// GetTransactionInfo gets information about a single transaction.
+func (c *ServerClient) GetTransactionInfo(transactionID string) (*TransactionInfo, error) {
+	return c.GetTransactionInfoContext(context.Background(), transactionID)
+}
+
+// GetTransactionInfoContext is like GetTransactionInfo but takes a context.
+func (c *ServerClient) GetTransactionInfoContext(ctx context.Context, transactionID string) (*TransactionInfo, error) {
+	var body struct {
+		SignedTransactionInfo string `json:"signedTransactionInfo"`
+	}
+
+	path := "/inApps/v1/transactions/" + url.PathEscape(transactionID)
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, &body); err != nil {
+		return nil, err
+	}
+
+	return c.Verifier.ParseTransaction(body.SignedTransactionInfo)
+}
+
+// GetTransactionHistory gets a page of a customer's in-app purchase
+// transaction history.
+func (c *ServerClient) GetTransactionHistory(transactionID string, q *HistoryQuery) (*HistoryResponse, error) {
+	return c.GetTransactionHistoryContext(context.Background(), transactionID, q)
+}
+
+// GetTransactionHistoryContext is like GetTransactionHistory but takes a
+// context.
+func (c *ServerClient) GetTransactionHistoryContext(ctx context.Context, transactionID string, q *HistoryQuery) (*HistoryResponse, error) {
+	var r HistoryResponse
+
+	path := "/inApps/v2/history/" + url.PathEscape(transactionID)
+	if err := c.do(ctx, http.MethodGet, path, q.values(), nil, &r); err != nil {
+		return nil, err
+	}
+
+	txs, err := c.parseTransactions(r.SignedTransactions)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Transactions = txs
+	return &r, nil
+}
+
+// GetAllSubscriptionStatuses gets the statuses of all of a customer's
+// subscriptions, optionally filtered by status.
+func (c *ServerClient) GetAllSubscriptionStatuses(transactionID string, statuses ...SubscriptionStatus) (*StatusResponse, error) {
+	return c.GetAllSubscriptionStatusesContext(context.Background(), transactionID, statuses...)
+}
+
+// GetAllSubscriptionStatusesContext is like GetAllSubscriptionStatuses but
+// takes a context.
+func (c *ServerClient) GetAllSubscriptionStatusesContext(ctx context.Context, transactionID string, statuses ...SubscriptionStatus) (*StatusResponse, error) {
+	q := url.Values{}
+	for _, s := range statuses {
+		q.Add("status", strconv.Itoa(int(s)))
+	}
+
+	var r StatusResponse
+
+	path := "/inApps/v1/subscriptions/" + url.PathEscape(transactionID)
+	if err := c.do(ctx, http.MethodGet, path, q, nil, &r); err != nil {
+		return nil, err
+	}
+
+	for i := range r.Data {
+		for j := range r.Data[i].LastTransactions {
+			t := &r.Data[i].LastTransactions[j]
+
+			tx, err := c.Verifier.ParseTransaction(t.SignedTransactionInfo)
+			if err != nil {
+				return nil, err
+			}
+
+			t.Transaction = tx
+
+			if t.SignedRenewalInfo == "" {
+				continue
+			}
+
+			ri, err := c.Verifier.ParseRenewalInfo(t.SignedRenewalInfo)
+			if err != nil {
+				return nil, err
+			}
+
+			t.RenewalInfo = ri
+		}
+	}
+
+	return &r, nil
+}
+
+// LookUpOrderID gets the transactions of an order using the order ID from a
+// customer's purchase receipt email.
+func (c *ServerClient) LookUpOrderID(orderID string) (*OrderLookupResponse, error) {
+	return c.LookUpOrderIDContext(context.Background(), orderID)
+}
+
+// LookUpOrderIDContext is like LookUpOrderID but takes a context.
+func (c *ServerClient) LookUpOrderIDContext(ctx context.Context, orderID string) (*OrderLookupResponse, error) {
+	var r OrderLookupResponse
+
+	path := "/inApps/v1/lookup/" + url.PathEscape(orderID)
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, &r); err != nil {
+		return nil, err
+	}
+
+	txs, err := c.parseTransactions(r.SignedTransactions)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Transactions = txs
+	return &r, nil
+}
+
+// GetRefundHistory gets a page of the refunded transactions of a customer.
+func (c *ServerClient) GetRefundHistory(transactionID, revision string) (*RefundHistoryResponse, error) {
+	return c.GetRefundHistoryContext(context.Background(), transactionID, revision)
+}
+
+// GetRefundHistoryContext is like GetRefundHistory but takes a context.
+func (c *ServerClient) GetRefundHistoryContext(ctx context.Context, transactionID, revision string) (*RefundHistoryResponse, error) {
+	q := url.Values{}
+	if revision != "" {
+		q.Set("revision", revision)
+	}
+
+	var r RefundHistoryResponse
+
+	path := "/inApps/v2/refund/lookup/" + url.PathEscape(transactionID)
+	if err := c.do(ctx, http.MethodGet, path, q, nil, &r); err != nil {
+		return nil, err
+	}
+
+	txs, err := c.parseTransactions(r.SignedTransactions)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Transactions = txs
+	return &r, nil
+}
+
+func (c *ServerClient) parseTransactions(signed []string) ([]*TransactionInfo, error) {
+	txs := make([]*TransactionInfo, len(signed))
+	for i, s := range signed {
+		tx, err := c.Verifier.ParseTransaction(s)
+		if err != nil {
+			return nil, err
+		}
+
+		txs[i] = tx
+	}
+
+	return txs, nil
+}
+
+func (c *ServerClient) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if in != nil {
+		reqBody, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+
+		body = bytes.NewReader(reqBody)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+
+	tok, err := c.tokens.Token()
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+tok)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return newServerError(res)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	decoder := json.NewDecoder(res.Body)
+	return decoder.Decode(out)
+}
@@ -0,0 +1,92 @@
+package appstore
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/brainleap/iap"
+)
+
+// Verifier adapts Client to the iap.Verifier interface. The request token is
+// the base64 encoded receipt and the password is the app's shared secret.
+type Verifier struct {
+	Client   *Client
+	Password string
+}
+
+// NewVerifier creates a new AppStore verifier.
+func NewVerifier(c *Client, password string) *Verifier {
+	return &Verifier{Client: c, Password: password}
+}
+
+// Verify validates the receipt and returns the latest transaction of the
+// requested product in it.
+func (v *Verifier) Verify(r *iap.Request) (*iap.Purchase, error) {
+	return v.VerifyContext(context.Background(), r)
+}
+
+// VerifyContext is like Verify but takes a context.
+func (v *Verifier) VerifyContext(ctx context.Context, r *iap.Request) (*iap.Purchase, error) {
+	res, err := v.Client.VerifyContext(ctx, r.Token, v.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := res.Err(); err != nil {
+		return nil, err
+	}
+
+	return res.purchase(r, time.Now())
+}
+
+func (r *Response) purchase(req *iap.Request, now time.Time) (*iap.Purchase, error) {
+	latest := r.latestTransaction(req.ProductID)
+	if latest == nil {
+		return nil, iap.ErrPurchaseNotFound
+	}
+
+	state := iap.StatePurchased
+	expiry := latest.ExpiresTime()
+	switch {
+	case latest.Upgraded():
+		// The cancellation date of an upgrade is not a refund.
+		state = iap.StateExpired
+	case latest.Canceled():
+		state = iap.StateRefunded
+	case req.Kind == iap.KindSubscription && !expiry.After(now):
+		if grace := r.gracePeriodExpiresTime(latest); grace.After(now) {
+			expiry = grace
+		} else {
+			state = iap.StateExpired
+		}
+	}
+
+	env := iap.EnvironmentProduction
+	if r.Mode == SandboxMode || r.Environment == EnvironmentSandbox || (r.Receipt != nil && r.Receipt.ReceiptType == "ProductionSandbox") {
+		env = iap.EnvironmentSandbox
+	}
+
+	return &iap.Purchase{
+		Store:                 iap.AppStore,
+		Kind:                  req.Kind,
+		ProductID:             latest.ProductID,
+		TransactionID:         latest.TransactionID,
+		OriginalTransactionID: latest.OriginalTransactionID,
+		PurchaseTime:          latest.PurchaseTime(),
+		ExpiryTime:            expiry,
+		State:                 state,
+		Environment:           env,
+		Raw:                   r,
+	}, nil
+}
+
+// millis parses the millisecond timestamps used in receipts.
+func millis(s string) time.Time {
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return iap.Millis(ms)
+}
@@ -0,0 +1,169 @@
+package appstore_test
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/brainleap/iap"
+	"github.com/brainleap/iap/appstore"
+	"github.com/brainleap/iap/iaptest"
+)
+
+func TestVerifier(t *testing.T) {
+	now := time.Now()
+	ms := func(d time.Duration) string {
+		return strconv.FormatInt(now.Add(d).UnixNano()/int64(time.Millisecond), 10)
+	}
+
+	inApp := func(productID, transactionID string, purchased time.Duration, expires string) appstore.InApp {
+		return appstore.InApp{
+			ProductID:             productID,
+			TransactionID:         transactionID,
+			OriginalTransactionID: "1000",
+			PurchaseDateMS:        ms(purchased),
+			ExpiresDateMS:         expires,
+		}
+	}
+
+	store := iaptest.NewAppStore()
+	defer store.Close()
+
+	store.AddReceipt("product", appstore.ProductionMode, &appstore.Response{
+		Receipt: &appstore.Receipt{InApp: []appstore.InApp{inApp("coins_100", "2000", -time.Hour, "")}},
+	})
+	store.AddReceipt("subscription", appstore.ProductionMode, &appstore.Response{
+		LatestReceiptInfo: []appstore.InApp{
+			inApp("monthly", "1000", -48*time.Hour, ms(-24*time.Hour)),
+			inApp("monthly", "1001", -time.Hour, ms(24*time.Hour)),
+		},
+	})
+	store.AddReceipt("expired", appstore.ProductionMode, &appstore.Response{
+		LatestReceiptInfo: []appstore.InApp{inApp("monthly", "1000", -48*time.Hour, ms(-24*time.Hour))},
+	})
+	store.AddReceipt("grace period", appstore.ProductionMode, &appstore.Response{
+		LatestReceiptInfo: []appstore.InApp{inApp("monthly", "1000", -48*time.Hour, ms(-24*time.Hour))},
+		PendingRenewalInfo: []appstore.PendingRenewalInfo{{
+			OriginalTransactionID:    "1000",
+			SubscriptionRetryFlag:    "1",
+			GracePeriodExpiresDateMS: ms(time.Hour),
+		}},
+	})
+	store.AddReceipt("refunded", appstore.ProductionMode, &appstore.Response{
+		Receipt: &appstore.Receipt{InApp: []appstore.InApp{func() appstore.InApp {
+			in := inApp("coins_100", "2000", -time.Hour, "")
+			in.CancellationDateMS = ms(-time.Minute)
+			return in
+		}()}},
+	})
+	store.AddReceipt("sandbox", appstore.SandboxMode, &appstore.Response{
+		Receipt: &appstore.Receipt{InApp: []appstore.InApp{inApp("coins_100", "2000", -time.Hour, "")}},
+	})
+
+	c, err := store.Client(appstore.AutoMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := appstore.NewVerifier(c, "")
+
+	tests := []struct {
+		name              string
+		req               iap.Request
+		wantTransactionID string
+		wantState         iap.State
+		wantEnvironment   iap.Environment
+		wantExpiry        string
+		wantErr           error
+	}{
+		{
+			name:              "product",
+			req:               iap.Request{Kind: iap.KindProduct, ProductID: "coins_100", Token: "product"},
+			wantTransactionID: "2000",
+			wantState:         iap.StatePurchased,
+			wantEnvironment:   iap.EnvironmentProduction,
+		},
+		{
+			name:              "subscription",
+			req:               iap.Request{Kind: iap.KindSubscription, ProductID: "monthly", Token: "subscription"},
+			wantTransactionID: "1001",
+			wantState:         iap.StatePurchased,
+			wantEnvironment:   iap.EnvironmentProduction,
+			wantExpiry:        ms(24 * time.Hour),
+		},
+		{
+			name:              "expired subscription",
+			req:               iap.Request{Kind: iap.KindSubscription, ProductID: "monthly", Token: "expired"},
+			wantTransactionID: "1000",
+			wantState:         iap.StateExpired,
+			wantEnvironment:   iap.EnvironmentProduction,
+			wantExpiry:        ms(-24 * time.Hour),
+		},
+		{
+			name:              "grace period",
+			req:               iap.Request{Kind: iap.KindSubscription, ProductID: "monthly", Token: "grace period"},
+			wantTransactionID: "1000",
+			wantState:         iap.StatePurchased,
+			wantEnvironment:   iap.EnvironmentProduction,
+			wantExpiry:        ms(time.Hour),
+		},
+		{
+			name:              "refunded",
+			req:               iap.Request{Kind: iap.KindProduct, ProductID: "coins_100", Token: "refunded"},
+			wantTransactionID: "2000",
+			wantState:         iap.StateRefunded,
+			wantEnvironment:   iap.EnvironmentProduction,
+		},
+		{
+			name:              "sandbox",
+			req:               iap.Request{Kind: iap.KindProduct, ProductID: "coins_100", Token: "sandbox"},
+			wantTransactionID: "2000",
+			wantState:         iap.StatePurchased,
+			wantEnvironment:   iap.EnvironmentSandbox,
+		},
+		{
+			name:    "other product",
+			req:     iap.Request{Kind: iap.KindProduct, ProductID: "coins_500", Token: "product"},
+			wantErr: iap.ErrPurchaseNotFound,
+		},
+		{
+			name:    "malformed receipt",
+			req:     iap.Request{Kind: iap.KindProduct, ProductID: "coins_100", Token: "other"},
+			wantErr: iap.ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := v.Verify(&tt.req)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+
+			if p.Store != iap.AppStore || p.Kind != tt.req.Kind || p.ProductID != tt.req.ProductID ||
+				p.TransactionID != tt.wantTransactionID || p.OriginalTransactionID != "1000" {
+				t.Errorf("Verify() = %+v", p)
+			}
+
+			if p.State != tt.wantState {
+				t.Errorf("State = %d, want %d", p.State, tt.wantState)
+			}
+
+			if p.Environment != tt.wantEnvironment {
+				t.Errorf("Environment = %d, want %d", p.Environment, tt.wantEnvironment)
+			}
+
+			if tt.wantExpiry != "" && strconv.FormatInt(p.ExpiryTime.UnixNano()/int64(time.Millisecond), 10) != tt.wantExpiry {
+				t.Errorf("ExpiryTime = %v, want %s", p.ExpiryTime, tt.wantExpiry)
+			}
+		})
+	}
+}
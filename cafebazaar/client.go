@@ -18,14 +18,22 @@ const (
 )
 
 // NewClient creates a new Cafebazaar client.
-func NewClient(clientID, clientSecret, redirectUrl string) *Client {
-	c := &Client{}
+func NewClient(clientID, clientSecret, redirectUrl string, opts ...ClientOption) *Client {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	c := &Client{
+		BaseURL: o.baseURL,
+		base:    o.Client(),
+	}
 
 	c.OAuth = &oauth2.Config{
 		ClientID:     clientID,
 		ClientSecret: clientSecret,
 		Scopes:       []string{authScope},
-		RedirectURL: redirectUrl,
+		RedirectURL:  redirectUrl,
 		Endpoint: oauth2.Endpoint{
 			AuthURL:  authorizeURL,
 			TokenURL: tokenURL,
@@ -35,10 +43,41 @@ func NewClient(clientID, clientSecret, redirectUrl string) *Client {
 	return c
 }
 
-// Client provides Cafebazaar in-app billing API.
+// Client provides Cafebazaar in-app billing API. BaseURL defaults to the
+// Cafebazaar payment API.
 type Client struct {
-	OAuth  *oauth2.Config
-	Client *http.Client
+	OAuth   *oauth2.Config
+	Client  *http.Client
+	BaseURL string
+
+	// base is the client configured by the client options, which the
+	// authorized client is built on.
+	base *http.Client
+}
+
+func (c *Client) baseURL() string {
+	if c.BaseURL != "" {
+		return c.BaseURL
+	}
+
+	return paymentBaseURL
+}
+
+// oauthContext makes the OAuth flow use the client configured by the client
+// options.
+func (c *Client) oauthContext(ctx context.Context) context.Context {
+	if c.base == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, oauth2.HTTPClient, c.base)
+}
+
+func (c *Client) authorize(ctx context.Context, tok *oauth2.Token) {
+	c.Client = c.OAuth.Client(ctx, tok)
+	if c.base != nil {
+		c.Client.Timeout = c.base.Timeout
+	}
 }
 
 // AuthCodeURL returns URL to which user must be redirected to be asked for
@@ -49,31 +88,53 @@ func (c *Client) AuthCodeURL() string {
 
 // Setup initializes the client by providing authorization code.
 func (c *Client) Setup(authCode string) (*oauth2.Token, error) {
-	tok, err := c.OAuth.Exchange(context.Background(), authCode)
+	return c.SetupContext(context.Background(), authCode)
+}
+
+// SetupContext is like Setup but takes a context. The context is used for the
+// code exchange and for refreshing the token later on, so it should not be
+// canceled while the client is in use.
+func (c *Client) SetupContext(ctx context.Context, authCode string) (*oauth2.Token, error) {
+	ctx = c.oauthContext(ctx)
+
+	tok, err := c.OAuth.Exchange(ctx, authCode)
 	if err != nil {
 		return nil, err
 	}
 
-	c.Client = c.OAuth.Client(context.Background(), tok)
+	c.authorize(ctx, tok)
 	return tok, nil
 }
 
-func (c *Client) SetupWithToken(tok *oauth2.Token){
-    c.Client = c.OAuth.Client(context.Background(), tok)
+// SetupWithToken initializes the client by providing a previously obtained
+// token.
+func (c *Client) SetupWithToken(tok *oauth2.Token) {
+	c.SetupWithTokenContext(context.Background(), tok)
+}
+
+// SetupWithTokenContext is like SetupWithToken but takes a context used for
+// refreshing the token.
+func (c *Client) SetupWithTokenContext(ctx context.Context, tok *oauth2.Token) {
+	c.authorize(c.oauthContext(ctx), tok)
 }
 
 // ValidateProduct checks the purchase and consumption status of an in-app
 // product.
 func (c *Client) ValidateProduct(pkg, prod, token string) (*Product, error) {
+	return c.ValidateProductContext(context.Background(), pkg, prod, token)
+}
+
+// ValidateProductContext is like ValidateProduct but takes a context.
+func (c *Client) ValidateProductContext(ctx context.Context, pkg, prod, token string) (*Product, error) {
 	url := fmt.Sprintf(
 		"%s/validate/%s/inapp/%s/purchases/%s/",
-		paymentBaseURL,
+		c.baseURL(),
 		url.PathEscape(pkg),
 		url.PathEscape(prod),
 		url.PathEscape(token),
 	)
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -85,7 +146,7 @@ func (c *Client) ValidateProduct(pkg, prod, token string) (*Product, error) {
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed with status: %d", res.StatusCode)
+		return nil, newError(res)
 	}
 
 	var p Product
@@ -101,15 +162,20 @@ func (c *Client) ValidateProduct(pkg, prod, token string) (*Product, error) {
 // ValidateSubscription checks the purchase and consumption status of a
 // subscription.
 func (c *Client) ValidateSubscription(pkg, prod, token string) (*Subscription, error) {
+	return c.ValidateSubscriptionContext(context.Background(), pkg, prod, token)
+}
+
+// ValidateSubscriptionContext is like ValidateSubscription but takes a context.
+func (c *Client) ValidateSubscriptionContext(ctx context.Context, pkg, prod, token string) (*Subscription, error) {
 	url := fmt.Sprintf(
 		"%s/applications/%s/subscriptions/%s/purchases/%s/",
-		paymentBaseURL,
+		c.baseURL(),
 		url.PathEscape(pkg),
 		url.PathEscape(prod),
 		url.PathEscape(token),
 	)
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -121,7 +187,7 @@ func (c *Client) ValidateSubscription(pkg, prod, token string) (*Subscription, e
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed with status: %d", res.StatusCode)
+		return nil, newError(res)
 	}
 
 	var s Subscription
@@ -136,15 +202,20 @@ func (c *Client) ValidateSubscription(pkg, prod, token string) (*Subscription, e
 
 // CancelSubscription cancels a subscription purchase.
 func (c *Client) CancelSubscription(pkg, prod, token string) error {
+	return c.CancelSubscriptionContext(context.Background(), pkg, prod, token)
+}
+
+// CancelSubscriptionContext is like CancelSubscription but takes a context.
+func (c *Client) CancelSubscriptionContext(ctx context.Context, pkg, prod, token string) error {
 	url := fmt.Sprintf(
 		"%s/applications/%s/subscriptions/%s/purchases/%s/cancel/",
-		paymentBaseURL,
+		c.baseURL(),
 		url.PathEscape(pkg),
 		url.PathEscape(prod),
 		url.PathEscape(token),
 	)
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return err
 	}
@@ -156,7 +227,7 @@ func (c *Client) CancelSubscription(pkg, prod, token string) error {
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed with status: %d", res.StatusCode)
+		return newError(res)
 	}
 
 	return nil
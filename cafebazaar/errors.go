@@ -0,0 +1,42 @@
+package cafebazaar
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/brainleap/iap"
+)
+
+// maxErrorBody limits how much of an error response is kept.
+const maxErrorBody = 64 << 10
+
+// APIError is the error body returned by the Cafebazaar API.
+type APIError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// newError creates an *iap.Error from a failed response. The parsed
+// Cafebazaar error is kept in Details.
+func newError(res *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+
+	e := iap.NewHTTPError(iap.Cafebazaar, res.StatusCode, body)
+
+	var apiErr APIError
+	if err := json.Unmarshal(body, &apiErr); err != nil || apiErr.Error == "" {
+		return e
+	}
+
+	e.Details = &apiErr
+	e.Reason = apiErr.Error
+	e.Message = apiErr.ErrorDescription
+
+	if apiErr.Error == "invalid_token" || apiErr.Error == "invalid_grant" {
+		e.Err = iap.ErrAuth
+	}
+
+	return e
+}
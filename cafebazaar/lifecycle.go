@@ -0,0 +1,23 @@
+package cafebazaar
+
+import (
+	"time"
+
+	"github.com/brainleap/iap"
+)
+
+// ClassifySubscription maps a subscription purchase to its lifecycle state at
+// the given time. Cafebazaar only reports whether a subscription is active,
+// canceled or expired.
+func ClassifySubscription(s *Subscription, now time.Time) iap.SubscriptionStatus {
+	validUntil := iap.Millis(s.ValidUntilTimeMillis)
+	if !validUntil.After(now) {
+		return iap.SubscriptionStatus{Lifecycle: iap.LifecycleExpired}
+	}
+
+	if !s.AutoRenewing {
+		return iap.SubscriptionStatus{Lifecycle: iap.LifecycleCanceled, NextChange: validUntil}
+	}
+
+	return iap.SubscriptionStatus{Lifecycle: iap.LifecycleActive, NextChange: validUntil}
+}
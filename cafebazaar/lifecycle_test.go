@@ -0,0 +1,40 @@
+package cafebazaar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brainleap/iap"
+)
+
+func TestClassifySubscription(t *testing.T) {
+	now := time.Now()
+	future := now.Add(24*time.Hour).UnixNano() / int64(time.Millisecond)
+	past := now.Add(-24*time.Hour).UnixNano() / int64(time.Millisecond)
+
+	tests := []struct {
+		name           string
+		s              Subscription
+		wantLifecycle  iap.Lifecycle
+		wantNextChange int64
+	}{
+		{name: "active", s: Subscription{ValidUntilTimeMillis: future, AutoRenewing: true}, wantLifecycle: iap.LifecycleActive, wantNextChange: future},
+		{name: "canceled", s: Subscription{ValidUntilTimeMillis: future}, wantLifecycle: iap.LifecycleCanceled, wantNextChange: future},
+		{name: "expired", s: Subscription{ValidUntilTimeMillis: past, AutoRenewing: true}, wantLifecycle: iap.LifecycleExpired},
+		{name: "no expiry", s: Subscription{}, wantLifecycle: iap.LifecycleExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClassifySubscription(&tt.s, now)
+
+			if got.Lifecycle != tt.wantLifecycle {
+				t.Errorf("Lifecycle = %s, want %s", got.Lifecycle, tt.wantLifecycle)
+			}
+
+			if !got.NextChange.Equal(iap.Millis(tt.wantNextChange)) {
+				t.Errorf("NextChange = %v, want %v", got.NextChange, iap.Millis(tt.wantNextChange))
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package cafebazaar
+
+import (
+	"context"
+	"time"
+
+	"github.com/brainleap/iap"
+)
+
+// Verifier adapts Client to the iap.Verifier interface.
+type Verifier struct {
+	Client      *Client
+	PackageName string
+}
+
+// NewVerifier creates a new Cafebazaar verifier for the given package.
+func NewVerifier(c *Client, pkg string) *Verifier {
+	return &Verifier{Client: c, PackageName: pkg}
+}
+
+// Verify validates an in-app product or subscription purchase.
+func (v *Verifier) Verify(r *iap.Request) (*iap.Purchase, error) {
+	return v.VerifyContext(context.Background(), r)
+}
+
+// VerifyContext is like Verify but takes a context.
+func (v *Verifier) VerifyContext(ctx context.Context, r *iap.Request) (*iap.Purchase, error) {
+	if r.Kind == iap.KindSubscription {
+		s, err := v.Client.ValidateSubscriptionContext(ctx, v.PackageName, r.ProductID, r.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		return subscriptionPurchase(r, s, time.Now()), nil
+	}
+
+	p, err := v.Client.ValidateProductContext(ctx, v.PackageName, r.ProductID, r.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	return productPurchase(r, p), nil
+}
+
+func productPurchase(r *iap.Request, p *Product) *iap.Purchase {
+	var state iap.State
+	switch p.PurchaseState {
+	case PurchaseDone:
+		state = iap.StatePurchased
+	case PurchaseRefunded:
+		state = iap.StateRefunded
+	}
+
+	return &iap.Purchase{
+		Store:         iap.Cafebazaar,
+		Kind:          iap.KindProduct,
+		ProductID:     r.ProductID,
+		TransactionID: r.Token,
+		PurchaseTime:  iap.Millis(p.PurchaseTimeMillis),
+		State:         state,
+		Environment:   iap.EnvironmentProduction,
+		Raw:           p,
+	}
+}
+
+func subscriptionPurchase(r *iap.Request, s *Subscription, now time.Time) *iap.Purchase {
+	state := iap.StatePurchased
+	if !iap.Millis(s.ValidUntilTimeMillis).After(now) {
+		state = iap.StateExpired
+	}
+
+	return &iap.Purchase{
+		Store:         iap.Cafebazaar,
+		Kind:          iap.KindSubscription,
+		ProductID:     r.ProductID,
+		TransactionID: r.Token,
+		PurchaseTime:  iap.Millis(s.InitiationTimeMillis),
+		ExpiryTime:    iap.Millis(s.ValidUntilTimeMillis),
+		State:         state,
+		Environment:   iap.EnvironmentProduction,
+		Raw:           s,
+	}
+}
@@ -0,0 +1,111 @@
+package cafebazaar_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/brainleap/iap"
+	"github.com/brainleap/iap/cafebazaar"
+	"github.com/brainleap/iap/iaptest"
+)
+
+const testPackage = "com.example.app"
+
+func TestVerifier(t *testing.T) {
+	now := time.Now()
+	ms := func(d time.Duration) int64 {
+		return now.Add(d).UnixNano() / int64(time.Millisecond)
+	}
+
+	store := iaptest.NewCafebazaar()
+	defer store.Close()
+
+	store.AddProduct(testPackage, "coins_100", "product", &cafebazaar.Product{PurchaseTimeMillis: ms(-time.Hour)})
+	store.AddProduct(testPackage, "coins_100", "refunded", &cafebazaar.Product{PurchaseState: cafebazaar.PurchaseRefunded})
+	store.AddSubscription(testPackage, "monthly", "subscription", &cafebazaar.Subscription{
+		InitiationTimeMillis: ms(-time.Hour),
+		ValidUntilTimeMillis: ms(24 * time.Hour),
+		AutoRenewing:         true,
+	})
+	store.AddSubscription(testPackage, "monthly", "expired", &cafebazaar.Subscription{ValidUntilTimeMillis: ms(-time.Hour)})
+
+	v := cafebazaar.NewVerifier(store.Client(), testPackage)
+
+	tests := []struct {
+		name         string
+		req          iap.Request
+		wantState    iap.State
+		wantPurchase int64
+		wantExpiry   int64
+		wantErr      error
+	}{
+		{
+			name:         "product",
+			req:          iap.Request{Kind: iap.KindProduct, ProductID: "coins_100", Token: "product"},
+			wantState:    iap.StatePurchased,
+			wantPurchase: ms(-time.Hour),
+		},
+		{
+			name:      "refunded product",
+			req:       iap.Request{Kind: iap.KindProduct, ProductID: "coins_100", Token: "refunded"},
+			wantState: iap.StateRefunded,
+		},
+		{
+			name:         "subscription",
+			req:          iap.Request{Kind: iap.KindSubscription, ProductID: "monthly", Token: "subscription"},
+			wantState:    iap.StatePurchased,
+			wantPurchase: ms(-time.Hour),
+			wantExpiry:   ms(24 * time.Hour),
+		},
+		{
+			name:       "expired subscription",
+			req:        iap.Request{Kind: iap.KindSubscription, ProductID: "monthly", Token: "expired"},
+			wantState:  iap.StateExpired,
+			wantExpiry: ms(-time.Hour),
+		},
+		{
+			name:    "unknown token",
+			req:     iap.Request{Kind: iap.KindProduct, ProductID: "coins_100", Token: "unknown"},
+			wantErr: iap.ErrPurchaseNotFound,
+		},
+		{
+			name:    "other subscription",
+			req:     iap.Request{Kind: iap.KindSubscription, ProductID: "yearly", Token: "subscription"},
+			wantErr: iap.ErrPurchaseNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := v.Verify(&tt.req)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+
+			if p.Store != iap.Cafebazaar || p.Kind != tt.req.Kind || p.ProductID != tt.req.ProductID ||
+				p.TransactionID != tt.req.Token || p.Environment != iap.EnvironmentProduction {
+				t.Errorf("Verify() = %+v", p)
+			}
+
+			if p.State != tt.wantState {
+				t.Errorf("State = %d, want %d", p.State, tt.wantState)
+			}
+
+			if !p.PurchaseTime.Equal(iap.Millis(tt.wantPurchase)) {
+				t.Errorf("PurchaseTime = %v, want %v", p.PurchaseTime, iap.Millis(tt.wantPurchase))
+			}
+
+			if !p.ExpiryTime.Equal(iap.Millis(tt.wantExpiry)) {
+				t.Errorf("ExpiryTime = %v, want %v", p.ExpiryTime, iap.Millis(tt.wantExpiry))
+			}
+		})
+	}
+}
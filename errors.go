@@ -0,0 +1,93 @@
+package iap
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Sentinel errors classifying store errors. Use errors.Is to check for them.
+var (
+	ErrPurchaseNotFound  = errors.New("purchase not found")
+	ErrInvalidToken      = errors.New("invalid purchase token")
+	ErrAuth              = errors.New("authentication failed")
+	ErrRateLimited       = errors.New("rate limited")
+	ErrUnavailable       = errors.New("store unavailable")
+	ErrSandboxReceipt    = errors.New("receipt is from the sandbox environment")
+	ErrProductionReceipt = errors.New("receipt is from the production environment")
+)
+
+// Error is an error reported by a store.
+//
+// StatusCode is the HTTP status of the response, if any. Code and Reason are
+// the store's own error code and reason, e.g. the verifyReceipt status or the
+// Google API error reason. Details holds the parsed error body, e.g.
+// *playstore.APIError. Err is the sentinel error classifying the error, if
+// any.
+type Error struct {
+	Store      Store
+	StatusCode int
+	Code       int
+	Reason     string
+	Message    string
+	Retryable  bool
+	Body       []byte
+	Details    interface{}
+	Err        error
+}
+
+// NewHTTPError creates an error for a failed HTTP response and classifies it
+// by its status.
+func NewHTTPError(store Store, statusCode int, body []byte) *Error {
+	e := &Error{
+		Store:      store,
+		StatusCode: statusCode,
+		Body:       body,
+	}
+
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		e.Err = ErrAuth
+	case statusCode == http.StatusNotFound:
+		e.Err = ErrPurchaseNotFound
+	case statusCode == http.StatusGone:
+		e.Err = ErrInvalidToken
+	case statusCode == http.StatusTooManyRequests:
+		e.Err = ErrRateLimited
+		e.Retryable = true
+	case statusCode >= http.StatusInternalServerError:
+		e.Err = ErrUnavailable
+		e.Retryable = true
+	}
+
+	return e
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if msg == "" && e.Err != nil {
+		msg = e.Err.Error()
+	}
+
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("%s: %s", e.Store, msg)
+	}
+
+	if msg == "" {
+		return fmt.Sprintf("%s: failed with status: %d", e.Store, e.StatusCode)
+	}
+
+	return fmt.Sprintf("%s: failed with status: %d: %s", e.Store, e.StatusCode, msg)
+}
+
+// Unwrap returns the sentinel error classifying the error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryable reports whether err is a store error that may succeed when
+// retried.
+func IsRetryable(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Retryable
+}
@@ -0,0 +1,118 @@
+// Package iap provides a store-agnostic view over the in-app billing clients
+// in the appstore, playstore and cafebazaar packages.
+package iap
+
+import (
+	"context"
+	"time"
+)
+
+// Store is the data type for stores.
+type Store string
+
+// List of stores.
+const (
+	AppStore   Store = "appstore"
+	PlayStore  Store = "playstore"
+	Cafebazaar Store = "cafebazaar"
+)
+
+// Kind is the data type for purchase kinds.
+type Kind int
+
+// List of purchase kinds.
+const (
+	KindProduct      Kind = 0
+	KindSubscription Kind = 1
+)
+
+// State is the data type for normalized purchase states.
+type State int
+
+// List of purchase states.
+const (
+	StateUnknown   State = 0
+	StatePurchased State = 1
+	StatePending   State = 2
+	StateCanceled  State = 3
+	StateRefunded  State = 4
+	StateExpired   State = 5
+)
+
+// Environment is the data type for purchase environments.
+type Environment int
+
+// List of purchase environments.
+const (
+	EnvironmentUnknown    Environment = 0
+	EnvironmentProduction Environment = 1
+	EnvironmentSandbox    Environment = 2
+)
+
+// Request identifies a purchase to be verified.
+//
+// Token is the store specific proof of purchase: the purchase token for
+// PlayStore and Cafebazaar, and the base64 encoded receipt for AppStore.
+// OriginalTransactionID identifies an AppStore subscription, as reported by
+// Purchase; it is needed to extend AppStore subscriptions and ignored by the
+// other stores.
+type Request struct {
+	Kind                  Kind
+	ProductID             string
+	Token                 string
+	OriginalTransactionID string
+}
+
+// Purchase is the normalized status of a purchase. OriginalTransactionID is
+// only set for AppStore purchases.
+type Purchase struct {
+	Store                 Store
+	Kind                  Kind
+	ProductID             string
+	TransactionID         string
+	OriginalTransactionID string
+	PurchaseTime          time.Time
+	ExpiryTime            time.Time
+	State                 State
+	Environment           Environment
+
+	// Raw is the store specific response the purchase was built from, e.g.
+	// *playstore.Product or *appstore.Response.
+	Raw interface{}
+}
+
+// Entitled reports whether the purchase grants access at the given time.
+func (p *Purchase) Entitled(now time.Time) bool {
+	if p.State != StatePurchased {
+		return false
+	}
+
+	if p.Kind == KindSubscription && !p.ExpiryTime.IsZero() {
+		return now.Before(p.ExpiryTime)
+	}
+
+	return true
+}
+
+// Verifier validates purchases against a store.
+type Verifier interface {
+	Verify(r *Request) (*Purchase, error)
+	VerifyContext(ctx context.Context, r *Request) (*Purchase, error)
+}
+
+// Extender extends subscriptions at no cost to the user, e.g. to compensate
+// for a service outage. Extend returns the new expiry time.
+type Extender interface {
+	Extend(r *Request, days int) (time.Time, error)
+	ExtendContext(ctx context.Context, r *Request, days int) (time.Time, error)
+}
+
+// Millis converts milliseconds since epoch to time. Zero is mapped to the zero
+// time.
+func Millis(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
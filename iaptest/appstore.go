@@ -0,0 +1,302 @@
+package iaptest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brainleap/iap/appstore"
+)
+
+const (
+	appProductionPath = "/production"
+	appSandboxPath    = "/sandbox"
+	appDateLayout     = "2006-01-02 15:04:05 Etc/GMT"
+)
+
+// AppStore is a fake of the App Store verifyReceipt endpoint and of the App
+// Store Server API.
+//
+// Receipts and transactions are served from the environment they were added
+// for. verifyReceipt answers receipts of the other environment with status
+// 21007 or 21008, as the App Store does. If Password is set, receipts must be
+// verified with it.
+//
+// Signed data is signed with a certificate chain issued by the fake's own
+// root, see RootCA.
+//
+// Mass renewal date extensions are applied at once, but their status is
+// reported as incomplete for the first MassExtendPolls status requests.
+type AppStore struct {
+	*Server
+
+	Password        string
+	BundleID        string
+	MassExtendPolls int
+
+	signer *signer
+
+	mu           sync.Mutex
+	receipts     map[string]*appReceipt
+	transactions []*appTransaction
+	orders       map[string][]string
+	consumption  map[string]*appstore.ConsumptionRequest
+	extensions   map[string]*appExtension
+	history      []*appNotification
+}
+
+type appReceipt struct {
+	mode appstore.Mode
+	res  appstore.Response
+}
+
+type appNotification struct {
+	n    appstore.Notification
+	item appstore.NotificationHistoryItem
+}
+
+type appExtension struct {
+	status  appstore.MassExtendRenewalDateStatus
+	pending int
+}
+
+type appTransaction struct {
+	tx *appstore.TransactionInfo
+	ri *appstore.RenewalInfo
+}
+
+// NewAppStore starts a new fake App Store. The caller should call Close when
+// finished, to shut it down.
+func NewAppStore() *AppStore {
+	s := &AppStore{
+		BundleID:    "com.example.app",
+		signer:      newSigner(),
+		receipts:    make(map[string]*appReceipt),
+		orders:      make(map[string][]string),
+		consumption: make(map[string]*appstore.ConsumptionRequest),
+		extensions:  make(map[string]*appExtension),
+	}
+	s.Server = newServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+// ProductionURL returns the production base URL of the fake.
+func (s *AppStore) ProductionURL() string {
+	return s.URL + appProductionPath
+}
+
+// SandboxURL returns the sandbox base URL of the fake.
+func (s *AppStore) SandboxURL() string {
+	return s.URL + appSandboxPath
+}
+
+// Client creates an AppStore client using the fake. The client does not wait
+// between retries.
+func (s *AppStore) Client(mode appstore.Mode, opts ...appstore.ClientOption) (*appstore.Client, error) {
+	opts = append([]appstore.ClientOption{appstore.WithBaseURLs(s.ProductionURL(), s.SandboxURL())}, opts...)
+
+	c, err := appstore.NewClient(mode, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	c.RetryBackoff = time.Millisecond
+	return c, nil
+}
+
+// AddReceipt adds a receipt answered with res when verified in the given
+// mode.
+func (s *AppStore) AddReceipt(data string, mode appstore.Mode, res *appstore.Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.receipts[data] = &appReceipt{mode: mode, res: *res}
+}
+
+// ExpireSubscription ends the subscription with the given original
+// transaction ID now and turns its auto-renewal off, both in receipts and in
+// the transactions of the App Store Server API.
+func (s *AppStore) ExpireSubscription(originalTransactionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	s.updateInApps(func(in *appstore.InApp) {
+		if in.OriginalTransactionID == originalTransactionID && in.ExpiresDateMS != "" {
+			setDate(now, &in.ExpiresDate, &in.ExpiresDateMS)
+		}
+	})
+
+	for _, r := range s.receipts {
+		for i := range r.res.PendingRenewalInfo {
+			ri := &r.res.PendingRenewalInfo[i]
+			if ri.OriginalTransactionID == originalTransactionID {
+				ri.SubscriptionAutoRenewStatus = "0"
+				ri.SubscriptionExpirationIntent = "1"
+			}
+		}
+	}
+
+	for _, t := range s.transactions {
+		if t.tx.OriginalTransactionID != originalTransactionID {
+			continue
+		}
+
+		if t.tx.ExpiresDate > millis(now) {
+			t.tx.ExpiresDate = millis(now)
+		}
+
+		if t.ri != nil {
+			t.ri.AutoRenewStatus = 0
+			t.ri.ExpirationIntent = 1
+		}
+	}
+}
+
+// Refund refunds the transaction with the given ID now, both in receipts and
+// in the transactions of the App Store Server API.
+func (s *AppStore) Refund(transactionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	s.updateInApps(func(in *appstore.InApp) {
+		if in.TransactionID == transactionID {
+			setDate(now, &in.CancellationDate, &in.CancellationDateMS)
+			in.CancellationReason = "0"
+		}
+	})
+
+	reason := 0
+	for _, t := range s.transactions {
+		if t.tx.TransactionID == transactionID {
+			t.tx.RevocationDate = millis(now)
+			t.tx.RevocationReason = &reason
+		}
+	}
+}
+
+func (s *AppStore) updateInApps(f func(in *appstore.InApp)) {
+	for _, r := range s.receipts {
+		if r.res.Receipt != nil {
+			for i := range r.res.Receipt.InApp {
+				f(&r.res.Receipt.InApp[i])
+			}
+		}
+
+		for i := range r.res.LatestReceiptInfo {
+			f(&r.res.LatestReceiptInfo[i])
+		}
+	}
+}
+
+func setDate(t time.Time, date, ms *string) {
+	*date = t.UTC().Format(appDateLayout)
+	*ms = formatInt(millis(t))
+}
+
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func (s *AppStore) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	mode := appstore.ProductionMode
+	path := r.URL.Path
+
+	switch {
+	case strings.HasPrefix(path, appProductionPath+"/"):
+		path = strings.TrimPrefix(path, appProductionPath)
+	case strings.HasPrefix(path, appSandboxPath+"/"):
+		mode = appstore.SandboxMode
+		path = strings.TrimPrefix(path, appSandboxPath)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if path == "/verifyReceipt" {
+		s.serveVerifyReceipt(w, r, mode)
+		return
+	}
+
+	s.serveServerAPI(w, r, mode)
+}
+
+func (s *AppStore) serveVerifyReceipt(w http.ResponseWriter, r *http.Request, mode appstore.Mode) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		ReceiptData            string `json:"receipt-data"`
+		Password               string `json:"password"`
+		ExcludeOldTransactions bool   `json:"exclude-old-transactions"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusOK, &appstore.Response{Status: 21000})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.receipts[body.ReceiptData]
+	switch {
+	case !ok:
+		writeJSON(w, http.StatusOK, &appstore.Response{Status: 21002})
+	case s.Password != "" && body.Password != s.Password:
+		writeJSON(w, http.StatusOK, &appstore.Response{Status: 21004})
+	case rec.mode == appstore.SandboxMode && mode != appstore.SandboxMode:
+		writeJSON(w, http.StatusOK, &appstore.Response{Status: 21007})
+	case rec.mode != appstore.SandboxMode && mode == appstore.SandboxMode:
+		writeJSON(w, http.StatusOK, &appstore.Response{Status: 21008})
+	default:
+		res := rec.res
+		if res.Environment == "" {
+			res.Environment = appstore.EnvironmentProduction
+			if rec.mode == appstore.SandboxMode {
+				res.Environment = appstore.EnvironmentSandbox
+			}
+		}
+
+		if body.ExcludeOldTransactions {
+			res.LatestReceiptInfo = latestInApps(res.LatestReceiptInfo)
+		}
+
+		writeJSON(w, http.StatusOK, &res)
+	}
+}
+
+// latestInApps keeps the most recent transaction of each original
+// transaction.
+func latestInApps(ins []appstore.InApp) []appstore.InApp {
+	latest := make(map[string]int)
+	var out []appstore.InApp
+
+	for _, in := range ins {
+		i, ok := latest[in.OriginalTransactionID]
+		if !ok {
+			latest[in.OriginalTransactionID] = len(out)
+			out = append(out, in)
+			continue
+		}
+
+		if parseMillis(in.PurchaseDateMS) > parseMillis(out[i].PurchaseDateMS) {
+			out[i] = in
+		}
+	}
+
+	return out
+}
+
+func parseMillis(s string) int64 {
+	ms, _ := strconv.ParseInt(s, 10, 64)
+	return ms
+}
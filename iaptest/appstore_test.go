@@ -0,0 +1,280 @@
+package iaptest_test
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/brainleap/iap"
+	"github.com/brainleap/iap/appstore"
+	"github.com/brainleap/iap/iaptest"
+)
+
+func subscriptionInApp(transactionID string, expires time.Time) appstore.InApp {
+	return appstore.InApp{
+		ProductID:             "monthly",
+		TransactionID:         transactionID,
+		OriginalTransactionID: "1000",
+		ExpiresDateMS:         strconv.FormatInt(expires.UnixNano()/int64(time.Millisecond), 10),
+	}
+}
+
+func TestAppStoreVerifyReceipt(t *testing.T) {
+	tests := []struct {
+		name            string
+		mode            appstore.Mode
+		receipt         string
+		password        string
+		wantStatus      int
+		wantMode        appstore.Mode
+		wantEnvironment appstore.Environment
+	}{
+		{name: "production", mode: appstore.ProductionMode, receipt: "production", wantEnvironment: appstore.EnvironmentProduction},
+		{name: "sandbox", mode: appstore.SandboxMode, receipt: "sandbox", wantMode: appstore.SandboxMode, wantEnvironment: appstore.EnvironmentSandbox},
+		{name: "auto production", mode: appstore.AutoMode, receipt: "production", wantEnvironment: appstore.EnvironmentProduction},
+		{name: "auto sandbox", mode: appstore.AutoMode, receipt: "sandbox", wantMode: appstore.SandboxMode, wantEnvironment: appstore.EnvironmentSandbox},
+		{name: "sandbox receipt in production", mode: appstore.ProductionMode, receipt: "sandbox", wantStatus: 21007},
+		{name: "production receipt in sandbox", mode: appstore.SandboxMode, receipt: "production", wantStatus: 21008, wantMode: appstore.SandboxMode},
+		{name: "unknown receipt", mode: appstore.ProductionMode, receipt: "other", wantStatus: 21002},
+		{name: "wrong password", mode: appstore.ProductionMode, receipt: "production", password: "wrong", wantStatus: 21004},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := iaptest.NewAppStore()
+			defer store.Close()
+
+			store.Password = "secret"
+			store.AddReceipt("production", appstore.ProductionMode, &appstore.Response{})
+			store.AddReceipt("sandbox", appstore.SandboxMode, &appstore.Response{})
+
+			c, err := store.Client(tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			password := tt.password
+			if password == "" {
+				password = store.Password
+			}
+
+			res, err := c.Verify(tt.receipt, password)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+
+			if res.Status != tt.wantStatus || res.Mode != tt.wantMode || res.Environment != tt.wantEnvironment {
+				t.Errorf("Verify() = status %d, mode %d, environment %q, want %d, %d, %q",
+					res.Status, res.Mode, res.Environment, tt.wantStatus, tt.wantMode, tt.wantEnvironment)
+			}
+		})
+	}
+}
+
+func TestAppStoreVerifyReceiptExcludesOldTransactions(t *testing.T) {
+	store := iaptest.NewAppStore()
+	defer store.Close()
+
+	now := time.Now()
+	first := subscriptionInApp("1000", now.Add(-24*time.Hour))
+	first.PurchaseDateMS = strconv.FormatInt(now.Add(-48*time.Hour).UnixNano()/int64(time.Millisecond), 10)
+	renewal := subscriptionInApp("1001", now.Add(24*time.Hour))
+	renewal.PurchaseDateMS = strconv.FormatInt(now.Add(-time.Hour).UnixNano()/int64(time.Millisecond), 10)
+
+	store.AddReceipt("receipt", appstore.ProductionMode, &appstore.Response{
+		LatestReceiptInfo: []appstore.InApp{first, renewal},
+	})
+
+	c, err := store.Client(appstore.ProductionMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := c.Verify("receipt", "")
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	if len(res.LatestReceiptInfo) != 1 || res.LatestReceiptInfo[0].TransactionID != "1001" {
+		t.Errorf("LatestReceiptInfo = %+v, want the renewal only", res.LatestReceiptInfo)
+	}
+}
+
+func TestAppStoreMutations(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(store *iaptest.AppStore)
+		check  func(t *testing.T, in appstore.InApp, tx *appstore.TransactionInfo)
+	}{
+		{
+			name:   "expire",
+			mutate: func(store *iaptest.AppStore) { store.ExpireSubscription("1000") },
+			check: func(t *testing.T, in appstore.InApp, tx *appstore.TransactionInfo) {
+				now := time.Now().UnixNano() / int64(time.Millisecond)
+				if ms, _ := strconv.ParseInt(in.ExpiresDateMS, 10, 64); ms > now {
+					t.Errorf("receipt expires at %d, want before %d", ms, now)
+				}
+				if tx.ExpiresDate > now {
+					t.Errorf("transaction expires at %d, want before %d", tx.ExpiresDate, now)
+				}
+			},
+		},
+		{
+			name:   "refund",
+			mutate: func(store *iaptest.AppStore) { store.Refund("1000") },
+			check: func(t *testing.T, in appstore.InApp, tx *appstore.TransactionInfo) {
+				if in.CancellationDateMS == "" || in.CancellationReason != "0" {
+					t.Errorf("receipt = %+v, want canceled", in)
+				}
+				if tx.RevocationDate == 0 || tx.RevocationReason == nil {
+					t.Errorf("transaction = %+v, want revoked", tx)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := iaptest.NewAppStore()
+			defer store.Close()
+
+			expires := time.Now().Add(24 * time.Hour)
+			store.AddReceipt("receipt", appstore.ProductionMode, &appstore.Response{
+				LatestReceiptInfo: []appstore.InApp{subscriptionInApp("1000", expires)},
+			})
+			store.AddTransaction(&appstore.TransactionInfo{
+				TransactionID:         "1000",
+				OriginalTransactionID: "1000",
+				ProductID:             "monthly",
+				ExpiresDate:           expires.UnixNano() / int64(time.Millisecond),
+			}, &appstore.RenewalInfo{OriginalTransactionID: "1000", AutoRenewStatus: 1})
+
+			tt.mutate(store)
+
+			c, err := store.Client(appstore.ProductionMode)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := c.Verify("receipt", "")
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+
+			sc, err := store.ServerClient(appstore.ProductionMode)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tx, err := sc.GetTransactionInfo("1000")
+			if err != nil {
+				t.Fatalf("GetTransactionInfo() error = %v", err)
+			}
+
+			tt.check(t, res.LatestReceiptInfo[0], tx)
+		})
+	}
+}
+
+func TestAppStoreFailNext(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   int
+		statusCode int
+		wantErr    error
+	}{
+		{name: "recovers", failures: 2, statusCode: http.StatusServiceUnavailable},
+		{name: "exhausts retries", failures: 3, statusCode: http.StatusServiceUnavailable, wantErr: iap.ErrUnavailable},
+		{name: "not retried", failures: 1, statusCode: http.StatusUnauthorized, wantErr: iap.ErrAuth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := iaptest.NewAppStore()
+			defer store.Close()
+
+			store.AddReceipt("receipt", appstore.ProductionMode, &appstore.Response{})
+			store.FailNext(tt.failures, tt.statusCode, "")
+
+			c, err := store.Client(appstore.ProductionMode)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = c.Verify("receipt", "")
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAppStoreServerAPI(t *testing.T) {
+	store := iaptest.NewAppStore()
+	defer store.Close()
+
+	store.AddTransaction(&appstore.TransactionInfo{TransactionID: "1000", OriginalTransactionID: "1000", ProductID: "coins_100"}, nil)
+	store.AddTransaction(&appstore.TransactionInfo{
+		TransactionID:         "2000",
+		OriginalTransactionID: "2000",
+		ProductID:             "coins_100",
+		Environment:           appstore.EnvironmentSandbox,
+	}, nil)
+
+	tests := []struct {
+		name          string
+		mode          appstore.Mode
+		transactionID string
+		wantErr       error
+	}{
+		{name: "production", mode: appstore.ProductionMode, transactionID: "1000"},
+		{name: "sandbox", mode: appstore.SandboxMode, transactionID: "2000"},
+		{name: "other environment", mode: appstore.ProductionMode, transactionID: "2000", wantErr: iap.ErrPurchaseNotFound},
+		{name: "unknown", mode: appstore.SandboxMode, transactionID: "3000", wantErr: iap.ErrPurchaseNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := store.ServerClient(tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tx, err := c.GetTransactionInfo(tt.transactionID)
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Fatalf("GetTransactionInfo() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err == nil && tx.TransactionID != tt.transactionID {
+				t.Errorf("GetTransactionInfo() = %+v", tx)
+			}
+		})
+	}
+}
+
+func TestAppStoreSign(t *testing.T) {
+	store := iaptest.NewAppStore()
+	defer store.Close()
+
+	token, err := store.Sign(&appstore.TransactionInfo{TransactionID: "1000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := store.Verifier().ParseTransaction(token)
+	if err != nil {
+		t.Fatalf("ParseTransaction() error = %v", err)
+	}
+	if tx.TransactionID != "1000" {
+		t.Errorf("ParseTransaction() = %+v", tx)
+	}
+
+	other := iaptest.NewAppStore()
+	defer other.Close()
+
+	if _, err := other.Verifier().ParseTransaction(token); err == nil {
+		t.Error("ParseTransaction() with other root succeeded, want error")
+	}
+}
@@ -0,0 +1,853 @@
+package iaptest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brainleap/iap/appstore"
+)
+
+const apiKeyID = "IAPTEST"
+
+// Marker extensions the App Store sets on the certificates signing its data.
+var (
+	oidLeafMarker         = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 6, 11, 1}
+	oidIntermediateMarker = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 6, 2, 1}
+)
+
+// signer signs App Store data with a generated certificate chain and holds
+// the API key clients authenticate with.
+type signer struct {
+	root   *x509.Certificate
+	x5c    []string
+	key    *ecdsa.PrivateKey
+	apiKey *ecdsa.PrivateKey
+}
+
+func newSigner() *signer {
+	rootKey := generateKey()
+	root := newCertificate("iaptest Root CA", nil, rootKey, nil, &rootKey.PublicKey)
+
+	intermediateKey := generateKey()
+	intermediate := newCertificate("iaptest Intermediate CA", oidIntermediateMarker, rootKey, root, &intermediateKey.PublicKey)
+
+	key := generateKey()
+	leaf := newCertificate("iaptest Signing", oidLeafMarker, intermediateKey, intermediate, &key.PublicKey)
+
+	return &signer{
+		root: root,
+		x5c: []string{
+			base64.StdEncoding.EncodeToString(leaf.Raw),
+			base64.StdEncoding.EncodeToString(intermediate.Raw),
+			base64.StdEncoding.EncodeToString(root.Raw),
+		},
+		key:    key,
+		apiKey: generateKey(),
+	}
+}
+
+func generateKey() *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	return key
+}
+
+// newCertificate issues a CA certificate carrying the given marker extension,
+// or a leaf certificate for the leaf marker. A nil parent issues a self-signed
+// root.
+func newCertificate(cn string, marker asn1.ObjectIdentifier, parentKey *ecdsa.PrivateKey, parent *x509.Certificate, pub *ecdsa.PublicKey) *x509.Certificate {
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		panic(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	if marker.Equal(oidLeafMarker) {
+		tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+		tmpl.IsCA = false
+	}
+
+	if marker != nil {
+		tmpl.ExtraExtensions = []pkix.Extension{{
+			Id:    marker,
+			Value: []byte{0x05, 0x00},
+		}}
+	}
+
+	if parent == nil {
+		parent = tmpl
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, pub, parentKey)
+	if err != nil {
+		panic(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(err)
+	}
+
+	return cert
+}
+
+func (sg *signer) sign(v interface{}) (string, error) {
+	header := map[string]interface{}{
+		"alg": "ES256",
+		"x5c": sg.x5c,
+	}
+
+	return signES256(sg.key, header, v)
+}
+
+func signES256(key *ecdsa.PrivateKey, header, payload interface{}) (string, error) {
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+
+	p, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(p)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	sig := make([]byte, 64)
+	rb, sb := r.Bytes(), s.Bytes()
+	copy(sig[32-len(rb):32], rb)
+	copy(sig[64-len(sb):], sb)
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// verifyToken verifies the bearer token of an App Store Server API request.
+func (sg *signer) verifyToken(tok string) error {
+	parts := strings.Split(tok, ".")
+	if len(parts) != 3 {
+		return errors.New("malformed token")
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(sig) != 64 {
+		return errors.New("malformed token signature")
+	}
+
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if !ecdsa.Verify(&sg.apiKey.PublicKey, digest[:], r, s) {
+		return errors.New("invalid token signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return err
+	}
+
+	var claims struct {
+		Exp int64  `json:"exp"`
+		Aud string `json:"aud"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return err
+	}
+
+	if claims.Aud != "appstoreconnect-v1" || time.Now().Unix() >= claims.Exp {
+		return errors.New("token expired or not issued for the api")
+	}
+
+	return nil
+}
+
+// RootCA returns the root certificate of the chain the fake signs with.
+func (s *AppStore) RootCA() *x509.Certificate {
+	return s.signer.root
+}
+
+// Verifier returns a JWS verifier trusting the fake.
+func (s *AppStore) Verifier() *appstore.JWSVerifier {
+	return appstore.NewJWSVerifier(s.signer.root)
+}
+
+// Sign signs v in JWS compact format the way the App Store signs
+// transactions, renewal infos and notifications.
+func (s *AppStore) Sign(v interface{}) (string, error) {
+	return s.signer.sign(v)
+}
+
+// PrivateKey returns the .p8 private key the fake accepts API tokens of.
+func (s *AppStore) PrivateKey() []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(s.signer.apiKey)
+	if err != nil {
+		panic(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+// ServerClient creates an App Store Server API client using the fake.
+func (s *AppStore) ServerClient(mode appstore.Mode, opts ...appstore.ClientOption) (*appstore.ServerClient, error) {
+	opts = append([]appstore.ClientOption{appstore.WithBaseURLs(s.ProductionURL(), s.SandboxURL())}, opts...)
+
+	c, err := appstore.NewServerClient(mode, s.PrivateKey(), apiKeyID, "iaptest", s.BundleID, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Verifier = s.Verifier()
+	return c, nil
+}
+
+// AddTransaction adds a transaction and, for subscriptions, its renewal info
+// to the App Store Server API. Transactions without an environment are
+// production transactions.
+//
+// The fake treats all transactions of an environment as belonging to the same
+// customer.
+func (s *AppStore) AddTransaction(tx *appstore.TransactionInfo, ri *appstore.RenewalInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := &appTransaction{tx: &appstore.TransactionInfo{}}
+	*t.tx = *tx
+	if t.tx.Environment == "" {
+		t.tx.Environment = appstore.EnvironmentProduction
+	}
+
+	if ri != nil {
+		t.ri = &appstore.RenewalInfo{}
+		*t.ri = *ri
+		if t.ri.Environment == "" {
+			t.ri.Environment = t.tx.Environment
+		}
+	}
+
+	s.transactions = append(s.transactions, t)
+}
+
+// Transaction returns a copy of a transaction, or nil if there is none.
+func (s *AppStore) Transaction(transactionID string) *appstore.TransactionInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.transactions {
+		if t.tx.TransactionID == transactionID {
+			tx := *t.tx
+			return &tx
+		}
+	}
+
+	return nil
+}
+
+// Consumption returns a copy of the consumption information last sent for a
+// transaction, or nil if there is none.
+func (s *AppStore) Consumption(transactionID string) *appstore.ConsumptionRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.consumption[transactionID]
+	if !ok {
+		return nil
+	}
+
+	r := *c
+	return &r
+}
+
+// AddNotification adds a notification to the notification history, with the
+// results of its send attempts. The signed transaction and renewal info are
+// filled from the notification's Transaction and RenewalInfo, and SignedDate
+// defaults to now. Notifications without data are production notifications.
+func (s *AppStore) AddNotification(n *appstore.Notification, results ...appstore.SendAttemptResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := &appNotification{n: *n}
+	if e.n.SignedDate == 0 {
+		e.n.SignedDate = nowMillis()
+	}
+
+	if e.n.Data == nil {
+		e.n.Data = &appstore.NotificationData{}
+	} else {
+		data := *e.n.Data
+		e.n.Data = &data
+	}
+
+	if e.n.Data.Environment == "" {
+		e.n.Data.Environment = appstore.EnvironmentProduction
+	}
+
+	if e.n.Transaction != nil && e.n.Data.SignedTransactionInfo == "" {
+		signed, err := s.signer.sign(e.n.Transaction)
+		if err != nil {
+			return err
+		}
+
+		e.n.Data.SignedTransactionInfo = signed
+	}
+
+	if e.n.RenewalInfo != nil && e.n.Data.SignedRenewalInfo == "" {
+		signed, err := s.signer.sign(e.n.RenewalInfo)
+		if err != nil {
+			return err
+		}
+
+		e.n.Data.SignedRenewalInfo = signed
+	}
+
+	signed, err := s.signer.sign(&e.n)
+	if err != nil {
+		return err
+	}
+
+	e.item.SignedPayload = signed
+	for _, r := range results {
+		e.item.SendAttemptResults = append(e.item.SendAttemptResults, appstore.SendAttempt{
+			AttemptDate:       e.n.SignedDate,
+			SendAttemptResult: r,
+		})
+	}
+
+	s.history = append(s.history, e)
+	return nil
+}
+
+// AddOrder makes an order ID look up the given transactions.
+func (s *AppStore) AddOrder(orderID string, transactionIDs ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.orders[orderID] = transactionIDs
+}
+
+func environment(mode appstore.Mode) appstore.Environment {
+	if mode == appstore.SandboxMode {
+		return appstore.EnvironmentSandbox
+	}
+
+	return appstore.EnvironmentProduction
+}
+
+func (s *AppStore) serveServerAPI(w http.ResponseWriter, r *http.Request, mode appstore.Mode) {
+	if err := s.signer.verifyToken(bearerToken(r)); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	prefix := appProductionPath
+	if mode == appstore.SandboxMode {
+		prefix = appSandboxPath
+	}
+
+	segs, ok := splitPath(r, prefix+"/inApps/")
+	if !ok || len(segs) < 3 {
+		appError(w, http.StatusNotFound, 4040000, "Not found.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	env := environment(mode)
+	route := strings.Join(segs[:len(segs)-1], "/")
+	id := segs[len(segs)-1]
+
+	switch {
+	case r.Method == http.MethodGet && route == "v1/lookup":
+		s.serveLookUpOrderID(w, env, id)
+		return
+	case r.Method == http.MethodPut && route == "v1/transactions/consumption":
+		s.serveConsumption(w, r, env, id)
+		return
+	case r.Method == http.MethodPost && route == "v1/notifications" && id == "history":
+		s.serveNotificationHistory(w, r, env)
+		return
+	case r.Method == http.MethodPut && route == "v1/subscriptions/extend":
+		s.serveExtend(w, r, env, id)
+		return
+	case r.Method == http.MethodPost && route == "v1/subscriptions/extend" && id == "mass":
+		s.serveMassExtend(w, r, env)
+		return
+	case r.Method == http.MethodGet && len(segs) == 6 && route == "v1/subscriptions/extend/mass/"+segs[4]:
+		s.serveMassExtendStatus(w, segs[4], id)
+		return
+	case r.Method != http.MethodGet:
+		appError(w, http.StatusNotFound, 4040000, "Not found.")
+		return
+	}
+
+	t := s.transaction(env, id)
+	if t == nil {
+		appError(w, http.StatusNotFound, 4040010, "Transaction id not found.")
+		return
+	}
+
+	switch route {
+	case "v1/transactions":
+		s.serveTransaction(w, t.tx)
+	case "v2/history":
+		s.serveHistory(w, r, env)
+	case "v1/subscriptions":
+		s.serveStatuses(w, r, env)
+	case "v2/refund/lookup":
+		s.serveRefundHistory(w, env)
+	default:
+		appError(w, http.StatusNotFound, 4040000, "Not found.")
+	}
+}
+
+func (s *AppStore) transaction(env appstore.Environment, id string) *appTransaction {
+	for _, t := range s.transactions {
+		if t.tx.Environment == env && (t.tx.TransactionID == id || t.tx.OriginalTransactionID == id) {
+			return t
+		}
+	}
+
+	return nil
+}
+
+func (s *AppStore) serveTransaction(w http.ResponseWriter, tx *appstore.TransactionInfo) {
+	signed, err := s.signer.sign(tx)
+	if err != nil {
+		appError(w, http.StatusInternalServerError, 5000000, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"signedTransactionInfo": signed})
+}
+
+func (s *AppStore) signTransactions(txs []*appstore.TransactionInfo) ([]string, error) {
+	signed := make([]string, len(txs))
+	for i, tx := range txs {
+		st, err := s.signer.sign(tx)
+		if err != nil {
+			return nil, err
+		}
+
+		signed[i] = st
+	}
+
+	return signed, nil
+}
+
+func (s *AppStore) serveHistory(w http.ResponseWriter, r *http.Request, env appstore.Environment) {
+	q := r.URL.Query()
+	products := q["productId"]
+
+	var txs []*appstore.TransactionInfo
+	for _, t := range s.transactions {
+		if t.tx.Environment != env || (len(products) > 0 && !contains(products, t.tx.ProductID)) {
+			continue
+		}
+
+		if rv := q.Get("revoked"); rv != "" && (rv == "true") != (t.tx.RevocationDate != 0) {
+			continue
+		}
+
+		txs = append(txs, t.tx)
+	}
+
+	sort.SliceStable(txs, func(i, j int) bool {
+		if q.Get("sort") == "DESCENDING" {
+			return txs[i].PurchaseDate > txs[j].PurchaseDate
+		}
+
+		return txs[i].PurchaseDate < txs[j].PurchaseDate
+	})
+
+	signed, err := s.signTransactions(txs)
+	if err != nil {
+		appError(w, http.StatusInternalServerError, 5000000, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &appstore.HistoryResponse{
+		BundleID:           s.BundleID,
+		Environment:        env,
+		SignedTransactions: signed,
+	})
+}
+
+func (s *AppStore) serveStatuses(w http.ResponseWriter, r *http.Request, env appstore.Environment) {
+	filter := r.URL.Query()["status"]
+
+	// The latest transaction of each subscription, grouped by subscription
+	// group.
+	latest := make(map[string]*appTransaction)
+	var groups []string
+	for _, t := range s.transactions {
+		if t.tx.Environment != env || t.tx.SubscriptionGroupIdentifier == "" {
+			continue
+		}
+
+		l, ok := latest[t.tx.OriginalTransactionID]
+		if !ok && !contains(groups, t.tx.SubscriptionGroupIdentifier) {
+			groups = append(groups, t.tx.SubscriptionGroupIdentifier)
+		}
+
+		if !ok || t.tx.PurchaseDate >= l.tx.PurchaseDate {
+			latest[t.tx.OriginalTransactionID] = t
+		}
+	}
+
+	now := millis(time.Now())
+
+	res := appstore.StatusResponse{
+		Environment: env,
+		BundleID:    s.BundleID,
+	}
+
+	for _, g := range groups {
+		group := appstore.SubscriptionGroupStatus{SubscriptionGroupIdentifier: g}
+
+		for id, t := range latest {
+			if t.tx.SubscriptionGroupIdentifier != g {
+				continue
+			}
+
+			status := subscriptionStatus(t, now)
+			if len(filter) > 0 && !contains(filter, strconv.Itoa(int(status))) {
+				continue
+			}
+
+			last := appstore.LastTransaction{
+				Status:                status,
+				OriginalTransactionID: id,
+			}
+
+			var err error
+			last.SignedTransactionInfo, err = s.signer.sign(t.tx)
+			if err == nil && t.ri != nil {
+				last.SignedRenewalInfo, err = s.signer.sign(t.ri)
+			}
+			if err != nil {
+				appError(w, http.StatusInternalServerError, 5000000, err.Error())
+				return
+			}
+
+			group.LastTransactions = append(group.LastTransactions, last)
+		}
+
+		sort.Slice(group.LastTransactions, func(i, j int) bool {
+			return group.LastTransactions[i].OriginalTransactionID < group.LastTransactions[j].OriginalTransactionID
+		})
+
+		res.Data = append(res.Data, group)
+	}
+
+	writeJSON(w, http.StatusOK, &res)
+}
+
+func subscriptionStatus(t *appTransaction, now int64) appstore.SubscriptionStatus {
+	switch {
+	case t.tx.RevocationDate != 0:
+		return appstore.SSRevoked
+	case t.tx.ExpiresDate > now:
+		return appstore.SSActive
+	case t.ri != nil && t.ri.GracePeriodExpiresDate > now:
+		return appstore.SSGracePeriod
+	case t.ri != nil && t.ri.IsInBillingRetryPeriod:
+		return appstore.SSBillingRetry
+	default:
+		return appstore.SSExpired
+	}
+}
+
+func (s *AppStore) serveRefundHistory(w http.ResponseWriter, env appstore.Environment) {
+	var txs []*appstore.TransactionInfo
+	for _, t := range s.transactions {
+		if t.tx.Environment == env && t.tx.RevocationDate != 0 {
+			txs = append(txs, t.tx)
+		}
+	}
+
+	signed, err := s.signTransactions(txs)
+	if err != nil {
+		appError(w, http.StatusInternalServerError, 5000000, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &appstore.RefundHistoryResponse{
+		SignedTransactions: signed,
+	})
+}
+
+func (s *AppStore) serveLookUpOrderID(w http.ResponseWriter, env appstore.Environment, orderID string) {
+	ids, ok := s.orders[orderID]
+	if !ok {
+		writeJSON(w, http.StatusOK, &appstore.OrderLookupResponse{Status: appstore.OrderInvalid})
+		return
+	}
+
+	var txs []*appstore.TransactionInfo
+	for _, id := range ids {
+		for _, t := range s.transactions {
+			if t.tx.Environment == env && t.tx.TransactionID == id {
+				txs = append(txs, t.tx)
+			}
+		}
+	}
+
+	signed, err := s.signTransactions(txs)
+	if err != nil {
+		appError(w, http.StatusInternalServerError, 5000000, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &appstore.OrderLookupResponse{
+		Status:             appstore.OrderValid,
+		SignedTransactions: signed,
+	})
+}
+
+func (s *AppStore) serveConsumption(w http.ResponseWriter, r *http.Request, env appstore.Environment, id string) {
+	t := s.transaction(env, id)
+	if t == nil {
+		appError(w, http.StatusNotFound, 4040010, "Transaction id not found.")
+		return
+	}
+
+	var c appstore.ConsumptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		appError(w, http.StatusBadRequest, 4000023, "Invalid request.")
+		return
+	}
+
+	s.consumption[t.tx.TransactionID] = &c
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// appHistoryPageSize is the number of notifications per notification history
+// page.
+const appHistoryPageSize = 20
+
+func (s *AppStore) serveNotificationHistory(w http.ResponseWriter, r *http.Request, env appstore.Environment) {
+	var req appstore.NotificationHistoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		appError(w, http.StatusBadRequest, 4000023, "Invalid request.")
+		return
+	}
+
+	if req.StartDate == 0 || req.EndDate == 0 || req.StartDate >= req.EndDate {
+		appError(w, http.StatusBadRequest, 4000016, "Invalid start date or end date.")
+		return
+	}
+
+	offset := 0
+	if t := r.URL.Query().Get("paginationToken"); t != "" {
+		n, err := strconv.Atoi(t)
+		if err != nil || n < 0 {
+			appError(w, http.StatusBadRequest, 4000006, "Invalid pagination token.")
+			return
+		}
+
+		offset = n
+	}
+
+	var matched []appstore.NotificationHistoryItem
+	for _, e := range s.history {
+		if notificationMatches(&e.n, &e.item, env, &req) {
+			matched = append(matched, e.item)
+		}
+	}
+
+	var res appstore.NotificationHistoryResponse
+	if offset < len(matched) {
+		matched = matched[offset:]
+		if len(matched) > appHistoryPageSize {
+			matched = matched[:appHistoryPageSize]
+			res.HasMore = true
+			res.PaginationToken = strconv.Itoa(offset + appHistoryPageSize)
+		}
+
+		res.NotificationHistory = matched
+	}
+
+	writeJSON(w, http.StatusOK, &res)
+}
+
+func notificationMatches(n *appstore.Notification, item *appstore.NotificationHistoryItem, env appstore.Environment, req *appstore.NotificationHistoryRequest) bool {
+	if n.Data.Environment != env || n.SignedDate < req.StartDate || n.SignedDate >= req.EndDate {
+		return false
+	}
+
+	if (req.NotificationType != "" && n.NotificationType != req.NotificationType) ||
+		(req.NotificationSubtype != "" && n.Subtype != req.NotificationSubtype) {
+		return false
+	}
+
+	if req.TransactionID != "" && (n.Transaction == nil ||
+		(n.Transaction.TransactionID != req.TransactionID && n.Transaction.OriginalTransactionID != req.TransactionID)) {
+		return false
+	}
+
+	if req.OnlyFailures {
+		attempts := item.SendAttemptResults
+		if len(attempts) > 0 && attempts[len(attempts)-1].SendAttemptResult == appstore.SARSuccess {
+			return false
+		}
+	}
+
+	return true
+}
+
+// maxExtendByDays is the longest renewal date extension the App Store accepts.
+const maxExtendByDays = 90
+
+func (s *AppStore) serveExtend(w http.ResponseWriter, r *http.Request, env appstore.Environment, id string) {
+	var req appstore.ExtendRenewalDateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		appError(w, http.StatusBadRequest, 4000023, "Invalid request.")
+		return
+	}
+
+	if req.ExtendByDays < 1 || req.ExtendByDays > maxExtendByDays {
+		appError(w, http.StatusBadRequest, 4000009, "Invalid extend by days value.")
+		return
+	}
+
+	t := s.transaction(env, id)
+	if t == nil {
+		appError(w, http.StatusNotFound, 4040005, "Original transaction id not found.")
+		return
+	}
+
+	if !extendable(t, nowMillis()) {
+		appError(w, http.StatusForbidden, 4030004, "Subscription extension ineligible.")
+		return
+	}
+
+	extend(t, req.ExtendByDays)
+
+	writeJSON(w, http.StatusOK, &appstore.ExtendRenewalDateResponse{
+		OriginalTransactionID: t.tx.OriginalTransactionID,
+		WebOrderLineItemID:    t.tx.WebOrderLineItemID,
+		Success:               true,
+		EffectiveDate:         t.tx.ExpiresDate,
+	})
+}
+
+func (s *AppStore) serveMassExtend(w http.ResponseWriter, r *http.Request, env appstore.Environment) {
+	var req appstore.MassExtendRenewalDateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		appError(w, http.StatusBadRequest, 4000023, "Invalid request.")
+		return
+	}
+
+	if req.ExtendByDays < 1 || req.ExtendByDays > maxExtendByDays {
+		appError(w, http.StatusBadRequest, 4000009, "Invalid extend by days value.")
+		return
+	}
+
+	now := nowMillis()
+	e := &appExtension{
+		status: appstore.MassExtendRenewalDateStatus{
+			RequestIdentifier: req.RequestIdentifier,
+			Complete:          true,
+			CompleteDate:      now,
+		},
+		pending: s.MassExtendPolls,
+	}
+
+	for _, t := range s.transactions {
+		if t.tx.Environment != env || t.tx.ProductID != req.ProductID || !extendable(t, now) {
+			continue
+		}
+
+		if len(req.StorefrontCountryCodes) > 0 && !contains(req.StorefrontCountryCodes, t.tx.Storefront) {
+			continue
+		}
+
+		extend(t, req.ExtendByDays)
+		e.status.SucceededCount++
+	}
+
+	s.extensions[req.ProductID+"/"+req.RequestIdentifier] = e
+
+	writeJSON(w, http.StatusOK, map[string]string{"requestIdentifier": req.RequestIdentifier})
+}
+
+func (s *AppStore) serveMassExtendStatus(w http.ResponseWriter, productID, requestIdentifier string) {
+	e, ok := s.extensions[productID+"/"+requestIdentifier]
+	if !ok {
+		appError(w, http.StatusNotFound, 4040009, "Status request not found.")
+		return
+	}
+
+	if e.pending > 0 {
+		e.pending--
+		writeJSON(w, http.StatusOK, &appstore.MassExtendRenewalDateStatus{RequestIdentifier: requestIdentifier})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &e.status)
+}
+
+// extendable reports whether a subscription transaction is active.
+func extendable(t *appTransaction, now int64) bool {
+	return t.tx.RevocationDate == 0 && t.tx.ExpiresDate > now
+}
+
+func extend(t *appTransaction, days int) {
+	ms := int64(days) * int64(24*time.Hour/time.Millisecond)
+
+	t.tx.ExpiresDate += ms
+	if t.ri != nil && t.ri.RenewalDate != 0 {
+		t.ri.RenewalDate += ms
+	}
+}
+
+// appError writes an App Store Server API error.
+func appError(w http.ResponseWriter, statusCode, code int, message string) {
+	writeJSON(w, statusCode, &appstore.APIError{
+		ErrorCode:    code,
+		ErrorMessage: message,
+	})
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+
+	return false
+}
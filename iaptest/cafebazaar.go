@@ -0,0 +1,267 @@
+package iaptest
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/brainleap/iap/cafebazaar"
+	"golang.org/x/oauth2"
+)
+
+const (
+	cafebazaarBasePath      = "/devapi/v2/api"
+	cafebazaarAuthorizePath = "/devapi/v2/auth/authorize/"
+	cafebazaarTokenPath     = "/devapi/v2/auth/token/"
+	cafebazaarAccessToken   = "iaptest-access-token"
+	cafebazaarRefreshToken  = "iaptest-refresh-token"
+)
+
+// Cafebazaar is a fake of the Cafebazaar developer API.
+//
+// Purchases are looked up by token and must match the package name and
+// product or subscription ID of the request. The fake's OAuth token endpoint
+// accepts any authorization code.
+type Cafebazaar struct {
+	*Server
+
+	mu            sync.Mutex
+	products      map[string]*cafebazaarProduct
+	subscriptions map[string]*cafebazaarSubscription
+}
+
+type cafebazaarProduct struct {
+	pkg  string
+	prod string
+	p    cafebazaar.Product
+}
+
+type cafebazaarSubscription struct {
+	pkg string
+	sub string
+	s   cafebazaar.Subscription
+}
+
+// NewCafebazaar starts a new fake Cafebazaar store. The caller should call
+// Close when finished, to shut it down.
+func NewCafebazaar() *Cafebazaar {
+	s := &Cafebazaar{
+		products:      make(map[string]*cafebazaarProduct),
+		subscriptions: make(map[string]*cafebazaarSubscription),
+	}
+	s.Server = newServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+// BaseURL returns the payment API base URL of the fake.
+func (s *Cafebazaar) BaseURL() string {
+	return s.URL + cafebazaarBasePath
+}
+
+// Endpoint returns the OAuth endpoint of the fake.
+func (s *Cafebazaar) Endpoint() oauth2.Endpoint {
+	return oauth2.Endpoint{
+		AuthURL:  s.URL + cafebazaarAuthorizePath,
+		TokenURL: s.URL + cafebazaarTokenPath,
+	}
+}
+
+// Client creates a Cafebazaar client using the fake, already set up with a
+// valid token.
+func (s *Cafebazaar) Client(opts ...cafebazaar.ClientOption) *cafebazaar.Client {
+	opts = append([]cafebazaar.ClientOption{cafebazaar.WithBaseURL(s.BaseURL())}, opts...)
+
+	c := cafebazaar.NewClient("iaptest", "iaptest", "", opts...)
+	c.OAuth.Endpoint = s.Endpoint()
+	c.SetupWithToken(&oauth2.Token{
+		AccessToken:  cafebazaarAccessToken,
+		TokenType:    "Bearer",
+		RefreshToken: cafebazaarRefreshToken,
+	})
+
+	return c
+}
+
+// AddProduct adds an in-app product purchase.
+func (s *Cafebazaar) AddProduct(pkg, prod, token string, p *cafebazaar.Product) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.products[token] = &cafebazaarProduct{pkg: pkg, prod: prod, p: *p}
+}
+
+// AddSubscription adds a subscription purchase.
+func (s *Cafebazaar) AddSubscription(pkg, sub, token string, sp *cafebazaar.Subscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subscriptions[token] = &cafebazaarSubscription{pkg: pkg, sub: sub, s: *sp}
+}
+
+// Product returns a copy of an in-app product purchase, or nil if there is
+// none.
+func (s *Cafebazaar) Product(token string) *cafebazaar.Product {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.products[token]
+	if !ok {
+		return nil
+	}
+
+	p := e.p
+	return &p
+}
+
+// Subscription returns a copy of a subscription purchase, or nil if there is
+// none.
+func (s *Cafebazaar) Subscription(token string) *cafebazaar.Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.subscriptions[token]
+	if !ok {
+		return nil
+	}
+
+	sp := e.s
+	return &sp
+}
+
+// SetProductState sets the purchase state of an in-app product purchase.
+func (s *Cafebazaar) SetProductState(token string, state cafebazaar.PurchaseState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.products[token]; ok {
+		e.p.PurchaseState = state
+	}
+}
+
+// ExpireSubscription ends a subscription purchase now.
+func (s *Cafebazaar) ExpireSubscription(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.subscriptions[token]; ok {
+		e.s.ValidUntilTimeMillis = nowMillis()
+		e.s.AutoRenewing = false
+	}
+}
+
+// Refund refunds an in-app product or subscription purchase. Refunded
+// subscriptions end now.
+func (s *Cafebazaar) Refund(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.products[token]; ok {
+		e.p.PurchaseState = cafebazaar.PurchaseRefunded
+	}
+
+	if e, ok := s.subscriptions[token]; ok {
+		e.s.ValidUntilTimeMillis = nowMillis()
+		e.s.AutoRenewing = false
+	}
+}
+
+func (s *Cafebazaar) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == cafebazaarTokenPath {
+		s.serveToken(w, r)
+		return
+	}
+
+	if bearerToken(r) != cafebazaarAccessToken {
+		cafebazaarError(w, http.StatusUnauthorized, "invalid_token", "Access token is invalid or expired.")
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		cafebazaarError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed.")
+		return
+	}
+
+	segs, ok := splitPath(r, cafebazaarBasePath+"/")
+	if !ok {
+		cafebazaarError(w, http.StatusNotFound, "not_found", "Not found.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	// validate/{pkg}/inapp/{prod}/purchases/{token}
+	case len(segs) == 6 && segs[0] == "validate" && segs[2] == "inapp" && segs[4] == "purchases":
+		e, ok := s.products[segs[5]]
+		if !ok || e.pkg != segs[1] || e.prod != segs[3] {
+			cafebazaarNotFound(w)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, &e.p)
+
+	// applications/{pkg}/subscriptions/{sub}/purchases/{token}[/cancel]
+	case (len(segs) == 6 || len(segs) == 7 && segs[6] == "cancel") &&
+		segs[0] == "applications" && segs[2] == "subscriptions" && segs[4] == "purchases":
+		e, ok := s.subscriptions[segs[5]]
+		if !ok || e.pkg != segs[1] || e.sub != segs[3] {
+			cafebazaarNotFound(w)
+			return
+		}
+
+		if len(segs) == 7 {
+			e.s.AutoRenewing = false
+			writeJSON(w, http.StatusOK, struct{}{})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, &e.s)
+
+	default:
+		cafebazaarError(w, http.StatusNotFound, "not_found", "Not found.")
+	}
+}
+
+func (s *Cafebazaar) serveToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		cafebazaarError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed.")
+		return
+	}
+
+	switch r.FormValue("grant_type") {
+	case "authorization_code":
+		if r.FormValue("code") == "" {
+			cafebazaarError(w, http.StatusBadRequest, "invalid_grant", "Missing authorization code.")
+			return
+		}
+	case "refresh_token":
+		if r.FormValue("refresh_token") != cafebazaarRefreshToken {
+			cafebazaarError(w, http.StatusBadRequest, "invalid_grant", "Invalid refresh token.")
+			return
+		}
+	default:
+		cafebazaarError(w, http.StatusBadRequest, "unsupported_grant_type", "Unsupported grant type.")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token":  cafebazaarAccessToken,
+		"token_type":    "Bearer",
+		"expires_in":    3600,
+		"refresh_token": cafebazaarRefreshToken,
+		"scope":         "androidpublisher",
+	})
+}
+
+func cafebazaarNotFound(w http.ResponseWriter) {
+	cafebazaarError(w, http.StatusNotFound, "not_found", "The requested purchase is not found!")
+}
+
+// cafebazaarError writes an error in the Cafebazaar API error format.
+func cafebazaarError(w http.ResponseWriter, statusCode int, code, description string) {
+	writeJSON(w, statusCode, &cafebazaar.APIError{
+		Error:            code,
+		ErrorDescription: description,
+	})
+}
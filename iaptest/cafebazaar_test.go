@@ -0,0 +1,148 @@
+package iaptest_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/brainleap/iap"
+	"github.com/brainleap/iap/cafebazaar"
+	"github.com/brainleap/iap/iaptest"
+)
+
+func TestCafebazaarProduct(t *testing.T) {
+	tests := []struct {
+		name      string
+		pkg       string
+		prod      string
+		token     string
+		mutate    func(store *iaptest.Cafebazaar)
+		wantErr   error
+		wantState cafebazaar.PurchaseState
+	}{
+		{name: "valid", pkg: testPackage, prod: "coins_100", token: "token"},
+		{
+			name:      "refunded",
+			pkg:       testPackage,
+			prod:      "coins_100",
+			token:     "token",
+			mutate:    func(store *iaptest.Cafebazaar) { store.Refund("token") },
+			wantState: cafebazaar.PurchaseRefunded,
+		},
+		{
+			name:      "state set",
+			pkg:       testPackage,
+			prod:      "coins_100",
+			token:     "token",
+			mutate:    func(store *iaptest.Cafebazaar) { store.SetProductState("token", cafebazaar.PurchaseRefunded) },
+			wantState: cafebazaar.PurchaseRefunded,
+		},
+		{name: "unknown token", pkg: testPackage, prod: "coins_100", token: "other", wantErr: iap.ErrPurchaseNotFound},
+		{name: "other package", pkg: "com.example.other", prod: "coins_100", token: "token", wantErr: iap.ErrPurchaseNotFound},
+		{name: "other product", pkg: testPackage, prod: "coins_500", token: "token", wantErr: iap.ErrPurchaseNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := iaptest.NewCafebazaar()
+			defer store.Close()
+
+			store.AddProduct(testPackage, "coins_100", "token", &cafebazaar.Product{DeveloperPayload: "payload"})
+			if tt.mutate != nil {
+				tt.mutate(store)
+			}
+
+			p, err := store.Client().ValidateProduct(tt.pkg, tt.prod, tt.token)
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Fatalf("ValidateProduct() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err == nil && (p.PurchaseState != tt.wantState || p.DeveloperPayload != "payload") {
+				t.Errorf("ValidateProduct() = %+v", p)
+			}
+		})
+	}
+}
+
+func TestCafebazaarSubscription(t *testing.T) {
+	validUntil := time.Now().Add(24*time.Hour).UnixNano() / int64(time.Millisecond)
+
+	tests := []struct {
+		name             string
+		mutate           func(store *iaptest.Cafebazaar, c *cafebazaar.Client) error
+		wantAutoRenewing bool
+		wantExpired      bool
+	}{
+		{name: "active", wantAutoRenewing: true},
+		{
+			name: "canceled",
+			mutate: func(store *iaptest.Cafebazaar, c *cafebazaar.Client) error {
+				return c.CancelSubscription(testPackage, "monthly", "token")
+			},
+		},
+		{
+			name: "expired",
+			mutate: func(store *iaptest.Cafebazaar, c *cafebazaar.Client) error {
+				store.ExpireSubscription("token")
+				return nil
+			},
+			wantExpired: true,
+		},
+		{
+			name: "refunded",
+			mutate: func(store *iaptest.Cafebazaar, c *cafebazaar.Client) error {
+				store.Refund("token")
+				return nil
+			},
+			wantExpired: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := iaptest.NewCafebazaar()
+			defer store.Close()
+
+			store.AddSubscription(testPackage, "monthly", "token", &cafebazaar.Subscription{
+				ValidUntilTimeMillis: validUntil,
+				AutoRenewing:         true,
+			})
+
+			c := store.Client()
+			if tt.mutate != nil {
+				if err := tt.mutate(store, c); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			s, err := c.ValidateSubscription(testPackage, "monthly", "token")
+			if err != nil {
+				t.Fatalf("ValidateSubscription() error = %v", err)
+			}
+
+			if s.AutoRenewing != tt.wantAutoRenewing || (s.ValidUntilTimeMillis < validUntil) != tt.wantExpired {
+				t.Errorf("ValidateSubscription() = %+v", s)
+			}
+		})
+	}
+}
+
+func TestCafebazaarFailNext(t *testing.T) {
+	store := iaptest.NewCafebazaar()
+	defer store.Close()
+
+	store.AddProduct(testPackage, "coins_100", "token", &cafebazaar.Product{})
+	store.FailNext(1, http.StatusBadGateway, "")
+
+	c := store.Client()
+
+	_, err := c.ValidateProduct(testPackage, "coins_100", "token")
+	if !errors.Is(err, iap.ErrUnavailable) || !iap.IsRetryable(err) {
+		t.Fatalf("ValidateProduct() error = %v, want retryable %v", err, iap.ErrUnavailable)
+	}
+
+	if _, err := c.ValidateProduct(testPackage, "coins_100", "token"); err != nil {
+		t.Errorf("ValidateProduct() after failure error = %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+// Package iaptest provides in-process fakes of the store APIs for tests.
+//
+// Each fake runs an httptest server backed by a purchase database that tests
+// seed and mutate, and creates clients pointed at itself:
+//
+//	store := iaptest.NewPlayStore()
+//	defer store.Close()
+//
+//	store.AddProduct("com.example.app", "coins_100", "token", &playstore.Product{})
+//
+//	client, err := store.Client()
+//
+// Errors and latency are injected with FailNext and SetLatency.
+package iaptest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is the test server shared by the fakes.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	latency  time.Duration
+	failures []failure
+}
+
+type failure struct {
+	statusCode int
+	body       string
+}
+
+func newServer(h http.Handler) *Server {
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.intercept(w, r) {
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	}))
+
+	return s
+}
+
+// FailNext makes the next n requests fail with the given status code and
+// body.
+func (s *Server) FailNext(n, statusCode int, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 0; i < n; i++ {
+		s.failures = append(s.failures, failure{statusCode: statusCode, body: body})
+	}
+}
+
+// SetLatency delays every following request by d.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latency = d
+}
+
+// intercept applies the injected latency and failures and reports whether the
+// request was answered.
+func (s *Server) intercept(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	latency := s.latency
+
+	var f *failure
+	if len(s.failures) > 0 {
+		f = &s.failures[0]
+		s.failures = s.failures[1:]
+	}
+	s.mu.Unlock()
+
+	if latency > 0 {
+		t := time.NewTimer(latency)
+		select {
+		case <-t.C:
+		case <-r.Context().Done():
+			t.Stop()
+			return true
+		}
+	}
+
+	if f == nil {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(f.statusCode)
+	w.Write([]byte(f.body))
+
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(v)
+}
+
+// bearerToken returns the bearer token of a request.
+func bearerToken(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, "Bearer ") {
+		return ""
+	}
+
+	return strings.TrimPrefix(h, "Bearer ")
+}
+
+// splitPath splits the escaped path of a request below prefix into unescaped
+// segments.
+func splitPath(r *http.Request, prefix string) ([]string, bool) {
+	p := r.URL.EscapedPath()
+	if !strings.HasPrefix(p, prefix) {
+		return nil, false
+	}
+
+	segs := strings.Split(strings.Trim(strings.TrimPrefix(p, prefix), "/"), "/")
+	for i, seg := range segs {
+		u, err := url.PathUnescape(seg)
+		if err != nil {
+			return nil, false
+		}
+
+		segs[i] = u
+	}
+
+	return segs, true
+}
+
+func formatInt(i int64) string {
+	return strconv.FormatInt(i, 10)
+}
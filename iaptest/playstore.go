@@ -0,0 +1,701 @@
+package iaptest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brainleap/iap/playstore"
+)
+
+const (
+	playBasePath    = "/androidpublisher/v3"
+	playTokenPath   = "/token"
+	playAccessToken = "iaptest-access-token"
+)
+
+var (
+	serviceAccountKeyOnce sync.Once
+	serviceAccountKey     *rsa.PrivateKey
+)
+
+// PlayStore is a fake of the Android Publisher API purchases and orders
+// endpoints.
+//
+// Purchases are looked up by token and must match the package name and
+// product or subscription ID of the request. Requests must carry the access
+// token issued by the fake's OAuth token endpoint.
+type PlayStore struct {
+	*Server
+
+	mu            sync.Mutex
+	products      map[string]*playProduct
+	subscriptions map[string]*playSubscription
+	v2            map[string]*playSubscriptionV2
+	voided        []*playVoided
+	refunded      map[string]bool
+}
+
+type playProduct struct {
+	pkg  string
+	prod string
+	test bool
+	p    playstore.Product
+}
+
+type playSubscription struct {
+	pkg  string
+	sub  string
+	test bool
+	s    playstore.Subscription
+}
+
+type playSubscriptionV2 struct {
+	pkg string
+	s   playstore.SubscriptionPurchaseV2
+}
+
+type playVoided struct {
+	pkg          string
+	subscription bool
+	v            playstore.VoidedPurchase
+}
+
+// NewPlayStore starts a new fake Google Play store. The caller should call
+// Close when finished, to shut it down.
+func NewPlayStore() *PlayStore {
+	s := &PlayStore{
+		products:      make(map[string]*playProduct),
+		subscriptions: make(map[string]*playSubscription),
+		v2:            make(map[string]*playSubscriptionV2),
+		refunded:      make(map[string]bool),
+	}
+	s.Server = newServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+// BaseURL returns the Android Publisher API base URL of the fake.
+func (s *PlayStore) BaseURL() string {
+	return s.URL + playBasePath
+}
+
+// ServiceAccountKey returns a service account JSON key whose token URI points
+// at the fake.
+func (s *PlayStore) ServiceAccountKey() []byte {
+	serviceAccountKeyOnce.Do(func() {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			panic(err)
+		}
+
+		serviceAccountKey = key
+	})
+
+	key := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(serviceAccountKey),
+	})
+
+	b, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "iaptest",
+		"private_key_id": "iaptest",
+		"private_key":    string(key),
+		"client_email":   "iaptest@iaptest.iam.gserviceaccount.com",
+		"client_id":      "1",
+		"token_uri":      s.URL + playTokenPath,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return b
+}
+
+// Client creates a PlayStore client using the fake.
+func (s *PlayStore) Client(opts ...playstore.ClientOption) (*playstore.Client, error) {
+	opts = append([]playstore.ClientOption{playstore.WithBaseURL(s.BaseURL())}, opts...)
+
+	return playstore.NewClient(s.ServiceAccountKey(), opts...)
+}
+
+// AddProduct adds an in-app product purchase.
+func (s *PlayStore) AddProduct(pkg, prod, token string, p *playstore.Product) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.products[token] = &playProduct{pkg: pkg, prod: prod, p: *p}
+}
+
+// AddSubscription adds a subscription purchase.
+func (s *PlayStore) AddSubscription(pkg, sub, token string, sp *playstore.Subscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subscriptions[token] = &playSubscription{pkg: pkg, sub: sub, s: *sp}
+}
+
+// AddSubscriptionV2 adds a subscription purchase served by the
+// subscriptionsv2 endpoint.
+func (s *PlayStore) AddSubscriptionV2(pkg, token string, sp *playstore.SubscriptionPurchaseV2) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.v2[token] = &playSubscriptionV2{pkg: pkg, s: *sp}
+}
+
+// SetSubscriptionState sets the state of a subscription purchase served by
+// the subscriptionsv2 endpoint.
+func (s *PlayStore) SetSubscriptionState(token string, state playstore.SubscriptionState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.v2[token]; ok {
+		e.s.SubscriptionState = state
+	}
+}
+
+// Product returns a copy of an in-app product purchase, or nil if there is
+// none.
+func (s *PlayStore) Product(token string) *playstore.Product {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.products[token]
+	if !ok {
+		return nil
+	}
+
+	p := e.p
+	return &p
+}
+
+// Subscription returns a copy of a subscription purchase, or nil if there is
+// none.
+func (s *PlayStore) Subscription(token string) *playstore.Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.subscriptions[token]
+	if !ok {
+		return nil
+	}
+
+	sp := e.s
+	return &sp
+}
+
+// SetTestPurchase marks an in-app product or subscription purchase as made by
+// a license tester. Purchases only report a purchase type when it is set or
+// non-zero, as PTTest is the zero value of playstore.PurchaseType.
+func (s *PlayStore) SetTestPurchase(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.products[token]; ok {
+		e.test = true
+	}
+
+	if e, ok := s.subscriptions[token]; ok {
+		e.test = true
+	}
+}
+
+// SetProductState sets the purchase state of an in-app product purchase.
+func (s *PlayStore) SetProductState(token string, state playstore.PurchaseState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.products[token]; ok {
+		e.p.PurchaseState = state
+	}
+}
+
+// ExpireSubscription ends a subscription purchase now.
+func (s *PlayStore) ExpireSubscription(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.subscriptions[token]; ok {
+		expire(&e.s, playstore.CRSystemCanceled)
+	}
+
+	if e, ok := s.v2[token]; ok {
+		expireV2(&e.s)
+	}
+}
+
+func expireV2(s *playstore.SubscriptionPurchaseV2) {
+	now := time.Now().UTC()
+
+	s.SubscriptionState = playstore.SSExpired
+	for i := range s.LineItems {
+		li := &s.LineItems[i]
+		if li.ExpiryTime.After(now) {
+			li.ExpiryTime = now
+		}
+		if li.AutoRenewingPlan != nil {
+			li.AutoRenewingPlan.AutoRenewEnabled = false
+		}
+	}
+}
+
+// Refund refunds an in-app product or subscription purchase as a developer
+// refund. See Void.
+func (s *PlayStore) Refund(token string) {
+	s.Void(token, playstore.VSDeveloper, playstore.VROther)
+}
+
+// Void voids an in-app product or subscription purchase and lists it as a
+// voided purchase. Voided products are canceled and voided subscriptions end
+// now.
+func (s *PlayStore) Void(token string, source playstore.VoidedSource, reason playstore.VoidedReason) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.void(token, source, reason)
+}
+
+func (s *PlayStore) void(token string, source playstore.VoidedSource, reason playstore.VoidedReason) {
+	v := &playVoided{
+		v: playstore.VoidedPurchase{
+			Kind:             "androidpublisher#voidedPurchase",
+			PurchaseToken:    token,
+			VoidedTimeMillis: nowMillis(),
+			VoidedSource:     source,
+			VoidedReason:     reason,
+		},
+	}
+
+	if e, ok := s.products[token]; ok {
+		e.p.PurchaseState = playstore.PurchaseCanceled
+
+		v.pkg = e.pkg
+		v.v.PurchaseTimeMillis = e.p.PurchaseTimeMillis
+		v.v.OrderID = e.p.OrderID
+		v.v.VoidedQuantity = 1
+	} else if e, ok := s.subscriptions[token]; ok {
+		expire(&e.s, playstore.CRSystemCanceled)
+
+		v.pkg = e.pkg
+		v.subscription = true
+		v.v.PurchaseTimeMillis = e.s.StartTimeMillis
+		v.v.OrderID = e.s.OrderID
+	} else {
+		return
+	}
+
+	s.voided = append(s.voided, v)
+}
+
+func expire(s *playstore.Subscription, reason playstore.CancelReason) {
+	s.ExpiryTimeMillis = nowMillis()
+	s.AutoRenewing = false
+	s.CancelReason = reason
+}
+
+func nowMillis() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
+
+func (s *PlayStore) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == playTokenPath {
+		s.serveToken(w, r)
+		return
+	}
+
+	if bearerToken(r) != playAccessToken {
+		playError(w, http.StatusUnauthorized, "UNAUTHENTICATED", "", "Request had invalid authentication credentials.")
+		return
+	}
+
+	segs, ok := splitPath(r, playBasePath+"/applications/")
+
+	// applications/{pkg}/purchases/voidedpurchases
+	if ok && len(segs) == 3 && segs[1] == "purchases" && segs[2] == "voidedpurchases" && r.Method == http.MethodGet {
+		s.serveVoidedPurchases(w, r, segs[0])
+		return
+	}
+
+	// applications/{pkg}/purchases/subscriptionsv2/tokens/{token}
+	if ok && len(segs) == 5 && segs[1] == "purchases" && segs[2] == "subscriptionsv2" && segs[3] == "tokens" && r.Method == http.MethodGet {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		e, found := s.v2[segs[4]]
+		if !found || e.pkg != segs[0] {
+			playNotFound(w)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, &e.s)
+		return
+	}
+
+	// applications/{pkg}/orders:batchGet
+	if ok && len(segs) == 2 && segs[1] == "orders:batchGet" && r.Method == http.MethodGet {
+		s.serveOrders(w, r, segs[0])
+		return
+	}
+
+	// applications/{pkg}/orders/{orderId}[:refund]
+	if ok && len(segs) == 3 && segs[1] == "orders" {
+		s.serveOrder(w, r, segs[0], segs[2])
+		return
+	}
+
+	// applications/{pkg}/purchases/{products|subscriptions}/{id}/tokens/{token}[:action]
+	if !ok || len(segs) != 6 || segs[1] != "purchases" || segs[4] != "tokens" {
+		playError(w, http.StatusNotFound, "NOT_FOUND", "notFound", "Not found.")
+		return
+	}
+
+	token, action := segs[5], ""
+	if i := strings.LastIndex(token, ":"); i >= 0 {
+		token, action = token[:i], token[i+1:]
+	}
+
+	method := http.MethodPost
+	if action == "" {
+		method = http.MethodGet
+	}
+
+	if r.Method != method {
+		playError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "methodNotAllowed", "Method not allowed.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch segs[2] {
+	case "products":
+		s.serveProduct(w, r, segs[0], segs[3], token, action)
+	case "subscriptions":
+		s.serveSubscription(w, r, segs[0], segs[3], token, action)
+	default:
+		playError(w, http.StatusNotFound, "NOT_FOUND", "notFound", "Not found.")
+	}
+}
+
+// purchaseType returns the purchase type to report, or nil for regular
+// purchases, which have none.
+func purchaseType(t playstore.PurchaseType, test bool) *playstore.PurchaseType {
+	if t == playstore.PTTest && !test {
+		return nil
+	}
+
+	return &t
+}
+
+func (s *PlayStore) serveToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.FormValue("assertion") == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error":             "invalid_request",
+			"error_description": "missing assertion",
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": playAccessToken,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+	})
+}
+
+func (s *PlayStore) serveProduct(w http.ResponseWriter, r *http.Request, pkg, prod, token, action string) {
+	e, ok := s.products[token]
+	if !ok || e.pkg != pkg {
+		playNotFound(w)
+		return
+	}
+
+	if e.prod != prod {
+		playError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "purchaseTokenDoesNotMatchProductId", "The purchase token does not match the product ID.")
+		return
+	}
+
+	switch action {
+	case "":
+		writeJSON(w, http.StatusOK, &struct {
+			*playstore.Product
+			PurchaseType *playstore.PurchaseType `json:"purchaseType,omitempty"`
+		}{&e.p, purchaseType(e.p.PurchaseType, e.test)})
+	case "acknowledge":
+		var body struct {
+			DeveloperPayload string `json:"developerPayload"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		e.p.AcknowledgementState = playstore.Acknowledged
+		e.p.DeveloperPayload = body.DeveloperPayload
+		writeJSON(w, http.StatusOK, struct{}{})
+	case "consume":
+		if e.p.ConsumptionState == playstore.Consumed {
+			playError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "productNotOwnedByUser", "The product purchase is not owned by the user.")
+			return
+		}
+
+		e.p.ConsumptionState = playstore.Consumed
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		playError(w, http.StatusNotFound, "NOT_FOUND", "notFound", "Not found.")
+	}
+}
+
+func (s *PlayStore) serveSubscription(w http.ResponseWriter, r *http.Request, pkg, sub, token, action string) {
+	e, ok := s.subscriptions[token]
+	if !ok || e.pkg != pkg {
+		playNotFound(w)
+		return
+	}
+
+	if e.sub != sub {
+		playError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "purchaseTokenDoesNotMatchSubscriptionId", "The purchase token does not match the subscription ID.")
+		return
+	}
+
+	switch action {
+	case "":
+		writeJSON(w, http.StatusOK, &struct {
+			*playstore.Subscription
+			PurchaseType *playstore.PurchaseType `json:"purchaseType,omitempty"`
+		}{&e.s, purchaseType(e.s.PurchaseType, e.test)})
+	case "acknowledge":
+		var body struct {
+			DeveloperPayload string `json:"developerPayload"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		e.s.AcknowledgementState = playstore.Acknowledged
+		e.s.DeveloperPayload = body.DeveloperPayload
+		writeJSON(w, http.StatusOK, struct{}{})
+	case "cancel":
+		e.s.AutoRenewing = false
+		e.s.CancelReason = playstore.CRDeveloperCanceled
+		writeJSON(w, http.StatusOK, struct{}{})
+	case "defer":
+		var body struct {
+			Info playstore.DeferralInfo `json:"deferralInfo"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			playError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "badRequest", err.Error())
+			return
+		}
+
+		if body.Info.ExpectedTimeMillis != e.s.ExpiryTimeMillis {
+			playError(w, http.StatusConflict, "ABORTED", "expiryTimeMismatch", "The expected expiry time does not match.")
+			return
+		}
+
+		e.s.ExpiryTimeMillis = body.Info.DesiredTimeMillis
+		writeJSON(w, http.StatusOK, map[string]string{
+			"newExpiryTimeMillis": formatInt(e.s.ExpiryTimeMillis),
+		})
+	case "refund":
+		// The subscription stays valid until it expires.
+		writeJSON(w, http.StatusOK, struct{}{})
+	case "revoke":
+		s.void(token, playstore.VSDeveloper, playstore.VROther)
+		e.s.CancelReason = playstore.CRDeveloperCanceled
+		writeJSON(w, http.StatusOK, struct{}{})
+	default:
+		playError(w, http.StatusNotFound, "NOT_FOUND", "notFound", "Not found.")
+	}
+}
+
+func (s *PlayStore) serveOrder(w http.ResponseWriter, r *http.Request, pkg, orderID string) {
+	action := ""
+	if i := strings.LastIndex(orderID, ":"); i >= 0 {
+		orderID, action = orderID[:i], orderID[i+1:]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.order(pkg, orderID)
+	if !ok {
+		playNotFound(w)
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, o)
+	case action == "refund" && r.Method == http.MethodPost:
+		if s.refunded[orderID] {
+			playError(w, http.StatusBadRequest, "FAILED_PRECONDITION", "orderAlreadyRefunded", "The order has already been refunded.")
+			return
+		}
+
+		s.refunded[orderID] = true
+		if r.URL.Query().Get("revoke") == "true" {
+			s.void(o.PurchaseToken, playstore.VSDeveloper, playstore.VROther)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		playError(w, http.StatusNotFound, "NOT_FOUND", "notFound", "Not found.")
+	}
+}
+
+func (s *PlayStore) serveOrders(w http.ResponseWriter, r *http.Request, pkg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res struct {
+		Orders []playstore.Order `json:"orders"`
+	}
+
+	for _, id := range r.URL.Query()["orderIds"] {
+		o, ok := s.order(pkg, id)
+		if !ok {
+			playNotFound(w)
+			return
+		}
+
+		res.Orders = append(res.Orders, *o)
+	}
+
+	writeJSON(w, http.StatusOK, &res)
+}
+
+// order returns the order of the purchase with the given order ID.
+func (s *PlayStore) order(pkg, orderID string) (*playstore.Order, bool) {
+	o := &playstore.Order{
+		OrderID: orderID,
+		State:   playstore.OSTProcessed,
+	}
+
+	var purchaseMillis int64
+	found := false
+
+	for token, e := range s.products {
+		if e.pkg == pkg && e.p.OrderID == orderID {
+			o.PurchaseToken = token
+			o.LineItems = []playstore.OrderLineItem{{
+				ProductID:              e.prod,
+				OneTimePurchaseDetails: &playstore.OneTimePurchaseDetails{Quantity: 1},
+			}}
+			purchaseMillis = e.p.PurchaseTimeMillis
+			found = true
+		}
+	}
+
+	for token, e := range s.subscriptions {
+		if e.pkg == pkg && e.s.OrderID == orderID {
+			o.PurchaseToken = token
+			o.LineItems = []playstore.OrderLineItem{{
+				ProductID:           e.sub,
+				SubscriptionDetails: &playstore.SubscriptionDetails{},
+			}}
+			purchaseMillis = e.s.StartTimeMillis
+			found = true
+		}
+	}
+
+	if !found {
+		return nil, false
+	}
+
+	o.CreateTime = time.Unix(0, purchaseMillis*int64(time.Millisecond)).UTC()
+	o.LastEventTime = o.CreateTime
+	if s.refunded[orderID] {
+		o.State = playstore.OSTRefunded
+	}
+
+	return o, true
+}
+
+func (s *PlayStore) serveVoidedPurchases(w http.ResponseWriter, r *http.Request, pkg string) {
+	q := r.URL.Query()
+
+	start := parseMillis(q.Get("startTime"))
+	end := parseMillis(q.Get("endTime"))
+	subscriptions := q.Get("type") == "1"
+
+	maxResults := 1000
+	if n, err := strconv.Atoi(q.Get("maxResults")); err == nil && n > 0 {
+		maxResults = n
+	}
+
+	offset := 0
+	if t := q.Get("token"); t != "" {
+		n, err := strconv.Atoi(t)
+		if err != nil || n < 0 {
+			playError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "invalidPageToken", "The page token is invalid.")
+			return
+		}
+
+		offset = n
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var matched []playstore.VoidedPurchase
+	for _, v := range s.voided {
+		if v.pkg != pkg || (v.subscription && !subscriptions) {
+			continue
+		}
+
+		if (start != 0 && v.v.VoidedTimeMillis < start) || (end != 0 && v.v.VoidedTimeMillis > end) {
+			continue
+		}
+
+		matched = append(matched, v.v)
+	}
+
+	res := playstore.VoidedPurchasesResponse{
+		PageInfo: &playstore.PageInfo{
+			TotalResults:  len(matched),
+			ResultPerPage: maxResults,
+			StartIndex:    offset,
+		},
+	}
+
+	if offset < len(matched) {
+		matched = matched[offset:]
+		if len(matched) > maxResults {
+			matched = matched[:maxResults]
+			res.TokenPagination = &playstore.TokenPagination{
+				NextPageToken: strconv.Itoa(offset + maxResults),
+			}
+		}
+
+		res.VoidedPurchases = matched
+	}
+
+	writeJSON(w, http.StatusOK, &res)
+}
+
+func playNotFound(w http.ResponseWriter) {
+	playError(w, http.StatusNotFound, "NOT_FOUND", "purchaseTokenNotFound", "The purchase token was not found.")
+}
+
+// playError writes an error in the Google API error format.
+func playError(w http.ResponseWriter, code int, status, reason, message string) {
+	apiErr := &playstore.APIError{
+		Code:    code,
+		Message: message,
+		Status:  status,
+	}
+	if reason != "" {
+		apiErr.Errors = []playstore.APIErrorItem{{
+			Domain:  "androidpublisher",
+			Reason:  reason,
+			Message: message,
+		}}
+	}
+
+	writeJSON(w, code, map[string]interface{}{"error": apiErr})
+}
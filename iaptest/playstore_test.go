@@ -0,0 +1,303 @@
+package iaptest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/brainleap/iap"
+	"github.com/brainleap/iap/iaptest"
+	"github.com/brainleap/iap/playstore"
+)
+
+const testPackage = "com.example.app"
+
+func newPlayStore(t *testing.T) (*iaptest.PlayStore, *playstore.Client) {
+	t.Helper()
+
+	store := iaptest.NewPlayStore()
+	t.Cleanup(store.Close)
+
+	c, err := store.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return store, c
+}
+
+func TestPlayStoreProduct(t *testing.T) {
+	tests := []struct {
+		name      string
+		call      func(c *playstore.Client) error
+		wantErr   error
+		wantState func(p *playstore.Product) bool
+	}{
+		{
+			name: "get",
+			call: func(c *playstore.Client) error {
+				p, err := c.GetProduct(testPackage, "coins_100", "token")
+				if err == nil && p.OrderID != "GPA.1" {
+					t.Errorf("GetProduct() = %+v", p)
+				}
+				return err
+			},
+		},
+		{
+			name: "acknowledge",
+			call: func(c *playstore.Client) error {
+				return c.AcknowledgeProduct(testPackage, "coins_100", "token", playstore.DeveloperPayload("payload"))
+			},
+			wantState: func(p *playstore.Product) bool {
+				return p.AcknowledgementState == playstore.Acknowledged && p.DeveloperPayload == "payload"
+			},
+		},
+		{
+			name: "consume",
+			call: func(c *playstore.Client) error {
+				return c.ConsumeProduct(testPackage, "coins_100", "token")
+			},
+			wantState: func(p *playstore.Product) bool { return p.ConsumptionState == playstore.Consumed },
+		},
+		{
+			name: "consume twice",
+			call: func(c *playstore.Client) error {
+				if err := c.ConsumeProduct(testPackage, "coins_100", "token"); err != nil {
+					return err
+				}
+				return c.ConsumeProduct(testPackage, "coins_100", "token")
+			},
+			wantErr: iap.ErrInvalidToken,
+		},
+		{
+			name: "unknown token",
+			call: func(c *playstore.Client) error {
+				_, err := c.GetProduct(testPackage, "coins_100", "other")
+				return err
+			},
+			wantErr: iap.ErrPurchaseNotFound,
+		},
+		{
+			name: "other package",
+			call: func(c *playstore.Client) error {
+				_, err := c.GetProduct("com.example.other", "coins_100", "token")
+				return err
+			},
+			wantErr: iap.ErrPurchaseNotFound,
+		},
+		{
+			name: "other product",
+			call: func(c *playstore.Client) error {
+				_, err := c.GetProduct(testPackage, "coins_500", "token")
+				return err
+			},
+			wantErr: iap.ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, c := newPlayStore(t)
+			store.AddProduct(testPackage, "coins_100", "token", &playstore.Product{OrderID: "GPA.1"})
+
+			err := tt.call(c)
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantState != nil && !tt.wantState(store.Product("token")) {
+				t.Errorf("product = %+v", store.Product("token"))
+			}
+		})
+	}
+}
+
+func TestPlayStoreSubscription(t *testing.T) {
+	expiry := time.Now().Add(24*time.Hour).UnixNano() / int64(time.Millisecond)
+
+	tests := []struct {
+		name      string
+		call      func(store *iaptest.PlayStore, c *playstore.Client) error
+		wantErr   bool
+		wantIs    error
+		wantState func(s *playstore.Subscription) bool
+	}{
+		{
+			name: "cancel",
+			call: func(store *iaptest.PlayStore, c *playstore.Client) error {
+				return c.CancelSubscription(testPackage, "monthly", "token")
+			},
+			wantState: func(s *playstore.Subscription) bool {
+				return !s.AutoRenewing && s.CancelReason == playstore.CRDeveloperCanceled && s.ExpiryTimeMillis == expiry
+			},
+		},
+		{
+			name: "defer",
+			call: func(store *iaptest.PlayStore, c *playstore.Client) error {
+				got, err := c.DeferSubscription(testPackage, "monthly", "token", expiry, expiry+1000)
+				if err == nil && got != expiry+1000 {
+					t.Errorf("DeferSubscription() = %d, want %d", got, expiry+1000)
+				}
+				return err
+			},
+			wantState: func(s *playstore.Subscription) bool { return s.ExpiryTimeMillis == expiry+1000 },
+		},
+		{
+			name: "defer with stale expiry",
+			call: func(store *iaptest.PlayStore, c *playstore.Client) error {
+				_, err := c.DeferSubscription(testPackage, "monthly", "token", expiry-1000, expiry+1000)
+				return err
+			},
+			wantErr:   true,
+			wantState: func(s *playstore.Subscription) bool { return s.ExpiryTimeMillis == expiry },
+		},
+		{
+			name: "revoke",
+			call: func(store *iaptest.PlayStore, c *playstore.Client) error {
+				return c.RevokeSubscription(testPackage, "monthly", "token")
+			},
+			wantState: func(s *playstore.Subscription) bool {
+				return !s.AutoRenewing && s.CancelReason == playstore.CRDeveloperCanceled && s.ExpiryTimeMillis < expiry
+			},
+		},
+		{
+			name: "expire",
+			call: func(store *iaptest.PlayStore, c *playstore.Client) error {
+				store.ExpireSubscription("token")
+
+				s, err := c.GetSubscription(testPackage, "monthly", "token")
+				if err == nil && (s.AutoRenewing || s.CancelReason != playstore.CRSystemCanceled) {
+					t.Errorf("GetSubscription() = %+v", s)
+				}
+				return err
+			},
+		},
+		{
+			name: "other subscription",
+			call: func(store *iaptest.PlayStore, c *playstore.Client) error {
+				_, err := c.GetSubscription(testPackage, "yearly", "token")
+				return err
+			},
+			wantErr: true,
+			wantIs:  iap.ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, c := newPlayStore(t)
+			store.AddSubscription(testPackage, "monthly", "token", &playstore.Subscription{
+				OrderID:          "GPA.2",
+				ExpiryTimeMillis: expiry,
+				AutoRenewing:     true,
+			})
+
+			err := tt.call(store, c)
+			if (err != nil) != tt.wantErr || tt.wantIs != nil && !errors.Is(err, tt.wantIs) {
+				t.Fatalf("error = %v, want error %v (%v)", err, tt.wantErr, tt.wantIs)
+			}
+
+			if tt.wantState != nil && !tt.wantState(store.Subscription("token")) {
+				t.Errorf("subscription = %+v", store.Subscription("token"))
+			}
+		})
+	}
+}
+
+func TestPlayStoreSubscriptionV2(t *testing.T) {
+	store, c := newPlayStore(t)
+	store.AddSubscriptionV2(testPackage, "token", &playstore.SubscriptionPurchaseV2{
+		SubscriptionState: playstore.SSActive,
+		LineItems: []playstore.SubscriptionPurchaseLineItem{{
+			ProductID:        "monthly",
+			ExpiryTime:       time.Now().Add(24 * time.Hour),
+			AutoRenewingPlan: &playstore.AutoRenewingPlan{AutoRenewEnabled: true},
+		}},
+	})
+
+	store.SetSubscriptionState("token", playstore.SSInGracePeriod)
+
+	s, err := c.GetSubscriptionV2(testPackage, "token")
+	if err != nil {
+		t.Fatalf("GetSubscriptionV2() error = %v", err)
+	}
+	if s.SubscriptionState != playstore.SSInGracePeriod {
+		t.Errorf("state = %s, want %s", s.SubscriptionState, playstore.SSInGracePeriod)
+	}
+
+	store.ExpireSubscription("token")
+
+	s, err = c.GetSubscriptionV2(testPackage, "token")
+	if err != nil {
+		t.Fatalf("GetSubscriptionV2() error = %v", err)
+	}
+	if s.SubscriptionState != playstore.SSExpired || s.LineItems[0].AutoRenewingPlan.AutoRenewEnabled || s.LineItems[0].ExpiryTime.After(time.Now()) {
+		t.Errorf("expired subscription = %+v", s)
+	}
+
+	if _, err := c.GetSubscriptionV2("com.example.other", "token"); !errors.Is(err, iap.ErrPurchaseNotFound) {
+		t.Errorf("GetSubscriptionV2() other package error = %v, want %v", err, iap.ErrPurchaseNotFound)
+	}
+}
+
+func TestPlayStoreFailNext(t *testing.T) {
+	tests := []struct {
+		name          string
+		statusCode    int
+		body          string
+		wantErr       error
+		wantRetryable bool
+	}{
+		{name: "unavailable", statusCode: http.StatusServiceUnavailable, wantErr: iap.ErrUnavailable, wantRetryable: true},
+		{name: "too many requests", statusCode: http.StatusTooManyRequests, wantErr: iap.ErrRateLimited, wantRetryable: true},
+		{
+			name:          "quota exceeded",
+			statusCode:    http.StatusForbidden,
+			body:          `{"error":{"code":403,"message":"Quota exceeded.","errors":[{"reason":"quotaExceeded"}]}}`,
+			wantErr:       iap.ErrRateLimited,
+			wantRetryable: true,
+		},
+		{name: "forbidden", statusCode: http.StatusForbidden, wantErr: iap.ErrAuth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, c := newPlayStore(t)
+			store.AddProduct(testPackage, "coins_100", "token", &playstore.Product{})
+
+			// Fetch the access token before failing requests.
+			if _, err := c.GetProduct(testPackage, "coins_100", "token"); err != nil {
+				t.Fatal(err)
+			}
+
+			store.FailNext(1, tt.statusCode, tt.body)
+
+			_, err := c.GetProduct(testPackage, "coins_100", "token")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetProduct() error = %v, want %v", err, tt.wantErr)
+			}
+			if iap.IsRetryable(err) != tt.wantRetryable {
+				t.Errorf("IsRetryable() = %v, want %v", !tt.wantRetryable, tt.wantRetryable)
+			}
+
+			if _, err := c.GetProduct(testPackage, "coins_100", "token"); err != nil {
+				t.Errorf("GetProduct() after failure error = %v", err)
+			}
+		})
+	}
+}
+
+func TestPlayStoreSetLatency(t *testing.T) {
+	store, c := newPlayStore(t)
+	store.AddProduct(testPackage, "coins_100", "token", &playstore.Product{})
+	store.SetLatency(time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.GetProductContext(ctx, testPackage, "coins_100", "token"); err == nil {
+		t.Error("GetProductContext() succeeded, want deadline error")
+	}
+}
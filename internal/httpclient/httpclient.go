@@ -0,0 +1,57 @@
+// Package httpclient builds the HTTP clients used by the store packages from
+// their client options.
+package httpclient
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Config is the HTTP configuration shared by the client options of the store
+// packages.
+//
+// Proxy and TLSConfig configure the default transport and are ignored when a
+// custom Transport is set.
+type Config struct {
+	Transport http.RoundTripper
+	Timeout   time.Duration
+	UserAgent string
+	Proxy     *url.URL
+	TLSConfig *tls.Config
+}
+
+// Client creates an HTTP client from the config.
+func (c *Config) Client() *http.Client {
+	rt := c.Transport
+	if rt == nil {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		if c.Proxy != nil {
+			t.Proxy = http.ProxyURL(c.Proxy)
+		}
+		if c.TLSConfig != nil {
+			t.TLSClientConfig = c.TLSConfig
+		}
+
+		rt = t
+	}
+
+	if c.UserAgent != "" {
+		rt = &userAgentTransport{userAgent: c.UserAgent, base: rt}
+	}
+
+	return &http.Client{Transport: rt, Timeout: c.Timeout}
+}
+
+type userAgentTransport struct {
+	userAgent string
+	base      http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("User-Agent", t.userAgent)
+
+	return t.base.RoundTrip(r)
+}
@@ -0,0 +1,63 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Option configures a Config. The store packages wrap options into their
+// own client options.
+type Option func(*Config)
+
+// WithTransport sets the transport requests are sent with.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Config) {
+		c.Transport = rt
+	}
+}
+
+// WithTimeout sets the timeout of requests.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.Timeout = d
+	}
+}
+
+// WithUserAgent sets the User-Agent header of requests.
+func WithUserAgent(ua string) Option {
+	return func(c *Config) {
+		c.UserAgent = ua
+	}
+}
+
+// WithProxy sets the proxy of the default transport.
+func WithProxy(proxy *url.URL) Option {
+	return func(c *Config) {
+		c.Proxy = proxy
+	}
+}
+
+// WithTLSConfig sets the TLS configuration of the default transport.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(c *Config) {
+		c.TLSConfig = cfg
+	}
+}
+
+// WithRootCAs sets the root certificates of the default transport. A TLS
+// configuration set before is cloned rather than modified, as it belongs to
+// the caller.
+func WithRootCAs(roots *x509.CertPool) Option {
+	return func(c *Config) {
+		cfg := &tls.Config{}
+		if c.TLSConfig != nil {
+			cfg = c.TLSConfig.Clone()
+		}
+
+		cfg.RootCAs = roots
+		c.TLSConfig = cfg
+	}
+}
@@ -0,0 +1,113 @@
+package iap
+
+import "time"
+
+// Lifecycle is the data type for normalized subscription lifecycle states.
+type Lifecycle int
+
+// List of subscription lifecycle states.
+const (
+	LifecycleUnknown     Lifecycle = 0
+	LifecycleActive      Lifecycle = 1
+	LifecycleTrial       Lifecycle = 2
+	LifecycleIntroOffer  Lifecycle = 3
+	LifecycleGracePeriod Lifecycle = 4
+	LifecycleAccountHold Lifecycle = 5
+	LifecyclePaused      Lifecycle = 6
+	LifecycleCanceled    Lifecycle = 7
+	LifecycleExpired     Lifecycle = 8
+	LifecycleRefunded    Lifecycle = 9
+	LifecycleRevoked     Lifecycle = 10
+)
+
+var lifecycleNames = map[Lifecycle]string{
+	LifecycleUnknown:     "unknown",
+	LifecycleActive:      "active",
+	LifecycleTrial:       "trial",
+	LifecycleIntroOffer:  "intro_offer",
+	LifecycleGracePeriod: "grace_period",
+	LifecycleAccountHold: "account_hold",
+	LifecyclePaused:      "paused",
+	LifecycleCanceled:    "canceled",
+	LifecycleExpired:     "expired",
+	LifecycleRefunded:    "refunded",
+	LifecycleRevoked:     "revoked",
+}
+
+// lifecycleTransitions lists the states each state can move to. Refunded and
+// revoked subscriptions are final; a new purchase starts a new lifecycle.
+var lifecycleTransitions = map[Lifecycle][]Lifecycle{
+	LifecycleActive: {
+		LifecycleActive, LifecycleGracePeriod, LifecycleAccountHold, LifecyclePaused,
+		LifecycleCanceled, LifecycleExpired, LifecycleRefunded, LifecycleRevoked,
+	},
+	LifecycleTrial: {
+		LifecycleActive, LifecycleIntroOffer, LifecycleGracePeriod, LifecycleAccountHold,
+		LifecycleCanceled, LifecycleExpired, LifecycleRevoked,
+	},
+	LifecycleIntroOffer: {
+		LifecycleActive, LifecycleIntroOffer, LifecycleGracePeriod, LifecycleAccountHold,
+		LifecycleCanceled, LifecycleExpired, LifecycleRefunded, LifecycleRevoked,
+	},
+	LifecycleGracePeriod: {
+		LifecycleActive, LifecycleAccountHold, LifecycleCanceled, LifecycleExpired, LifecycleRevoked,
+	},
+	LifecycleAccountHold: {
+		LifecycleActive, LifecycleExpired, LifecycleRevoked,
+	},
+	LifecyclePaused: {
+		LifecycleActive, LifecycleExpired, LifecycleRevoked,
+	},
+	LifecycleCanceled: {
+		LifecycleActive, LifecycleExpired, LifecycleRefunded, LifecycleRevoked,
+	},
+	LifecycleExpired: {
+		LifecycleActive, LifecycleRefunded,
+	},
+}
+
+// String returns the name of the state.
+func (l Lifecycle) String() string {
+	if name, ok := lifecycleNames[l]; ok {
+		return name
+	}
+
+	return "unknown"
+}
+
+// Entitled reports whether a subscription in the state grants access.
+func (l Lifecycle) Entitled() bool {
+	switch l {
+	case LifecycleActive, LifecycleTrial, LifecycleIntroOffer, LifecycleGracePeriod, LifecycleCanceled:
+		return true
+	}
+
+	return false
+}
+
+// Transitions returns the states a subscription in the state can move to.
+func (l Lifecycle) Transitions() []Lifecycle {
+	return append([]Lifecycle(nil), lifecycleTransitions[l]...)
+}
+
+// CanTransition reports whether a subscription can move from l to next.
+func (l Lifecycle) CanTransition(next Lifecycle) bool {
+	for _, t := range lifecycleTransitions[l] {
+		if t == next {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SubscriptionStatus is the classified lifecycle state of a subscription.
+//
+// NextChange is when the state is next expected to change: the renewal or
+// expiry time of an entitled subscription, the end of a grace period or the
+// resume time of a paused subscription. It is zero when no change is
+// scheduled.
+type SubscriptionStatus struct {
+	Lifecycle  Lifecycle
+	NextChange time.Time
+}
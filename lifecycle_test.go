@@ -0,0 +1,75 @@
+package iap
+
+import "testing"
+
+func TestLifecycle(t *testing.T) {
+	tests := []struct {
+		lifecycle    Lifecycle
+		wantName     string
+		wantEntitled bool
+	}{
+		{LifecycleUnknown, "unknown", false},
+		{LifecycleActive, "active", true},
+		{LifecycleTrial, "trial", true},
+		{LifecycleIntroOffer, "intro_offer", true},
+		{LifecycleGracePeriod, "grace_period", true},
+		{LifecycleAccountHold, "account_hold", false},
+		{LifecyclePaused, "paused", false},
+		{LifecycleCanceled, "canceled", true},
+		{LifecycleExpired, "expired", false},
+		{LifecycleRefunded, "refunded", false},
+		{LifecycleRevoked, "revoked", false},
+		{Lifecycle(99), "unknown", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.wantName, func(t *testing.T) {
+			if got := tt.lifecycle.String(); got != tt.wantName {
+				t.Errorf("String() = %q, want %q", got, tt.wantName)
+			}
+
+			if got := tt.lifecycle.Entitled(); got != tt.wantEntitled {
+				t.Errorf("Entitled() = %v, want %v", got, tt.wantEntitled)
+			}
+		})
+	}
+}
+
+func TestLifecycleCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to Lifecycle
+		want     bool
+	}{
+		{LifecycleActive, LifecycleCanceled, true},
+		{LifecycleActive, LifecycleRevoked, true},
+		{LifecycleTrial, LifecycleActive, true},
+		{LifecycleTrial, LifecycleRefunded, false},
+		{LifecycleGracePeriod, LifecycleAccountHold, true},
+		{LifecycleAccountHold, LifecycleGracePeriod, false},
+		{LifecycleCanceled, LifecycleActive, true},
+		{LifecycleExpired, LifecycleActive, true},
+		{LifecycleExpired, LifecycleCanceled, false},
+		{LifecycleRefunded, LifecycleActive, false},
+		{LifecycleRevoked, LifecycleActive, false},
+		{LifecycleUnknown, LifecycleActive, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.from.CanTransition(tt.to); got != tt.want {
+			t.Errorf("%s.CanTransition(%s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestLifecycleTransitions(t *testing.T) {
+	got := LifecycleExpired.Transitions()
+	got[0] = LifecycleRevoked
+
+	if LifecycleExpired.CanTransition(LifecycleRevoked) {
+		t.Error("modifying Transitions() changed the transitions")
+	}
+
+	if len(LifecycleRefunded.Transitions()) != 0 {
+		t.Errorf("refunded transitions = %v, want none", LifecycleRefunded.Transitions())
+	}
+}
@@ -0,0 +1,184 @@
+package playstore
+
+import (
+	"context"
+	"sort"
+)
+
+// CatalogPlan is the set of changes that turn a live in-app product catalog
+// into a desired one.
+type CatalogPlan struct {
+	Insert []InAppProduct
+	Update []CatalogChange
+	Delete []string
+}
+
+// CatalogChange is an in-app product that differs from its live version.
+// Fields lists the JSON names of the differing fields.
+type CatalogChange struct {
+	Product InAppProduct
+	Fields  []string
+}
+
+// Empty reports whether the plan has no changes.
+func (p *CatalogPlan) Empty() bool {
+	return len(p.Insert) == 0 && len(p.Update) == 0 && len(p.Delete) == 0
+}
+
+// DiffCatalog compares a desired in-app product catalog with the live one.
+//
+// Products are matched by SKU. If prune is set, live products missing from
+// the desired catalog are planned for deletion, except legacy subscription
+// products, which the catalog lists too. Regional prices are only compared
+// for the regions of the desired product, since the live catalog may carry
+// prices converted from the default price. The status, purchase type and
+// default language are only compared when set in the desired product, and
+// are kept from the live product otherwise.
+func DiffCatalog(desired, live []InAppProduct, prune bool) *CatalogPlan {
+	liveBySKU := make(map[string]*InAppProduct, len(live))
+	for i := range live {
+		liveBySKU[live[i].SKU] = &live[i]
+	}
+
+	plan := &CatalogPlan{}
+	seen := make(map[string]bool, len(desired))
+
+	for _, d := range desired {
+		seen[d.SKU] = true
+
+		l, ok := liveBySKU[d.SKU]
+		if !ok {
+			plan.Insert = append(plan.Insert, d)
+			continue
+		}
+
+		if fields := diffInAppProduct(&d, l); len(fields) > 0 {
+			plan.Update = append(plan.Update, CatalogChange{Product: mergeInAppProduct(d, l), Fields: fields})
+		}
+	}
+
+	if prune {
+		for _, l := range live {
+			if !seen[l.SKU] && l.PurchaseType != IPTSubscription {
+				plan.Delete = append(plan.Delete, l.SKU)
+			}
+		}
+	}
+
+	sort.Strings(plan.Delete)
+
+	return plan
+}
+
+func diffInAppProduct(d, l *InAppProduct) []string {
+	var fields []string
+
+	add := func(name string, equal bool) {
+		if !equal {
+			fields = append(fields, name)
+		}
+	}
+
+	add("status", d.Status == "" || d.Status == l.Status)
+	add("purchaseType", d.PurchaseType == "" || d.PurchaseType == l.PurchaseType)
+	add("defaultPrice", (d.DefaultPrice == nil) == (l.DefaultPrice == nil) && (d.DefaultPrice == nil || *d.DefaultPrice == *l.DefaultPrice))
+	add("prices", pricesIncluded(d.Prices, l.Prices))
+	add("listings", listingsEqual(d.Listings, l.Listings))
+	add("defaultLanguage", d.DefaultLanguage == "" || d.DefaultLanguage == l.DefaultLanguage)
+	add("subscriptionPeriod", d.SubscriptionPeriod == l.SubscriptionPeriod)
+	add("trialPeriod", d.TrialPeriod == l.TrialPeriod)
+	add("gracePeriod", d.GracePeriod == l.GracePeriod)
+
+	return fields
+}
+
+// mergeInAppProduct fills the fields DiffCatalog does not compare when unset
+// from the live product, so that updating the product keeps them.
+func mergeInAppProduct(d InAppProduct, l *InAppProduct) InAppProduct {
+	if d.Status == "" {
+		d.Status = l.Status
+	}
+	if d.PurchaseType == "" {
+		d.PurchaseType = l.PurchaseType
+	}
+	if d.DefaultLanguage == "" {
+		d.DefaultLanguage = l.DefaultLanguage
+	}
+
+	return d
+}
+
+func listingsEqual(a, b map[string]InAppProductListing) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for lang, la := range a {
+		lb, ok := b[lang]
+		if !ok || la.Title != lb.Title || la.Description != lb.Description {
+			return false
+		}
+
+		if len(la.Benefits) != len(lb.Benefits) {
+			return false
+		}
+
+		for i := range la.Benefits {
+			if la.Benefits[i] != lb.Benefits[i] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// pricesIncluded reports whether every price of desired is set in live.
+func pricesIncluded(desired, live map[string]Price) bool {
+	for region, p := range desired {
+		if lp, ok := live[region]; !ok || lp != p {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ApplyCatalogPlan applies a catalog plan to a package. Updates are sent as
+// one batch. The options apply to inserts and updates, as for
+// UpdateInAppProduct, and LatencyTolerance to deletes as well.
+func (c *Client) ApplyCatalogPlan(pkg string, plan *CatalogPlan, opts ...Option) error {
+	return c.ApplyCatalogPlanContext(context.Background(), pkg, plan, opts...)
+}
+
+// ApplyCatalogPlanContext is like ApplyCatalogPlan but takes a context.
+func (c *Client) ApplyCatalogPlanContext(ctx context.Context, pkg string, plan *CatalogPlan, opts ...Option) error {
+	for i := range plan.Insert {
+		p := plan.Insert[i]
+		p.PackageName = pkg
+
+		if _, err := c.InsertInAppProductContext(ctx, pkg, &p, opts...); err != nil {
+			return err
+		}
+	}
+
+	if len(plan.Update) > 0 {
+		products := make([]InAppProduct, len(plan.Update))
+		for i, u := range plan.Update {
+			products[i] = u.Product
+			products[i].PackageName = pkg
+		}
+
+		if _, err := c.BatchUpdateInAppProductsContext(ctx, pkg, products, opts...); err != nil {
+			return err
+		}
+	}
+
+	for _, sku := range plan.Delete {
+		if err := c.DeleteInAppProductContext(ctx, pkg, sku, opts...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
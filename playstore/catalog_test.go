@@ -0,0 +1,194 @@
+package playstore
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestDiffCatalog(t *testing.T) {
+	price := &Price{PriceMicros: 990000, Currency: "USD"}
+	listings := map[string]InAppProductListing{"en-US": {Title: "100 coins", Description: "A pile of coins"}}
+
+	product := func(sku string) InAppProduct {
+		return InAppProduct{
+			SKU:             sku,
+			Status:          IPSActive,
+			PurchaseType:    IPTManagedUser,
+			DefaultPrice:    price,
+			Prices:          map[string]Price{"US": *price, "DE": {PriceMicros: 990000, Currency: "EUR"}},
+			Listings:        listings,
+			DefaultLanguage: "en-US",
+		}
+	}
+
+	live := []InAppProduct{
+		product("coins_100"),
+		product("coins_500"),
+		{SKU: "legacy_monthly", Status: IPSActive, PurchaseType: IPTSubscription, SubscriptionPeriod: "P1M"},
+	}
+
+	tests := []struct {
+		name       string
+		desired    []InAppProduct
+		prune      bool
+		wantInsert []string
+		wantUpdate map[string][]string
+		wantDelete []string
+	}{
+		{
+			name:    "unchanged",
+			desired: []InAppProduct{product("coins_100"), product("coins_500")},
+		},
+		{
+			name: "unset fields",
+			desired: []InAppProduct{func() InAppProduct {
+				p := product("coins_100")
+				p.Status, p.PurchaseType, p.DefaultLanguage = "", "", ""
+				return p
+			}()},
+		},
+		{
+			name: "subset of regional prices",
+			desired: []InAppProduct{func() InAppProduct {
+				p := product("coins_100")
+				p.Prices = map[string]Price{"US": *price}
+				return p
+			}()},
+		},
+		{
+			name: "changed fields",
+			desired: []InAppProduct{func() InAppProduct {
+				p := product("coins_100")
+				p.Status = IPSInactive
+				p.DefaultPrice = &Price{PriceMicros: 1990000, Currency: "USD"}
+				p.Listings = map[string]InAppProductListing{"en-US": {Title: "100 gold coins", Description: "A pile of coins"}}
+				return p
+			}()},
+			wantUpdate: map[string][]string{"coins_100": {"status", "defaultPrice", "listings"}},
+		},
+		{
+			name: "changed regional price",
+			desired: []InAppProduct{func() InAppProduct {
+				p := product("coins_100")
+				p.Prices = map[string]Price{"DE": {PriceMicros: 1090000, Currency: "EUR"}}
+				return p
+			}()},
+			wantUpdate: map[string][]string{"coins_100": {"prices"}},
+		},
+		{
+			name:       "new product",
+			desired:    []InAppProduct{product("coins_100"), product("coins_500"), product("coins_1000")},
+			wantInsert: []string{"coins_1000"},
+		},
+		{
+			name:    "missing product",
+			desired: []InAppProduct{product("coins_100")},
+		},
+		{
+			name:       "missing product pruned",
+			desired:    []InAppProduct{product("coins_100")},
+			prune:      true,
+			wantDelete: []string{"coins_500"},
+		},
+		{
+			name:       "empty catalog pruned",
+			prune:      true,
+			wantDelete: []string{"coins_100", "coins_500"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := DiffCatalog(tt.desired, live, tt.prune)
+
+			var insert []string
+			for _, p := range plan.Insert {
+				insert = append(insert, p.SKU)
+			}
+
+			var update map[string][]string
+			for _, u := range plan.Update {
+				if update == nil {
+					update = make(map[string][]string)
+				}
+				update[u.Product.SKU] = u.Fields
+			}
+
+			if !reflect.DeepEqual(insert, tt.wantInsert) {
+				t.Errorf("Insert = %v, want %v", insert, tt.wantInsert)
+			}
+			if !reflect.DeepEqual(update, tt.wantUpdate) {
+				t.Errorf("Update = %v, want %v", update, tt.wantUpdate)
+			}
+			if !reflect.DeepEqual(plan.Delete, tt.wantDelete) {
+				t.Errorf("Delete = %v, want %v", plan.Delete, tt.wantDelete)
+			}
+
+			if plan.Empty() != (insert == nil && update == nil && tt.wantDelete == nil) {
+				t.Errorf("Empty() = %v", plan.Empty())
+			}
+		})
+	}
+}
+
+func TestDiffCatalogKeepsUnsetFields(t *testing.T) {
+	live := []InAppProduct{{SKU: "coins_100", Status: IPSInactive, PurchaseType: IPTManagedUser, DefaultLanguage: "de-DE"}}
+	desired := []InAppProduct{{SKU: "coins_100", DefaultPrice: &Price{PriceMicros: 990000, Currency: "EUR"}}}
+
+	plan := DiffCatalog(desired, live, false)
+	if len(plan.Update) != 1 {
+		t.Fatalf("Update = %+v, want one change", plan.Update)
+	}
+
+	p := plan.Update[0].Product
+	if p.Status != IPSInactive || p.PurchaseType != IPTManagedUser || p.DefaultLanguage != "de-DE" {
+		t.Errorf("updated product = %+v, want live status, purchase type and default language", p)
+	}
+}
+
+func TestApplyCatalogPlan(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery)
+		mu.Unlock()
+
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	c := &Client{Client: srv.Client(), BaseURL: srv.URL}
+
+	plan := &CatalogPlan{
+		Insert: []InAppProduct{{SKU: "coins_1000"}},
+		Update: []CatalogChange{{Product: InAppProduct{SKU: "coins_100"}, Fields: []string{"status"}}},
+		Delete: []string{"coins_500"},
+	}
+
+	if err := c.ApplyCatalogPlan("com.example.app", plan, LTTolerant); err != nil {
+		t.Fatalf("ApplyCatalogPlan() error = %v", err)
+	}
+
+	// Insert, batch update and delete.
+	if len(requests) != 3 {
+		t.Fatalf("requests = %v, want 3", requests)
+	}
+
+	want := "DELETE /applications/com.example.app/inappproducts/coins_500?latencyTolerance=" + string(LTTolerant)
+	if requests[2] != want {
+		t.Errorf("delete request = %q, want %q", requests[2], want)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 
@@ -13,8 +14,8 @@ import (
 )
 
 const (
-	baseURL   = "https://www.googleapis.com/androidpublisher/v3"
-	authScope = "https://www.googleapis.com/auth/androidpublisher"
+	defaultBaseURL = "https://www.googleapis.com/androidpublisher/v3"
+	authScope      = "https://www.googleapis.com/auth/androidpublisher"
 )
 
 // Option is the common type for optional arguments.
@@ -37,43 +38,66 @@ func getDeveloperPayload(opts []Option) string {
 	return ""
 }
 
-// NewClient creates a new PlayStore client.
-func NewClient(jsonKey []byte) (*Client, error) {
-	return NewClientWithProxy(jsonKey, "")
+// NewClient creates a new PlayStore client authenticating with the given
+// service account key.
+func NewClient(jsonKey []byte, opts ...ClientOption) (*Client, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	conf, err := google.JWTConfigFromJSON(jsonKey, authScope)
+	if err != nil {
+		return nil, err
+	}
+
+	hc := o.Client()
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, hc)
+
+	c := conf.Client(ctx)
+	c.Timeout = hc.Timeout
+
+	return &Client{Client: c, BaseURL: o.baseURL}, nil
 }
 
 // NewClientWithProxy creates a new PlayStore client with a proxy.
+//
+// Deprecated: Use NewClient with WithProxy.
 func NewClientWithProxy(jsonKey []byte, proxy string) (*Client, error) {
-	c := &http.Client{}
-
 	if proxy == "" {
-		proxyURL, err := url.Parse(proxy)
-		if err != nil {
-			return nil, err
-		}
-
-		c.Transport = &http.Transport{
-			Proxy: http.ProxyURL(proxyURL),
-		}
+		return NewClient(jsonKey)
 	}
 
-	conf, err := google.JWTConfigFromJSON(jsonKey, authScope)
+	proxyURL, err := url.Parse(proxy)
 	if err != nil {
 		return nil, err
 	}
 
-	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, c)
-
-	return &Client{Client: conf.Client(ctx)}, nil
+	return NewClient(jsonKey, WithProxy(proxyURL))
 }
 
-// Client provides PlayStore in-app billing API.
+// Client provides PlayStore in-app billing API. BaseURL defaults to the
+// Android Publisher API.
 type Client struct {
-	Client *http.Client
+	Client  *http.Client
+	BaseURL string
+}
+
+func (c *Client) baseURL() string {
+	if c.BaseURL != "" {
+		return c.BaseURL
+	}
+
+	return defaultBaseURL
 }
 
 // AcknowledgeProduct acknowledges purchase of an in-app product.
 func (c *Client) AcknowledgeProduct(pkg, prod, token string, opts ...Option) error {
+	return c.AcknowledgeProductContext(context.Background(), pkg, prod, token, opts...)
+}
+
+// AcknowledgeProductContext is like AcknowledgeProduct but takes a context.
+func (c *Client) AcknowledgeProductContext(ctx context.Context, pkg, prod, token string, opts ...Option) error {
 	body := struct {
 		DeveloperPayload string `json:"developerPayload"`
 	}{
@@ -87,13 +111,13 @@ func (c *Client) AcknowledgeProduct(pkg, prod, token string, opts ...Option) err
 
 	url := fmt.Sprintf(
 		"%s/applications/%s/purchases/products/%s/tokens/%s:acknowledge",
-		baseURL,
+		c.baseURL(),
 		url.PathEscape(pkg),
 		url.PathEscape(prod),
 		url.PathEscape(token),
 	)
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(reqBody))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(reqBody))
 	if err != nil {
 		return err
 	}
@@ -105,7 +129,7 @@ func (c *Client) AcknowledgeProduct(pkg, prod, token string, opts ...Option) err
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed with status: %d", res.StatusCode)
+		return newError(res)
 	}
 
 	return nil
@@ -113,41 +137,84 @@ func (c *Client) AcknowledgeProduct(pkg, prod, token string, opts ...Option) err
 
 // GetProduct checks the purchase and consumption status of an in-app product.
 func (c *Client) GetProduct(pkg, prod, token string) (*Product, error) {
+	return c.GetProductContext(context.Background(), pkg, prod, token)
+}
+
+// GetProductContext is like GetProduct but takes a context.
+func (c *Client) GetProductContext(ctx context.Context, pkg, prod, token string) (*Product, error) {
+	p, _, err := c.getProduct(ctx, pkg, prod, token)
+	return p, err
+}
+
+// getProduct is like GetProductContext but also reports whether the purchase
+// was made by a license tester. The API omits purchaseType for regular
+// purchases, which cannot be told apart from PTTest once decoded.
+func (c *Client) getProduct(ctx context.Context, pkg, prod, token string) (*Product, bool, error) {
 	url := fmt.Sprintf(
 		"%s/applications/%s/purchases/products/%s/tokens/%s",
-		baseURL,
+		c.baseURL(),
 		url.PathEscape(pkg),
 		url.PathEscape(prod),
 		url.PathEscape(token),
 	)
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	var p struct {
+		Product
+		PurchaseType *PurchaseType `json:"purchaseType"`
+	}
+	if err := c.do(ctx, http.MethodGet, url, nil, &p); err != nil {
+		return nil, false, err
+	}
+
+	if p.PurchaseType != nil {
+		p.Product.PurchaseType = *p.PurchaseType
+	}
+
+	return &p.Product, isTest(p.PurchaseType), nil
+}
+
+// ConsumeProduct consumes a purchase of an in-app product, so that it can be
+// purchased again.
+func (c *Client) ConsumeProduct(pkg, prod, token string) error {
+	return c.ConsumeProductContext(context.Background(), pkg, prod, token)
+}
+
+// ConsumeProductContext is like ConsumeProduct but takes a context.
+func (c *Client) ConsumeProductContext(ctx context.Context, pkg, prod, token string) error {
+	url := fmt.Sprintf(
+		"%s/applications/%s/purchases/products/%s/tokens/%s:consume",
+		c.baseURL(),
+		url.PathEscape(pkg),
+		url.PathEscape(prod),
+		url.PathEscape(token),
+	)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	res, err := c.Client.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed with status: %d", res.StatusCode)
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent {
+		return newError(res)
 	}
 
-	var p Product
-
-	decoder := json.NewDecoder(res.Body)
-	if err := decoder.Decode(&p); err != nil {
-		return nil, err
-	}
-
-	return &p, nil
+	return nil
 }
 
 // AcknowledgeSubscription acknowledges a subscription purchase.
 func (c *Client) AcknowledgeSubscription(pkg, sub, token string, opts ...Option) error {
+	return c.AcknowledgeSubscriptionContext(context.Background(), pkg, sub, token, opts...)
+}
+
+// AcknowledgeSubscriptionContext is like AcknowledgeSubscription but takes a
+// context.
+func (c *Client) AcknowledgeSubscriptionContext(ctx context.Context, pkg, sub, token string, opts ...Option) error {
 	body := struct {
 		DeveloperPayload string `json:"developerPayload"`
 	}{
@@ -161,13 +228,13 @@ func (c *Client) AcknowledgeSubscription(pkg, sub, token string, opts ...Option)
 
 	url := fmt.Sprintf(
 		"%s/applications/%s/purchases/subscriptions/%s/tokens/%s:acknowledge",
-		baseURL,
+		c.baseURL(),
 		url.PathEscape(pkg),
 		url.PathEscape(sub),
 		url.PathEscape(token),
 	)
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(reqBody))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(reqBody))
 	if err != nil {
 		return err
 	}
@@ -179,7 +246,7 @@ func (c *Client) AcknowledgeSubscription(pkg, sub, token string, opts ...Option)
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed with status: %d", res.StatusCode)
+		return newError(res)
 	}
 
 	return nil
@@ -187,15 +254,20 @@ func (c *Client) AcknowledgeSubscription(pkg, sub, token string, opts ...Option)
 
 // CancelSubscription cancels a subscription purchase.
 func (c *Client) CancelSubscription(pkg, sub, token string) error {
+	return c.CancelSubscriptionContext(context.Background(), pkg, sub, token)
+}
+
+// CancelSubscriptionContext is like CancelSubscription but takes a context.
+func (c *Client) CancelSubscriptionContext(ctx context.Context, pkg, sub, token string) error {
 	url := fmt.Sprintf(
 		"%s/applications/%s/purchases/subscriptions/%s/tokens/%s:cancel",
-		baseURL,
+		c.baseURL(),
 		url.PathEscape(pkg),
 		url.PathEscape(sub),
 		url.PathEscape(token),
 	)
 
-	req, err := http.NewRequest(http.MethodPost, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
 	if err != nil {
 		return err
 	}
@@ -207,7 +279,7 @@ func (c *Client) CancelSubscription(pkg, sub, token string) error {
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed with status: %d", res.StatusCode)
+		return newError(res)
 	}
 
 	return nil
@@ -215,6 +287,11 @@ func (c *Client) CancelSubscription(pkg, sub, token string) error {
 
 // DeferSubscription defers a subscription purchase.
 func (c *Client) DeferSubscription(pkg, sub, token string, expected, desired int64) (int64, error) {
+	return c.DeferSubscriptionContext(context.Background(), pkg, sub, token, expected, desired)
+}
+
+// DeferSubscriptionContext is like DeferSubscription but takes a context.
+func (c *Client) DeferSubscriptionContext(ctx context.Context, pkg, sub, token string, expected, desired int64) (int64, error) {
 	body := struct {
 		Info *DeferralInfo `json:"deferralInfo"`
 	}{
@@ -231,13 +308,13 @@ func (c *Client) DeferSubscription(pkg, sub, token string, expected, desired int
 
 	url := fmt.Sprintf(
 		"%s/applications/%s/purchases/subscriptions/%s/tokens/%s:defer",
-		baseURL,
+		c.baseURL(),
 		url.PathEscape(pkg),
 		url.PathEscape(sub),
 		url.PathEscape(token),
 	)
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(reqBody))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(reqBody))
 	if err != nil {
 		return 0, err
 	}
@@ -249,11 +326,11 @@ func (c *Client) DeferSubscription(pkg, sub, token string, expected, desired int
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("failed with status: %d", res.StatusCode)
+		return 0, newError(res)
 	}
 
 	var respBody struct {
-		NewTimeMillis int64 `json:"newExpiryTimeMillis"`
+		NewTimeMillis int64 `json:"newExpiryTimeMillis,string"`
 	}
 
 	decoder := json.NewDecoder(res.Body)
@@ -266,15 +343,20 @@ func (c *Client) DeferSubscription(pkg, sub, token string, expected, desired int
 
 // RefundSubscription refunds a subscription purchase.
 func (c *Client) RefundSubscription(pkg, sub, token string) error {
+	return c.RefundSubscriptionContext(context.Background(), pkg, sub, token)
+}
+
+// RefundSubscriptionContext is like RefundSubscription but takes a context.
+func (c *Client) RefundSubscriptionContext(ctx context.Context, pkg, sub, token string) error {
 	url := fmt.Sprintf(
 		"%s/applications/%s/purchases/subscriptions/%s/tokens/%s:refund",
-		baseURL,
+		c.baseURL(),
 		url.PathEscape(pkg),
 		url.PathEscape(sub),
 		url.PathEscape(token),
 	)
 
-	req, err := http.NewRequest(http.MethodPost, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
 	if err != nil {
 		return err
 	}
@@ -286,7 +368,7 @@ func (c *Client) RefundSubscription(pkg, sub, token string) error {
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed with status: %d", res.StatusCode)
+		return newError(res)
 	}
 
 	return nil
@@ -294,15 +376,20 @@ func (c *Client) RefundSubscription(pkg, sub, token string) error {
 
 // RevokeSubscription revokes a subscription purchase.
 func (c *Client) RevokeSubscription(pkg, sub, token string) error {
+	return c.RevokeSubscriptionContext(context.Background(), pkg, sub, token)
+}
+
+// RevokeSubscriptionContext is like RevokeSubscription but takes a context.
+func (c *Client) RevokeSubscriptionContext(ctx context.Context, pkg, sub, token string) error {
 	url := fmt.Sprintf(
 		"%s/applications/%s/purchases/subscriptions/%s/tokens/%s:revoke",
-		baseURL,
+		c.baseURL(),
 		url.PathEscape(pkg),
 		url.PathEscape(sub),
 		url.PathEscape(token),
 	)
 
-	req, err := http.NewRequest(http.MethodPost, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
 	if err != nil {
 		return err
 	}
@@ -314,7 +401,7 @@ func (c *Client) RevokeSubscription(pkg, sub, token string) error {
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed with status: %d", res.StatusCode)
+		return newError(res)
 	}
 
 	return nil
@@ -322,35 +409,81 @@ func (c *Client) RevokeSubscription(pkg, sub, token string) error {
 
 // GetSubscription checks the purchase and consumption status of a subscription.
 func (c *Client) GetSubscription(pkg, sub, token string) (*Subscription, error) {
+	return c.GetSubscriptionContext(context.Background(), pkg, sub, token)
+}
+
+// GetSubscriptionContext is like GetSubscription but takes a context.
+func (c *Client) GetSubscriptionContext(ctx context.Context, pkg, sub, token string) (*Subscription, error) {
+	s, _, err := c.getSubscription(ctx, pkg, sub, token)
+	return s, err
+}
+
+// getSubscription is like GetSubscriptionContext but also reports whether the
+// purchase was made by a license tester.
+func (c *Client) getSubscription(ctx context.Context, pkg, sub, token string) (*Subscription, bool, error) {
 	url := fmt.Sprintf(
 		"%s/applications/%s/purchases/subscriptions/%s/tokens/%s",
-		baseURL,
+		c.baseURL(),
 		url.PathEscape(pkg),
 		url.PathEscape(sub),
 		url.PathEscape(token),
 	)
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	var s struct {
+		Subscription
+		PurchaseType *PurchaseType `json:"purchaseType"`
+	}
+	if err := c.do(ctx, http.MethodGet, url, nil, &s); err != nil {
+		return nil, false, err
+	}
+
+	if s.PurchaseType != nil {
+		s.Subscription.PurchaseType = *s.PurchaseType
+	}
+
+	return &s.Subscription, isTest(s.PurchaseType), nil
+}
+
+func isTest(t *PurchaseType) bool {
+	return t != nil && *t == PTTest
+}
+
+// do sends a JSON request to the Android Publisher API and decodes the
+// response into out unless it is nil.
+func (c *Client) do(ctx context.Context, method, url string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		reqBody, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+
+		body = bytes.NewReader(reqBody)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
 	}
 
 	res, err := c.Client.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed with status: %d", res.StatusCode)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return newError(res)
 	}
 
-	var s Subscription
-
-	decoder := json.NewDecoder(res.Body)
-	if err := decoder.Decode(&s); err != nil {
-		return nil, err
+	if out == nil || res.StatusCode == http.StatusNoContent {
+		return nil
 	}
 
-	return &s, nil
+	decoder := json.NewDecoder(res.Body)
+	return decoder.Decode(out)
 }
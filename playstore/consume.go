@@ -0,0 +1,50 @@
+package playstore
+
+import (
+	"context"
+	"errors"
+)
+
+// Errors returned by GrantAndConsume for purchases that must not be granted.
+var (
+	ErrNotPurchased    = errors.New("product is not purchased")
+	ErrAlreadyConsumed = errors.New("product is already consumed")
+)
+
+// GrantFunc grants the content of a purchased in-app product to the user.
+type GrantFunc func(ctx context.Context, p *Product) error
+
+// GrantAndConsume grants a purchased consumable in-app product and consumes
+// it once the grant succeeded.
+func (c *Client) GrantAndConsume(pkg, prod, token string, grant GrantFunc) error {
+	return c.GrantAndConsumeContext(context.Background(), pkg, prod, token, grant)
+}
+
+// GrantAndConsumeContext is like GrantAndConsume but takes a context.
+//
+// The purchase is checked first. Purchases that are not completed or that are
+// already consumed are rejected with ErrNotPurchased or ErrAlreadyConsumed.
+// If grant fails, the purchase is left unconsumed and the error is returned,
+// so the purchase can be granted again later. If consuming fails after a
+// successful grant, the purchase is still unconsumed and a retry calls grant
+// again, so grant should be idempotent, e.g. keyed by the order ID.
+func (c *Client) GrantAndConsumeContext(ctx context.Context, pkg, prod, token string, grant GrantFunc) error {
+	p, err := c.GetProductContext(ctx, pkg, prod, token)
+	if err != nil {
+		return err
+	}
+
+	if p.PurchaseState != PurchaseDone {
+		return ErrNotPurchased
+	}
+
+	if p.ConsumptionState == Consumed {
+		return ErrAlreadyConsumed
+	}
+
+	if err := grant(ctx, p); err != nil {
+		return err
+	}
+
+	return c.ConsumeProductContext(ctx, pkg, prod, token)
+}
@@ -0,0 +1,100 @@
+package playstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/brainleap/iap"
+	"github.com/brainleap/iap/iaptest"
+	"github.com/brainleap/iap/playstore"
+)
+
+func TestGrantAndConsume(t *testing.T) {
+	errGrant := errors.New("grant failed")
+
+	tests := []struct {
+		name         string
+		product      playstore.Product
+		token        string
+		grantErr     error
+		wantErr      error
+		wantGranted  bool
+		wantConsumed bool
+	}{
+		{
+			name:         "purchased",
+			product:      playstore.Product{OrderID: "GPA.1"},
+			wantGranted:  true,
+			wantConsumed: true,
+		},
+		{
+			name:        "grant fails",
+			product:     playstore.Product{OrderID: "GPA.1"},
+			grantErr:    errGrant,
+			wantErr:     errGrant,
+			wantGranted: true,
+		},
+		{
+			name:         "already consumed",
+			product:      playstore.Product{OrderID: "GPA.1", ConsumptionState: playstore.Consumed},
+			wantErr:      playstore.ErrAlreadyConsumed,
+			wantConsumed: true,
+		},
+		{
+			name:    "canceled",
+			product: playstore.Product{OrderID: "GPA.1", PurchaseState: playstore.PurchaseCanceled},
+			wantErr: playstore.ErrNotPurchased,
+		},
+		{
+			name:    "pending",
+			product: playstore.Product{OrderID: "GPA.1", PurchaseState: playstore.PurchasePending},
+			wantErr: playstore.ErrNotPurchased,
+		},
+		{
+			name:    "unknown token",
+			product: playstore.Product{OrderID: "GPA.1"},
+			token:   "unknown",
+			wantErr: iap.ErrPurchaseNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := iaptest.NewPlayStore()
+			defer store.Close()
+
+			store.AddProduct(testPackage, "coins_100", "token", &tt.product)
+
+			c, err := store.Client()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token := tt.token
+			if token == "" {
+				token = "token"
+			}
+
+			granted := false
+			err = c.GrantAndConsume(testPackage, "coins_100", token, func(ctx context.Context, p *playstore.Product) error {
+				granted = true
+				if p.OrderID != "GPA.1" {
+					t.Errorf("granted product = %+v", p)
+				}
+				return tt.grantErr
+			})
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Fatalf("GrantAndConsume() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if granted != tt.wantGranted {
+				t.Errorf("granted = %v, want %v", granted, tt.wantGranted)
+			}
+
+			if consumed := store.Product("token").ConsumptionState == playstore.Consumed; consumed != tt.wantConsumed {
+				t.Errorf("consumed = %v, want %v", consumed, tt.wantConsumed)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package playstore
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/brainleap/iap"
+)
+
+// maxErrorBody limits how much of an error response is kept.
+const maxErrorBody = 64 << 10
+
+// Google API error reasons meaning the purchase token cannot be used.
+var invalidTokenReasons = map[string]bool{
+	"purchaseTokenInvalid":                    true,
+	"purchaseTokenNoLongerValid":              true,
+	"purchaseTokenDoesNotMatchProductId":      true,
+	"purchaseTokenDoesNotMatchSubscriptionId": true,
+	"productNotOwnedByUser":                   true,
+}
+
+// Google API error reasons meaning a rate limit or quota was exceeded. They
+// come with a 403 status, which would otherwise be classified as an auth
+// error.
+var rateLimitReasons = map[string]bool{
+	"rateLimitExceeded":     true,
+	"userRateLimitExceeded": true,
+	"quotaExceeded":         true,
+}
+
+// APIError is the error body returned by Google APIs.
+type APIError struct {
+	Code    int            `json:"code"`
+	Message string         `json:"message"`
+	Status  string         `json:"status"`
+	Errors  []APIErrorItem `json:"errors"`
+}
+
+// APIErrorItem is a single error of an APIError.
+type APIErrorItem struct {
+	Domain  string `json:"domain"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// newError creates an *iap.Error from a failed response. The parsed Google
+// API error is kept in Details.
+//
+// A 404 status only means iap.ErrPurchaseNotFound for purchase token
+// endpoints; for catalog or order lookups it is left unclassified.
+func newError(res *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+
+	e := iap.NewHTTPError(iap.PlayStore, res.StatusCode, body)
+	if res.StatusCode == http.StatusNotFound && !isPurchaseRequest(res.Request) {
+		e.Err = nil
+	}
+
+	var wrapper struct {
+		Error *APIError `json:"error"`
+	}
+	if err := json.Unmarshal(body, &wrapper); err != nil || wrapper.Error == nil {
+		return e
+	}
+
+	apiErr := wrapper.Error
+	e.Details = apiErr
+	e.Code = apiErr.Code
+	e.Message = apiErr.Message
+	e.Reason = apiErr.Status
+	if len(apiErr.Errors) > 0 {
+		e.Reason = apiErr.Errors[0].Reason
+	}
+
+	switch {
+	case rateLimitReasons[e.Reason]:
+		e.Err = iap.ErrRateLimited
+		e.Retryable = true
+	case invalidTokenReasons[e.Reason]:
+		e.Err = iap.ErrInvalidToken
+	}
+
+	return e
+}
+
+// isPurchaseRequest reports whether r was sent to a purchase token endpoint,
+// i.e. applications/{pkg}/purchases/{type}/.../tokens/{token}.
+func isPurchaseRequest(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+
+	path := r.URL.Path
+	return strings.Contains(path, "/purchases/") && strings.Contains(path, "/tokens/")
+}
@@ -0,0 +1,77 @@
+package playstore
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/brainleap/iap"
+)
+
+func TestNewError(t *testing.T) {
+	const (
+		purchaseURL = "https://example.com/applications/com.example.app/purchases/products/coins_100/tokens/token"
+		orderURL    = "https://example.com/applications/com.example.app/orders/GPA.1"
+		catalogURL  = "https://example.com/applications/com.example.app/inappproducts/coins_100"
+	)
+
+	apiError := func(code int, reason string) string {
+		return fmt.Sprintf(`{"error":{"code":%d,"message":"message","errors":[{"domain":"androidpublisher","reason":%q}]}}`, code, reason)
+	}
+
+	tests := []struct {
+		name          string
+		url           string
+		statusCode    int
+		body          string
+		wantErr       error
+		wantRetryable bool
+	}{
+		{name: "purchase not found", url: purchaseURL, statusCode: http.StatusNotFound, wantErr: iap.ErrPurchaseNotFound},
+		{name: "order not found", url: orderURL, statusCode: http.StatusNotFound},
+		{name: "product not found", url: catalogURL, statusCode: http.StatusNotFound, body: apiError(http.StatusNotFound, "notFound")},
+		{name: "invalid argument", url: purchaseURL, statusCode: http.StatusBadRequest, body: apiError(http.StatusBadRequest, "invalid")},
+		{name: "invalid token", url: purchaseURL, statusCode: http.StatusBadRequest, body: apiError(http.StatusBadRequest, "purchaseTokenInvalid"), wantErr: iap.ErrInvalidToken},
+		{name: "gone", url: purchaseURL, statusCode: http.StatusGone, wantErr: iap.ErrInvalidToken},
+		{name: "forbidden", url: orderURL, statusCode: http.StatusForbidden, body: apiError(http.StatusForbidden, "forbidden"), wantErr: iap.ErrAuth},
+		{
+			name:          "rate limited",
+			url:           catalogURL,
+			statusCode:    http.StatusForbidden,
+			body:          apiError(http.StatusForbidden, "rateLimitExceeded"),
+			wantErr:       iap.ErrRateLimited,
+			wantRetryable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = newError(&http.Response{
+				StatusCode: tt.statusCode,
+				Body:       ioutil.NopCloser(strings.NewReader(tt.body)),
+				Request:    req,
+			})
+
+			var e *iap.Error
+			if !errors.As(err, &e) {
+				t.Fatalf("newError() = %v, want *iap.Error", err)
+			}
+
+			if e.Err != tt.wantErr {
+				t.Errorf("Err = %v, want %v", e.Err, tt.wantErr)
+			}
+
+			if e.Retryable != tt.wantRetryable {
+				t.Errorf("Retryable = %v, want %v", e.Retryable, tt.wantRetryable)
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package playstore
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// InAppProductStatus is the data type for in-app product statuses.
+type InAppProductStatus string
+
+// List of in-app product statuses.
+const (
+	IPSActive   InAppProductStatus = "active"
+	IPSInactive InAppProductStatus = "inactive"
+)
+
+// InAppProductPurchaseType is the data type for in-app product purchase
+// types.
+type InAppProductPurchaseType string
+
+// List of in-app product purchase types.
+const (
+	IPTManagedUser  InAppProductPurchaseType = "managedUser"
+	IPTSubscription InAppProductPurchaseType = "subscription"
+)
+
+// LatencyTolerance is the optional argument that sets how fast product
+// updates must propagate to user-facing surfaces.
+type LatencyTolerance string
+
+// List of latency tolerances.
+const (
+	LTSensitive LatencyTolerance = "PRODUCT_UPDATE_LATENCY_TOLERANCE_LATENCY_SENSITIVE"
+	LTTolerant  LatencyTolerance = "PRODUCT_UPDATE_LATENCY_TOLERANCE_LATENCY_TOLERANT"
+)
+
+func (LatencyTolerance) isOption() {}
+
+// AutoConvertMissingPrices is the optional argument that fills the prices of
+// regions without a price from the default price.
+type AutoConvertMissingPrices bool
+
+func (AutoConvertMissingPrices) isOption() {}
+
+// AllowMissing is the optional argument that makes an update insert the
+// product if it does not exist.
+type AllowMissing bool
+
+func (AllowMissing) isOption() {}
+
+// InAppProduct is an in-app product of the catalog.
+type InAppProduct struct {
+	PackageName        string                         `json:"packageName,omitempty"`
+	SKU                string                         `json:"sku"`
+	Status             InAppProductStatus             `json:"status,omitempty"`
+	PurchaseType       InAppProductPurchaseType       `json:"purchaseType,omitempty"`
+	DefaultPrice       *Price                         `json:"defaultPrice,omitempty"`
+	Prices             map[string]Price               `json:"prices,omitempty"`
+	Listings           map[string]InAppProductListing `json:"listings,omitempty"`
+	DefaultLanguage    string                         `json:"defaultLanguage,omitempty"`
+	SubscriptionPeriod string                         `json:"subscriptionPeriod,omitempty"`
+	TrialPeriod        string                         `json:"trialPeriod,omitempty"`
+	GracePeriod        string                         `json:"gracePeriod,omitempty"`
+}
+
+// Price is a price in a currency.
+type Price struct {
+	PriceMicros int64  `json:"priceMicros,string"`
+	Currency    string `json:"currency"`
+}
+
+// InAppProductListing is the store listing of an in-app product in a
+// language.
+type InAppProductListing struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Benefits    []string `json:"benefits,omitempty"`
+}
+
+// InAppProductsResponse is a page of in-app products. Pass
+// TokenPagination.NextPageToken to the next call to fetch the following page.
+type InAppProductsResponse struct {
+	Kind            string           `json:"kind"`
+	PageInfo        *PageInfo        `json:"pageInfo"`
+	TokenPagination *TokenPagination `json:"tokenPagination"`
+	InAppProducts   []InAppProduct   `json:"inappproduct"`
+}
+
+// optionQuery returns the query of the optional arguments of catalog writes,
+// keeping the parameters the endpoint accepts.
+func optionQuery(opts []Option, accepted ...string) url.Values {
+	v := url.Values{}
+	for _, o := range opts {
+		switch o := o.(type) {
+		case AutoConvertMissingPrices:
+			v.Set("autoConvertMissingPrices", strconv.FormatBool(bool(o)))
+		case AllowMissing:
+			v.Set("allowMissing", strconv.FormatBool(bool(o)))
+		case LatencyTolerance:
+			v.Set("latencyTolerance", string(o))
+		}
+	}
+
+	for k := range v {
+		if !containsString(accepted, k) {
+			v.Del(k)
+		}
+	}
+
+	return v
+}
+
+func containsString(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+func withQuery(u string, v url.Values) string {
+	if len(v) == 0 {
+		return u
+	}
+
+	return u + "?" + v.Encode()
+}
+
+func (c *Client) inAppProductsURL(pkg string) string {
+	return fmt.Sprintf(
+		"%s/applications/%s/inappproducts",
+		c.baseURL(),
+		url.PathEscape(pkg),
+	)
+}
+
+func (c *Client) inAppProductURL(pkg, sku string) string {
+	return c.inAppProductsURL(pkg) + "/" + url.PathEscape(sku)
+}
+
+// ListInAppProducts lists a page of the in-app products of a package.
+func (c *Client) ListInAppProducts(pkg, pageToken string) (*InAppProductsResponse, error) {
+	return c.ListInAppProductsContext(context.Background(), pkg, pageToken)
+}
+
+// ListInAppProductsContext is like ListInAppProducts but takes a context.
+func (c *Client) ListInAppProductsContext(ctx context.Context, pkg, pageToken string) (*InAppProductsResponse, error) {
+	v := url.Values{}
+	if pageToken != "" {
+		v.Set("token", pageToken)
+	}
+
+	var r InAppProductsResponse
+	if err := c.do(ctx, http.MethodGet, withQuery(c.inAppProductsURL(pkg), v), nil, &r); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// ListAllInAppProducts lists the in-app products of a package, following the
+// pagination tokens until all pages are fetched.
+func (c *Client) ListAllInAppProducts(pkg string) ([]InAppProduct, error) {
+	return c.ListAllInAppProductsContext(context.Background(), pkg)
+}
+
+// ListAllInAppProductsContext is like ListAllInAppProducts but takes a
+// context.
+func (c *Client) ListAllInAppProductsContext(ctx context.Context, pkg string) ([]InAppProduct, error) {
+	var all []InAppProduct
+
+	token := ""
+	for {
+		r, err := c.ListInAppProductsContext(ctx, pkg, token)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, r.InAppProducts...)
+
+		if r.TokenPagination == nil || r.TokenPagination.NextPageToken == "" {
+			return all, nil
+		}
+
+		token = r.TokenPagination.NextPageToken
+	}
+}
+
+// GetInAppProduct gets an in-app product.
+func (c *Client) GetInAppProduct(pkg, sku string) (*InAppProduct, error) {
+	return c.GetInAppProductContext(context.Background(), pkg, sku)
+}
+
+// GetInAppProductContext is like GetInAppProduct but takes a context.
+func (c *Client) GetInAppProductContext(ctx context.Context, pkg, sku string) (*InAppProduct, error) {
+	var p InAppProduct
+	if err := c.do(ctx, http.MethodGet, c.inAppProductURL(pkg, sku), nil, &p); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// InsertInAppProduct creates an in-app product. It accepts the
+// AutoConvertMissingPrices option.
+func (c *Client) InsertInAppProduct(pkg string, p *InAppProduct, opts ...Option) (*InAppProduct, error) {
+	return c.InsertInAppProductContext(context.Background(), pkg, p, opts...)
+}
+
+// InsertInAppProductContext is like InsertInAppProduct but takes a context.
+func (c *Client) InsertInAppProductContext(ctx context.Context, pkg string, p *InAppProduct, opts ...Option) (*InAppProduct, error) {
+	u := withQuery(c.inAppProductsURL(pkg), optionQuery(opts, "autoConvertMissingPrices"))
+
+	var r InAppProduct
+	if err := c.do(ctx, http.MethodPost, u, p, &r); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// UpdateInAppProduct replaces an in-app product. It accepts the
+// AutoConvertMissingPrices, AllowMissing and LatencyTolerance options.
+func (c *Client) UpdateInAppProduct(pkg string, p *InAppProduct, opts ...Option) (*InAppProduct, error) {
+	return c.UpdateInAppProductContext(context.Background(), pkg, p, opts...)
+}
+
+// UpdateInAppProductContext is like UpdateInAppProduct but takes a context.
+func (c *Client) UpdateInAppProductContext(ctx context.Context, pkg string, p *InAppProduct, opts ...Option) (*InAppProduct, error) {
+	u := withQuery(c.inAppProductURL(pkg, p.SKU), optionQuery(opts, "autoConvertMissingPrices", "allowMissing", "latencyTolerance"))
+
+	var r InAppProduct
+	if err := c.do(ctx, http.MethodPut, u, p, &r); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// PatchInAppProduct updates the fields of an in-app product that are set in
+// p. It accepts the AutoConvertMissingPrices and LatencyTolerance options.
+func (c *Client) PatchInAppProduct(pkg string, p *InAppProduct, opts ...Option) (*InAppProduct, error) {
+	return c.PatchInAppProductContext(context.Background(), pkg, p, opts...)
+}
+
+// PatchInAppProductContext is like PatchInAppProduct but takes a context.
+func (c *Client) PatchInAppProductContext(ctx context.Context, pkg string, p *InAppProduct, opts ...Option) (*InAppProduct, error) {
+	u := withQuery(c.inAppProductURL(pkg, p.SKU), optionQuery(opts, "autoConvertMissingPrices", "latencyTolerance"))
+
+	var r InAppProduct
+	if err := c.do(ctx, http.MethodPatch, u, p, &r); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// DeleteInAppProduct deletes an in-app product. It accepts the
+// LatencyTolerance option.
+func (c *Client) DeleteInAppProduct(pkg, sku string, opts ...Option) error {
+	return c.DeleteInAppProductContext(context.Background(), pkg, sku, opts...)
+}
+
+// DeleteInAppProductContext is like DeleteInAppProduct but takes a context.
+func (c *Client) DeleteInAppProductContext(ctx context.Context, pkg, sku string, opts ...Option) error {
+	u := withQuery(c.inAppProductURL(pkg, sku), optionQuery(opts, "latencyTolerance"))
+
+	return c.do(ctx, http.MethodDelete, u, nil, nil)
+}
+
+// BatchUpdateInAppProducts replaces several in-app products in one request.
+// The options apply to all products, as for UpdateInAppProduct.
+func (c *Client) BatchUpdateInAppProducts(pkg string, products []InAppProduct, opts ...Option) ([]InAppProduct, error) {
+	return c.BatchUpdateInAppProductsContext(context.Background(), pkg, products, opts...)
+}
+
+// BatchUpdateInAppProductsContext is like BatchUpdateInAppProducts but takes
+// a context.
+func (c *Client) BatchUpdateInAppProductsContext(ctx context.Context, pkg string, products []InAppProduct, opts ...Option) ([]InAppProduct, error) {
+	type updateRequest struct {
+		InAppProduct             *InAppProduct    `json:"inappproduct"`
+		PackageName              string           `json:"packageName"`
+		SKU                      string           `json:"sku"`
+		AutoConvertMissingPrices bool             `json:"autoConvertMissingPrices,omitempty"`
+		AllowMissing             bool             `json:"allowMissing,omitempty"`
+		LatencyTolerance         LatencyTolerance `json:"latencyTolerance,omitempty"`
+	}
+
+	var base updateRequest
+	for _, o := range opts {
+		switch o := o.(type) {
+		case AutoConvertMissingPrices:
+			base.AutoConvertMissingPrices = bool(o)
+		case AllowMissing:
+			base.AllowMissing = bool(o)
+		case LatencyTolerance:
+			base.LatencyTolerance = o
+		}
+	}
+
+	body := struct {
+		Requests []updateRequest `json:"requests"`
+	}{
+		Requests: make([]updateRequest, len(products)),
+	}
+
+	for i := range products {
+		r := base
+		r.InAppProduct = &products[i]
+		r.PackageName = pkg
+		r.SKU = products[i].SKU
+
+		body.Requests[i] = r
+	}
+
+	var res struct {
+		InAppProducts []InAppProduct `json:"inappproducts"`
+	}
+
+	if err := c.do(ctx, http.MethodPost, c.inAppProductsURL(pkg)+":batchUpdate", &body, &res); err != nil {
+		return nil, err
+	}
+
+	return res.InAppProducts, nil
+}
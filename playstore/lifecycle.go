@@ -0,0 +1,122 @@
+package playstore
+
+import (
+	"time"
+
+	"github.com/brainleap/iap"
+)
+
+// ClassifySubscription maps a subscription purchase to its lifecycle state at
+// the given time.
+//
+// An expired subscription the developer canceled is reported as revoked, as
+// revoking cancels it and ends it right away. The subscription resource does
+// not tell a revocation from a developer cancellation that ran out, nor does
+// it report refunds; use ListVoidedPurchases to find them.
+//
+// Subscriptions in an introductory price period are reported as active, as
+// IntroductoryPriceInfo stays set after the introductory cycles end. Use
+// ClassifySubscriptionV2, which reads the offer phase, to tell them apart.
+func ClassifySubscription(s *Subscription, now time.Time) iap.SubscriptionStatus {
+	expiry := iap.Millis(s.ExpiryTimeMillis)
+
+	if expiry.After(now) {
+		st := iap.SubscriptionStatus{NextChange: expiry}
+
+		switch {
+		case s.AutoRenewing && s.PaymentState == PaymentPending:
+			st.Lifecycle = iap.LifecycleGracePeriod
+		case !s.AutoRenewing:
+			st.Lifecycle = iap.LifecycleCanceled
+		case s.PaymentState == PaymentFreeTrial:
+			st.Lifecycle = iap.LifecycleTrial
+		default:
+			st.Lifecycle = iap.LifecycleActive
+		}
+
+		return st
+	}
+
+	if resume := iap.Millis(s.AutoResumeTimeMillis); resume.After(now) {
+		return iap.SubscriptionStatus{Lifecycle: iap.LifecyclePaused, NextChange: resume}
+	}
+
+	switch {
+	case s.AutoRenewing && s.PaymentState == PaymentPending:
+		return iap.SubscriptionStatus{Lifecycle: iap.LifecycleAccountHold}
+	case !s.AutoRenewing && s.CancelReason == CRDeveloperCanceled:
+		return iap.SubscriptionStatus{Lifecycle: iap.LifecycleRevoked}
+	}
+
+	return iap.SubscriptionStatus{Lifecycle: iap.LifecycleExpired}
+}
+
+// ClassifySubscriptionV2 is like ClassifySubscription but takes a
+// SubscriptionPurchaseV2. Active subscriptions are reported as in a trial or
+// introductory offer by the offer phase of the line item expiring last.
+func ClassifySubscriptionV2(s *SubscriptionPurchaseV2, now time.Time) iap.SubscriptionStatus {
+	expiry := s.ExpiryTime()
+
+	switch s.SubscriptionState {
+	case SSActive:
+		st := iap.SubscriptionStatus{Lifecycle: iap.LifecycleActive, NextChange: expiry}
+		if phase := s.offerPhase(); phase != nil {
+			switch {
+			case phase.FreeTrial != nil:
+				st.Lifecycle = iap.LifecycleTrial
+			case phase.IntroductoryPrice != nil:
+				st.Lifecycle = iap.LifecycleIntroOffer
+			}
+		}
+
+		return st
+	case SSInGracePeriod:
+		return iap.SubscriptionStatus{Lifecycle: iap.LifecycleGracePeriod, NextChange: expiry}
+	case SSOnHold:
+		return iap.SubscriptionStatus{Lifecycle: iap.LifecycleAccountHold}
+	case SSPaused:
+		st := iap.SubscriptionStatus{Lifecycle: iap.LifecyclePaused}
+		if s.PausedStateContext != nil {
+			st.NextChange = s.PausedStateContext.AutoResumeTime
+		}
+
+		return st
+	case SSCanceled:
+		if expiry.After(now) {
+			return iap.SubscriptionStatus{Lifecycle: iap.LifecycleCanceled, NextChange: expiry}
+		}
+
+		return s.expiredStatus()
+	case SSExpired:
+		return s.expiredStatus()
+	}
+
+	return iap.SubscriptionStatus{Lifecycle: iap.LifecycleUnknown}
+}
+
+// expiredStatus reports an expired subscription the developer canceled as
+// revoked.
+func (s *SubscriptionPurchaseV2) expiredStatus() iap.SubscriptionStatus {
+	if c := s.CanceledStateContext; c != nil && c.DeveloperInitiatedCancellation != nil {
+		return iap.SubscriptionStatus{Lifecycle: iap.LifecycleRevoked}
+	}
+
+	return iap.SubscriptionStatus{Lifecycle: iap.LifecycleExpired}
+}
+
+// offerPhase returns the offer phase of the line item expiring last, or nil
+// if it has none.
+func (s *SubscriptionPurchaseV2) offerPhase() *OfferPhase {
+	var latest *SubscriptionPurchaseLineItem
+	for i := range s.LineItems {
+		if latest == nil || s.LineItems[i].ExpiryTime.After(latest.ExpiryTime) {
+			latest = &s.LineItems[i]
+		}
+	}
+
+	if latest == nil {
+		return nil
+	}
+
+	return latest.OfferPhase
+}
@@ -0,0 +1,228 @@
+package playstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brainleap/iap"
+)
+
+func TestClassifySubscription(t *testing.T) {
+	now := time.Now()
+	future := now.Add(24*time.Hour).UnixNano() / int64(time.Millisecond)
+	past := now.Add(-24*time.Hour).UnixNano() / int64(time.Millisecond)
+
+	tests := []struct {
+		name           string
+		s              Subscription
+		wantLifecycle  iap.Lifecycle
+		wantNextChange int64
+	}{
+		{
+			name:           "active",
+			s:              Subscription{ExpiryTimeMillis: future, AutoRenewing: true, PaymentState: PaymentReceived},
+			wantLifecycle:  iap.LifecycleActive,
+			wantNextChange: future,
+		},
+		{
+			name:           "trial",
+			s:              Subscription{ExpiryTimeMillis: future, AutoRenewing: true, PaymentState: PaymentFreeTrial},
+			wantLifecycle:  iap.LifecycleTrial,
+			wantNextChange: future,
+		},
+		{
+			name: "introductory price",
+			s: Subscription{
+				ExpiryTimeMillis:      future,
+				AutoRenewing:          true,
+				PaymentState:          PaymentReceived,
+				IntroductoryPriceInfo: &IntroductoryPriceInfo{Cycles: 1},
+			},
+			wantLifecycle:  iap.LifecycleActive,
+			wantNextChange: future,
+		},
+		{
+			name:           "grace period",
+			s:              Subscription{ExpiryTimeMillis: future, AutoRenewing: true, PaymentState: PaymentPending},
+			wantLifecycle:  iap.LifecycleGracePeriod,
+			wantNextChange: future,
+		},
+		{
+			name:           "canceled",
+			s:              Subscription{ExpiryTimeMillis: future, CancelReason: CRUserCanceled},
+			wantLifecycle:  iap.LifecycleCanceled,
+			wantNextChange: future,
+		},
+		{
+			name:           "paused",
+			s:              Subscription{ExpiryTimeMillis: past, AutoResumeTimeMillis: future, AutoRenewing: true},
+			wantLifecycle:  iap.LifecyclePaused,
+			wantNextChange: future,
+		},
+		{
+			name:          "account hold",
+			s:             Subscription{ExpiryTimeMillis: past, AutoRenewing: true, PaymentState: PaymentPending},
+			wantLifecycle: iap.LifecycleAccountHold,
+		},
+		{
+			name:          "expired",
+			s:             Subscription{ExpiryTimeMillis: past, CancelReason: CRSystemCanceled},
+			wantLifecycle: iap.LifecycleExpired,
+		},
+		{
+			name:          "expired after user cancellation",
+			s:             Subscription{ExpiryTimeMillis: past, CancelReason: CRUserCanceled},
+			wantLifecycle: iap.LifecycleExpired,
+		},
+		{
+			name:          "revoked",
+			s:             Subscription{ExpiryTimeMillis: past, CancelReason: CRDeveloperCanceled},
+			wantLifecycle: iap.LifecycleRevoked,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClassifySubscription(&tt.s, now)
+
+			if got.Lifecycle != tt.wantLifecycle {
+				t.Errorf("Lifecycle = %s, want %s", got.Lifecycle, tt.wantLifecycle)
+			}
+
+			if !got.NextChange.Equal(iap.Millis(tt.wantNextChange)) {
+				t.Errorf("NextChange = %v, want %v", got.NextChange, iap.Millis(tt.wantNextChange))
+			}
+		})
+	}
+}
+
+func TestClassifySubscriptionV2(t *testing.T) {
+	now := time.Now()
+	future := now.Add(24 * time.Hour)
+	past := now.Add(-24 * time.Hour)
+
+	item := func(expiry time.Time, phase *OfferPhase) SubscriptionPurchaseLineItem {
+		return SubscriptionPurchaseLineItem{ProductID: "monthly", ExpiryTime: expiry, OfferPhase: phase}
+	}
+
+	tests := []struct {
+		name           string
+		s              SubscriptionPurchaseV2
+		wantLifecycle  iap.Lifecycle
+		wantNextChange time.Time
+	}{
+		{
+			name: "active",
+			s: SubscriptionPurchaseV2{
+				SubscriptionState: SSActive,
+				LineItems:         []SubscriptionPurchaseLineItem{item(future, &OfferPhase{BasePlan: &struct{}{}})},
+			},
+			wantLifecycle:  iap.LifecycleActive,
+			wantNextChange: future,
+		},
+		{
+			name: "trial",
+			s: SubscriptionPurchaseV2{
+				SubscriptionState: SSActive,
+				LineItems:         []SubscriptionPurchaseLineItem{item(future, &OfferPhase{FreeTrial: &struct{}{}})},
+			},
+			wantLifecycle:  iap.LifecycleTrial,
+			wantNextChange: future,
+		},
+		{
+			name: "intro offer",
+			s: SubscriptionPurchaseV2{
+				SubscriptionState: SSActive,
+				LineItems:         []SubscriptionPurchaseLineItem{item(future, &OfferPhase{IntroductoryPrice: &struct{}{}})},
+			},
+			wantLifecycle:  iap.LifecycleIntroOffer,
+			wantNextChange: future,
+		},
+		{
+			name: "offer phase of latest line item",
+			s: SubscriptionPurchaseV2{
+				SubscriptionState: SSActive,
+				LineItems: []SubscriptionPurchaseLineItem{
+					item(now.Add(time.Hour), &OfferPhase{FreeTrial: &struct{}{}}),
+					item(future, &OfferPhase{BasePlan: &struct{}{}}),
+				},
+			},
+			wantLifecycle:  iap.LifecycleActive,
+			wantNextChange: future,
+		},
+		{
+			name:           "grace period",
+			s:              SubscriptionPurchaseV2{SubscriptionState: SSInGracePeriod, LineItems: []SubscriptionPurchaseLineItem{item(future, nil)}},
+			wantLifecycle:  iap.LifecycleGracePeriod,
+			wantNextChange: future,
+		},
+		{
+			name:          "account hold",
+			s:             SubscriptionPurchaseV2{SubscriptionState: SSOnHold, LineItems: []SubscriptionPurchaseLineItem{item(past, nil)}},
+			wantLifecycle: iap.LifecycleAccountHold,
+		},
+		{
+			name: "paused",
+			s: SubscriptionPurchaseV2{
+				SubscriptionState:  SSPaused,
+				LineItems:          []SubscriptionPurchaseLineItem{item(past, nil)},
+				PausedStateContext: &PausedStateContext{AutoResumeTime: future},
+			},
+			wantLifecycle:  iap.LifecyclePaused,
+			wantNextChange: future,
+		},
+		{
+			name:           "canceled",
+			s:              SubscriptionPurchaseV2{SubscriptionState: SSCanceled, LineItems: []SubscriptionPurchaseLineItem{item(future, nil)}},
+			wantLifecycle:  iap.LifecycleCanceled,
+			wantNextChange: future,
+		},
+		{
+			name:          "canceled and expired",
+			s:             SubscriptionPurchaseV2{SubscriptionState: SSCanceled, LineItems: []SubscriptionPurchaseLineItem{item(past, nil)}},
+			wantLifecycle: iap.LifecycleExpired,
+		},
+		{
+			name:          "expired",
+			s:             SubscriptionPurchaseV2{SubscriptionState: SSExpired, LineItems: []SubscriptionPurchaseLineItem{item(past, nil)}},
+			wantLifecycle: iap.LifecycleExpired,
+		},
+		{
+			name: "revoked",
+			s: SubscriptionPurchaseV2{
+				SubscriptionState:    SSExpired,
+				LineItems:            []SubscriptionPurchaseLineItem{item(past, nil)},
+				CanceledStateContext: &CanceledStateContext{DeveloperInitiatedCancellation: &struct{}{}},
+			},
+			wantLifecycle: iap.LifecycleRevoked,
+		},
+		{
+			name: "revoked while canceled",
+			s: SubscriptionPurchaseV2{
+				SubscriptionState:    SSCanceled,
+				LineItems:            []SubscriptionPurchaseLineItem{item(past, nil)},
+				CanceledStateContext: &CanceledStateContext{DeveloperInitiatedCancellation: &struct{}{}},
+			},
+			wantLifecycle: iap.LifecycleRevoked,
+		},
+		{
+			name:          "pending",
+			s:             SubscriptionPurchaseV2{SubscriptionState: SSPending},
+			wantLifecycle: iap.LifecycleUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClassifySubscriptionV2(&tt.s, now)
+
+			if got.Lifecycle != tt.wantLifecycle {
+				t.Errorf("Lifecycle = %s, want %s", got.Lifecycle, tt.wantLifecycle)
+			}
+
+			if !got.NextChange.Equal(tt.wantNextChange) {
+				t.Errorf("NextChange = %v, want %v", got.NextChange, tt.wantNextChange)
+			}
+		})
+	}
+}
@@ -84,7 +84,7 @@ const (
 // Product indicates the status of an in-app product purchase.
 type Product struct {
 	Kind                 string               `json:"kind"`
-	PurchaseTimeMillis   int64                `json:"purchaseTimeMillis"`
+	PurchaseTimeMillis   int64                `json:"purchaseTimeMillis,string"`
 	PurchaseState        PurchaseState        `json:"purchaseState"`
 	ConsumptionState     ConsumptionState     `json:"consumptionState"`
 	DeveloperPayload     string               `json:"developerPayload"`
@@ -96,18 +96,18 @@ type Product struct {
 // Subscription indicates the status of a subscription purchase.
 type Subscription struct {
 	Kind                       string                 `json:"kind"`
-	StartTimeMillis            int64                  `json:"startTimeMillis"`
-	ExpiryTimeMillis           int64                  `json:"expiryTimeMillis"`
-	AutoResumeTimeMillis       int64                  `json:"autoResumeTimeMillis"`
+	StartTimeMillis            int64                  `json:"startTimeMillis,string"`
+	ExpiryTimeMillis           int64                  `json:"expiryTimeMillis,string"`
+	AutoResumeTimeMillis       int64                  `json:"autoResumeTimeMillis,string"`
 	AutoRenewing               bool                   `json:"autoRenewing"`
 	PriceCurrencyCode          string                 `json:"priceCurrencyCode"`
-	PriceAmountMicros          int64                  `json:"priceAmountMicros"`
+	PriceAmountMicros          int64                  `json:"priceAmountMicros,string"`
 	IntroductoryPriceInfo      *IntroductoryPriceInfo `json:"introductoryPriceInfo"`
 	CountryCode                string                 `json:"countryCode"`
 	DeveloperPayload           string                 `json:"developerPayload"`
 	PaymentState               PaymentState           `json:"paymentState"`
 	CancelReason               CancelReason           `json:"cancelReason"`
-	UserCancellationTimeMillis int64                  `json:"userCancellationTimeMillis"`
+	UserCancellationTimeMillis int64                  `json:"userCancellationTimeMillis,string"`
 	CancelSurveyResult         *CancelSurveyResult    `json:"cancelSurveyResult"`
 	OrderID                    string                 `json:"orderId"`
 	LinkedPurchaseToken        string                 `json:"linkedPurchaseToken"`
@@ -124,7 +124,7 @@ type Subscription struct {
 // IntroductoryPriceInfo is the introductory price info of a subscription.
 type IntroductoryPriceInfo struct {
 	CurrencyCode string `json:"introductoryPriceCurrencyCode"`
-	AmountMicros int64  `json:"introductoryPriceAmountMicros"`
+	AmountMicros int64  `json:"introductoryPriceAmountMicros,string"`
 	Period       string `json:"introductoryPricePeriod"`
 	Cycles       int    `json:"introductoryPriceCycles"`
 }
@@ -144,13 +144,35 @@ type PriceChange struct {
 
 // NewPrice is the new price info in a price change.
 type NewPrice struct {
-	PriceMicros int64  `json:"priceMicros"`
+	PriceMicros int64  `json:"priceMicros,string"`
 	Currency    string `json:"currency"`
 }
 
 // DeferralInfo is the info about the new desired expiry time for
 // the subscription.
 type DeferralInfo struct {
-	ExpectedTimeMillis int64 `json:"expectedExpiryTimeMillis"`
-	DesiredTimeMillis  int64 `json:"desiredExpiryTimeMillis"`
+	ExpectedTimeMillis int64 `json:"expectedExpiryTimeMillis,string"`
+	DesiredTimeMillis  int64 `json:"desiredExpiryTimeMillis,string"`
+}
+
+// PageInfo is the paging information of a list response.
+type PageInfo struct {
+	TotalResults  int `json:"totalResults"`
+	ResultPerPage int `json:"resultPerPage"`
+	StartIndex    int `json:"startIndex"`
+}
+
+// TokenPagination contains the tokens of the neighboring pages of a list
+// response.
+type TokenPagination struct {
+	NextPageToken     string `json:"nextPageToken"`
+	PreviousPageToken string `json:"previousPageToken"`
+}
+
+// Money is an amount of money in a currency. The amount is Units plus Nanos
+// billionths of a unit.
+type Money struct {
+	CurrencyCode string `json:"currencyCode"`
+	Units        int64  `json:"units,string"`
+	Nanos        int    `json:"nanos"`
 }
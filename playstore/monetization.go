@@ -0,0 +1,535 @@
+package playstore
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// BasePlanState is the data type for base plan states.
+type BasePlanState string
+
+// List of base plan states.
+const (
+	BPSDraft    BasePlanState = "DRAFT"
+	BPSActive   BasePlanState = "ACTIVE"
+	BPSInactive BasePlanState = "INACTIVE"
+)
+
+// OfferState is the data type for subscription offer states.
+type OfferState string
+
+// List of subscription offer states.
+const (
+	OSDraft    OfferState = "DRAFT"
+	OSActive   OfferState = "ACTIVE"
+	OSInactive OfferState = "INACTIVE"
+)
+
+// PriceIncreaseType is the data type for the ways existing subscribers are
+// moved to an increased price.
+type PriceIncreaseType string
+
+// List of price increase types.
+const (
+	PITOptIn  PriceIncreaseType = "PRICE_INCREASE_TYPE_OPT_IN"
+	PITOptOut PriceIncreaseType = "PRICE_INCREASE_TYPE_OPT_OUT"
+)
+
+// SubscriptionProduct is a subscription of the catalog together with its
+// base plans. It is called Subscription in the monetization API.
+type SubscriptionProduct struct {
+	PackageName string                `json:"packageName,omitempty"`
+	ProductID   string                `json:"productId"`
+	BasePlans   []BasePlan            `json:"basePlans,omitempty"`
+	Listings    []SubscriptionListing `json:"listings,omitempty"`
+	Archived    bool                  `json:"archived,omitempty"`
+}
+
+// SubscriptionListing is the store listing of a subscription in a language.
+type SubscriptionListing struct {
+	LanguageCode string   `json:"languageCode"`
+	Title        string   `json:"title"`
+	Benefits     []string `json:"benefits,omitempty"`
+	Description  string   `json:"description,omitempty"`
+}
+
+// BasePlan is a billing configuration of a subscription. Exactly one of the
+// base plan types is set.
+type BasePlan struct {
+	BasePlanID               string                    `json:"basePlanId"`
+	State                    BasePlanState             `json:"state,omitempty"`
+	RegionalConfigs          []RegionalBasePlanConfig  `json:"regionalConfigs,omitempty"`
+	OtherRegionsConfig       *OtherRegionsConfig       `json:"otherRegionsConfig,omitempty"`
+	OfferTags                []OfferTag                `json:"offerTags,omitempty"`
+	AutoRenewingBasePlanType *AutoRenewingBasePlanType `json:"autoRenewingBasePlanType,omitempty"`
+	PrepaidBasePlanType      *PrepaidBasePlanType      `json:"prepaidBasePlanType,omitempty"`
+}
+
+// RegionalBasePlanConfig is the price and availability of a base plan in a
+// region.
+type RegionalBasePlanConfig struct {
+	RegionCode                string `json:"regionCode"`
+	NewSubscriberAvailability bool   `json:"newSubscriberAvailability"`
+	Price                     *Money `json:"price"`
+}
+
+// OtherRegionsConfig is the price and availability of a base plan in regions
+// Google Play may launch in later.
+type OtherRegionsConfig struct {
+	USDPrice                  *Money `json:"usdPrice"`
+	EURPrice                  *Money `json:"eurPrice"`
+	NewSubscriberAvailability bool   `json:"newSubscriberAvailability"`
+}
+
+// OfferTag is a tag apps use to select base plans and offers.
+type OfferTag struct {
+	Tag string `json:"tag"`
+}
+
+// AutoRenewingBasePlanType configures a base plan that renews automatically.
+// Durations are ISO 8601 periods, e.g. P1M.
+type AutoRenewingBasePlanType struct {
+	BillingPeriodDuration               string `json:"billingPeriodDuration"`
+	GracePeriodDuration                 string `json:"gracePeriodDuration,omitempty"`
+	AccountHoldDuration                 string `json:"accountHoldDuration,omitempty"`
+	ResubscribeState                    string `json:"resubscribeState,omitempty"`
+	ProrationMode                       string `json:"prorationMode,omitempty"`
+	LegacyCompatible                    bool   `json:"legacyCompatible,omitempty"`
+	LegacyCompatibleSubscriptionOfferID string `json:"legacyCompatibleSubscriptionOfferId,omitempty"`
+}
+
+// PrepaidBasePlanType configures a prepaid base plan. Durations are ISO 8601
+// periods, e.g. P1M.
+type PrepaidBasePlanType struct {
+	BillingPeriodDuration string `json:"billingPeriodDuration"`
+	TimeExtension         string `json:"timeExtension,omitempty"`
+}
+
+// SubscriptionOffer is a set of pricing phases offered on top of a base plan.
+type SubscriptionOffer struct {
+	PackageName        string                            `json:"packageName,omitempty"`
+	ProductID          string                            `json:"productId,omitempty"`
+	BasePlanID         string                            `json:"basePlanId,omitempty"`
+	OfferID            string                            `json:"offerId"`
+	State              OfferState                        `json:"state,omitempty"`
+	Phases             []SubscriptionOfferPhase          `json:"phases"`
+	RegionalConfigs    []RegionalSubscriptionOfferConfig `json:"regionalConfigs,omitempty"`
+	OtherRegionsConfig *OtherRegionsOfferConfig          `json:"otherRegionsConfig,omitempty"`
+	OfferTags          []OfferTag                        `json:"offerTags,omitempty"`
+}
+
+// RegionalSubscriptionOfferConfig is the availability of an offer in a
+// region.
+type RegionalSubscriptionOfferConfig struct {
+	RegionCode                string `json:"regionCode"`
+	NewSubscriberAvailability bool   `json:"newSubscriberAvailability"`
+}
+
+// OtherRegionsOfferConfig is the availability of an offer in regions Google
+// Play may launch in later.
+type OtherRegionsOfferConfig struct {
+	OtherRegionsNewSubscriberAvailability bool `json:"otherRegionsNewSubscriberAvailability"`
+}
+
+// SubscriptionOfferPhase is a pricing phase of an offer, repeated
+// RecurrenceCount times for Duration each.
+type SubscriptionOfferPhase struct {
+	RecurrenceCount    int                                    `json:"recurrenceCount"`
+	Duration           string                                 `json:"duration"`
+	RegionalConfigs    []RegionalSubscriptionOfferPhaseConfig `json:"regionalConfigs,omitempty"`
+	OtherRegionsConfig *OtherRegionsOfferPhaseConfig          `json:"otherRegionsConfig,omitempty"`
+}
+
+// RegionalSubscriptionOfferPhaseConfig is the price of an offer phase in a
+// region. Exactly one of the pricing fields is set.
+type RegionalSubscriptionOfferPhaseConfig struct {
+	RegionCode       string    `json:"regionCode"`
+	Price            *Money    `json:"price,omitempty"`
+	RelativeDiscount float64   `json:"relativeDiscount,omitempty"`
+	AbsoluteDiscount *Money    `json:"absoluteDiscount,omitempty"`
+	Free             *struct{} `json:"free,omitempty"`
+}
+
+// OtherRegionsOfferPhaseConfig is the price of an offer phase in regions
+// Google Play may launch in later. Exactly one of the pricing fields is set.
+type OtherRegionsOfferPhaseConfig struct {
+	USDPrice          *Money                   `json:"usdPrice,omitempty"`
+	EURPrice          *Money                   `json:"eurPrice,omitempty"`
+	RelativeDiscount  float64                  `json:"relativeDiscount,omitempty"`
+	AbsoluteDiscounts *OtherRegionsOfferPrices `json:"absoluteDiscounts,omitempty"`
+	Free              *struct{}                `json:"free,omitempty"`
+}
+
+// OtherRegionsOfferPrices is an amount in the currencies used for regions
+// Google Play may launch in later.
+type OtherRegionsOfferPrices struct {
+	USDPrice *Money `json:"usdPrice"`
+	EURPrice *Money `json:"eurPrice"`
+}
+
+// RegionalPriceMigration moves the existing subscribers of a region to the
+// current price of a base plan. Subscribers on prices older than
+// OldestAllowedPriceVersionTime, an RFC 3339 timestamp, are migrated.
+type RegionalPriceMigration struct {
+	RegionCode                    string            `json:"regionCode"`
+	OldestAllowedPriceVersionTime string            `json:"oldestAllowedPriceVersionTime"`
+	PriceIncreaseType             PriceIncreaseType `json:"priceIncreaseType,omitempty"`
+}
+
+// SubscriptionProductsResponse is a page of subscriptions. Pass NextPageToken
+// to the next call to fetch the following page.
+type SubscriptionProductsResponse struct {
+	Subscriptions []SubscriptionProduct `json:"subscriptions"`
+	NextPageToken string                `json:"nextPageToken"`
+}
+
+// SubscriptionOffersResponse is a page of subscription offers. Pass
+// NextPageToken to the next call to fetch the following page.
+type SubscriptionOffersResponse struct {
+	SubscriptionOffers []SubscriptionOffer `json:"subscriptionOffers"`
+	NextPageToken      string              `json:"nextPageToken"`
+}
+
+// regions is the version of the available regions prices are given for.
+type regions struct {
+	Version string `json:"version"`
+}
+
+func latencyTolerance(opts []Option) LatencyTolerance {
+	for _, o := range opts {
+		if lt, ok := o.(LatencyTolerance); ok {
+			return lt
+		}
+	}
+
+	return ""
+}
+
+func (c *Client) subscriptionsURL(pkg string) string {
+	return fmt.Sprintf(
+		"%s/applications/%s/subscriptions",
+		c.baseURL(),
+		url.PathEscape(pkg),
+	)
+}
+
+func (c *Client) subscriptionURL(pkg, productID string) string {
+	return c.subscriptionsURL(pkg) + "/" + url.PathEscape(productID)
+}
+
+func (c *Client) basePlanURL(pkg, productID, basePlanID string) string {
+	return c.subscriptionURL(pkg, productID) + "/basePlans/" + url.PathEscape(basePlanID)
+}
+
+func (c *Client) offersURL(pkg, productID, basePlanID string) string {
+	return c.basePlanURL(pkg, productID, basePlanID) + "/offers"
+}
+
+// ListSubscriptionProducts lists a page of the subscriptions of a package.
+func (c *Client) ListSubscriptionProducts(pkg, pageToken string, showArchived bool) (*SubscriptionProductsResponse, error) {
+	return c.ListSubscriptionProductsContext(context.Background(), pkg, pageToken, showArchived)
+}
+
+// ListSubscriptionProductsContext is like ListSubscriptionProducts but takes
+// a context.
+func (c *Client) ListSubscriptionProductsContext(ctx context.Context, pkg, pageToken string, showArchived bool) (*SubscriptionProductsResponse, error) {
+	v := url.Values{}
+	if pageToken != "" {
+		v.Set("pageToken", pageToken)
+	}
+	if showArchived {
+		v.Set("showArchived", "true")
+	}
+
+	var r SubscriptionProductsResponse
+	if err := c.do(ctx, http.MethodGet, withQuery(c.subscriptionsURL(pkg), v), nil, &r); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// ListAllSubscriptionProducts lists the subscriptions of a package, following
+// the page tokens until all pages are fetched.
+func (c *Client) ListAllSubscriptionProducts(pkg string, showArchived bool) ([]SubscriptionProduct, error) {
+	return c.ListAllSubscriptionProductsContext(context.Background(), pkg, showArchived)
+}
+
+// ListAllSubscriptionProductsContext is like ListAllSubscriptionProducts but
+// takes a context.
+func (c *Client) ListAllSubscriptionProductsContext(ctx context.Context, pkg string, showArchived bool) ([]SubscriptionProduct, error) {
+	var all []SubscriptionProduct
+
+	token := ""
+	for {
+		r, err := c.ListSubscriptionProductsContext(ctx, pkg, token, showArchived)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, r.Subscriptions...)
+
+		if r.NextPageToken == "" {
+			return all, nil
+		}
+
+		token = r.NextPageToken
+	}
+}
+
+// GetSubscriptionProduct gets a subscription.
+func (c *Client) GetSubscriptionProduct(pkg, productID string) (*SubscriptionProduct, error) {
+	return c.GetSubscriptionProductContext(context.Background(), pkg, productID)
+}
+
+// GetSubscriptionProductContext is like GetSubscriptionProduct but takes a
+// context.
+func (c *Client) GetSubscriptionProductContext(ctx context.Context, pkg, productID string) (*SubscriptionProduct, error) {
+	var s SubscriptionProduct
+	if err := c.do(ctx, http.MethodGet, c.subscriptionURL(pkg, productID), nil, &s); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// CreateSubscriptionProduct creates a subscription with its base plans in
+// draft state. regionsVersion is the version of the available regions the
+// prices are given for, e.g. 2022/02.
+func (c *Client) CreateSubscriptionProduct(pkg string, s *SubscriptionProduct, regionsVersion string) (*SubscriptionProduct, error) {
+	return c.CreateSubscriptionProductContext(context.Background(), pkg, s, regionsVersion)
+}
+
+// CreateSubscriptionProductContext is like CreateSubscriptionProduct but
+// takes a context.
+func (c *Client) CreateSubscriptionProductContext(ctx context.Context, pkg string, s *SubscriptionProduct, regionsVersion string) (*SubscriptionProduct, error) {
+	v := url.Values{}
+	v.Set("productId", s.ProductID)
+	v.Set("regionsVersion.version", regionsVersion)
+
+	var r SubscriptionProduct
+	if err := c.do(ctx, http.MethodPost, withQuery(c.subscriptionsURL(pkg), v), s, &r); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// PatchSubscriptionProduct updates the fields of a subscription listed in
+// updateMask, e.g. listings or basePlans. It accepts the AllowMissing and
+// LatencyTolerance options.
+func (c *Client) PatchSubscriptionProduct(pkg string, s *SubscriptionProduct, updateMask []string, regionsVersion string, opts ...Option) (*SubscriptionProduct, error) {
+	return c.PatchSubscriptionProductContext(context.Background(), pkg, s, updateMask, regionsVersion, opts...)
+}
+
+// PatchSubscriptionProductContext is like PatchSubscriptionProduct but takes
+// a context.
+func (c *Client) PatchSubscriptionProductContext(ctx context.Context, pkg string, s *SubscriptionProduct, updateMask []string, regionsVersion string, opts ...Option) (*SubscriptionProduct, error) {
+	v := optionQuery(opts, "allowMissing", "latencyTolerance")
+	v.Set("updateMask", strings.Join(updateMask, ","))
+	v.Set("regionsVersion.version", regionsVersion)
+
+	var r SubscriptionProduct
+	if err := c.do(ctx, http.MethodPatch, withQuery(c.subscriptionURL(pkg, s.ProductID), v), s, &r); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// ArchiveSubscriptionProduct archives a subscription. Archived subscriptions
+// can no longer be purchased.
+func (c *Client) ArchiveSubscriptionProduct(pkg, productID string) (*SubscriptionProduct, error) {
+	return c.ArchiveSubscriptionProductContext(context.Background(), pkg, productID)
+}
+
+// ArchiveSubscriptionProductContext is like ArchiveSubscriptionProduct but
+// takes a context.
+func (c *Client) ArchiveSubscriptionProductContext(ctx context.Context, pkg, productID string) (*SubscriptionProduct, error) {
+	var r SubscriptionProduct
+	if err := c.do(ctx, http.MethodPost, c.subscriptionURL(pkg, productID)+":archive", struct{}{}, &r); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// ActivateBasePlan activates a base plan, making it available to new
+// subscribers. It accepts the LatencyTolerance option.
+func (c *Client) ActivateBasePlan(pkg, productID, basePlanID string, opts ...Option) (*SubscriptionProduct, error) {
+	return c.ActivateBasePlanContext(context.Background(), pkg, productID, basePlanID, opts...)
+}
+
+// ActivateBasePlanContext is like ActivateBasePlan but takes a context.
+func (c *Client) ActivateBasePlanContext(ctx context.Context, pkg, productID, basePlanID string, opts ...Option) (*SubscriptionProduct, error) {
+	return c.basePlanAction(ctx, pkg, productID, basePlanID, "activate", opts)
+}
+
+// DeactivateBasePlan deactivates a base plan, making it unavailable to new
+// subscribers. Existing subscribers keep it. It accepts the LatencyTolerance
+// option.
+func (c *Client) DeactivateBasePlan(pkg, productID, basePlanID string, opts ...Option) (*SubscriptionProduct, error) {
+	return c.DeactivateBasePlanContext(context.Background(), pkg, productID, basePlanID, opts...)
+}
+
+// DeactivateBasePlanContext is like DeactivateBasePlan but takes a context.
+func (c *Client) DeactivateBasePlanContext(ctx context.Context, pkg, productID, basePlanID string, opts ...Option) (*SubscriptionProduct, error) {
+	return c.basePlanAction(ctx, pkg, productID, basePlanID, "deactivate", opts)
+}
+
+func (c *Client) basePlanAction(ctx context.Context, pkg, productID, basePlanID, action string, opts []Option) (*SubscriptionProduct, error) {
+	body := struct {
+		LatencyTolerance LatencyTolerance `json:"latencyTolerance,omitempty"`
+	}{
+		LatencyTolerance: latencyTolerance(opts),
+	}
+
+	var r SubscriptionProduct
+	if err := c.do(ctx, http.MethodPost, c.basePlanURL(pkg, productID, basePlanID)+":"+action, &body, &r); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// DeleteBasePlan deletes a base plan. Only draft base plans can be deleted.
+func (c *Client) DeleteBasePlan(pkg, productID, basePlanID string) error {
+	return c.DeleteBasePlanContext(context.Background(), pkg, productID, basePlanID)
+}
+
+// DeleteBasePlanContext is like DeleteBasePlan but takes a context.
+func (c *Client) DeleteBasePlanContext(ctx context.Context, pkg, productID, basePlanID string) error {
+	return c.do(ctx, http.MethodDelete, c.basePlanURL(pkg, productID, basePlanID), nil, nil)
+}
+
+// MigrateBasePlanPrices moves existing subscribers of a base plan to its
+// current prices in the given regions. It accepts the LatencyTolerance
+// option.
+func (c *Client) MigrateBasePlanPrices(pkg, productID, basePlanID string, migrations []RegionalPriceMigration, regionsVersion string, opts ...Option) error {
+	return c.MigrateBasePlanPricesContext(context.Background(), pkg, productID, basePlanID, migrations, regionsVersion, opts...)
+}
+
+// MigrateBasePlanPricesContext is like MigrateBasePlanPrices but takes a
+// context.
+func (c *Client) MigrateBasePlanPricesContext(ctx context.Context, pkg, productID, basePlanID string, migrations []RegionalPriceMigration, regionsVersion string, opts ...Option) error {
+	body := struct {
+		RegionalPriceMigrations []RegionalPriceMigration `json:"regionalPriceMigrations"`
+		RegionsVersion          regions                  `json:"regionsVersion"`
+		LatencyTolerance        LatencyTolerance         `json:"latencyTolerance,omitempty"`
+	}{
+		RegionalPriceMigrations: migrations,
+		RegionsVersion:          regions{Version: regionsVersion},
+		LatencyTolerance:        latencyTolerance(opts),
+	}
+
+	return c.do(ctx, http.MethodPost, c.basePlanURL(pkg, productID, basePlanID)+":migratePrices", &body, nil)
+}
+
+// CreateOffer creates an offer of a base plan in draft state.
+func (c *Client) CreateOffer(pkg, productID, basePlanID string, o *SubscriptionOffer, regionsVersion string) (*SubscriptionOffer, error) {
+	return c.CreateOfferContext(context.Background(), pkg, productID, basePlanID, o, regionsVersion)
+}
+
+// CreateOfferContext is like CreateOffer but takes a context.
+func (c *Client) CreateOfferContext(ctx context.Context, pkg, productID, basePlanID string, o *SubscriptionOffer, regionsVersion string) (*SubscriptionOffer, error) {
+	v := url.Values{}
+	v.Set("offerId", o.OfferID)
+	v.Set("regionsVersion.version", regionsVersion)
+
+	var r SubscriptionOffer
+	if err := c.do(ctx, http.MethodPost, withQuery(c.offersURL(pkg, productID, basePlanID), v), o, &r); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// ActivateOffer activates an offer, making it available to eligible users.
+// It accepts the LatencyTolerance option.
+func (c *Client) ActivateOffer(pkg, productID, basePlanID, offerID string, opts ...Option) (*SubscriptionOffer, error) {
+	return c.ActivateOfferContext(context.Background(), pkg, productID, basePlanID, offerID, opts...)
+}
+
+// ActivateOfferContext is like ActivateOffer but takes a context.
+func (c *Client) ActivateOfferContext(ctx context.Context, pkg, productID, basePlanID, offerID string, opts ...Option) (*SubscriptionOffer, error) {
+	return c.offerAction(ctx, pkg, productID, basePlanID, offerID, "activate", opts)
+}
+
+// DeactivateOffer deactivates an offer, making it unavailable to new users.
+// It accepts the LatencyTolerance option.
+func (c *Client) DeactivateOffer(pkg, productID, basePlanID, offerID string, opts ...Option) (*SubscriptionOffer, error) {
+	return c.DeactivateOfferContext(context.Background(), pkg, productID, basePlanID, offerID, opts...)
+}
+
+// DeactivateOfferContext is like DeactivateOffer but takes a context.
+func (c *Client) DeactivateOfferContext(ctx context.Context, pkg, productID, basePlanID, offerID string, opts ...Option) (*SubscriptionOffer, error) {
+	return c.offerAction(ctx, pkg, productID, basePlanID, offerID, "deactivate", opts)
+}
+
+func (c *Client) offerAction(ctx context.Context, pkg, productID, basePlanID, offerID, action string, opts []Option) (*SubscriptionOffer, error) {
+	body := struct {
+		LatencyTolerance LatencyTolerance `json:"latencyTolerance,omitempty"`
+	}{
+		LatencyTolerance: latencyTolerance(opts),
+	}
+
+	u := c.offersURL(pkg, productID, basePlanID) + "/" + url.PathEscape(offerID) + ":" + action
+
+	var r SubscriptionOffer
+	if err := c.do(ctx, http.MethodPost, u, &body, &r); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// ListOffers lists a page of the offers of a base plan. A basePlanID of "-"
+// lists the offers of all base plans of the subscription.
+func (c *Client) ListOffers(pkg, productID, basePlanID, pageToken string) (*SubscriptionOffersResponse, error) {
+	return c.ListOffersContext(context.Background(), pkg, productID, basePlanID, pageToken)
+}
+
+// ListOffersContext is like ListOffers but takes a context.
+func (c *Client) ListOffersContext(ctx context.Context, pkg, productID, basePlanID, pageToken string) (*SubscriptionOffersResponse, error) {
+	v := url.Values{}
+	if pageToken != "" {
+		v.Set("pageToken", pageToken)
+	}
+
+	var r SubscriptionOffersResponse
+	if err := c.do(ctx, http.MethodGet, withQuery(c.offersURL(pkg, productID, basePlanID), v), nil, &r); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// ListAllOffers lists the offers of a base plan, following the page tokens
+// until all pages are fetched.
+func (c *Client) ListAllOffers(pkg, productID, basePlanID string) ([]SubscriptionOffer, error) {
+	return c.ListAllOffersContext(context.Background(), pkg, productID, basePlanID)
+}
+
+// ListAllOffersContext is like ListAllOffers but takes a context.
+func (c *Client) ListAllOffersContext(ctx context.Context, pkg, productID, basePlanID string) ([]SubscriptionOffer, error) {
+	var all []SubscriptionOffer
+
+	token := ""
+	for {
+		r, err := c.ListOffersContext(ctx, pkg, productID, basePlanID, token)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, r.SubscriptionOffers...)
+
+		if r.NextPageToken == "" {
+			return all, nil
+		}
+
+		token = r.NextPageToken
+	}
+}
@@ -0,0 +1,281 @@
+package playstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/brainleap/iap"
+)
+
+// maxNotificationBody limits the size of a Pub/Sub push request.
+const maxNotificationBody = 1 << 20
+
+// SubscriptionNotificationType is the data type for subscription notification
+// types.
+type SubscriptionNotificationType int
+
+// List of subscription notification types.
+const (
+	SNRecovered                 SubscriptionNotificationType = 1
+	SNRenewed                   SubscriptionNotificationType = 2
+	SNCanceled                  SubscriptionNotificationType = 3
+	SNPurchased                 SubscriptionNotificationType = 4
+	SNOnHold                    SubscriptionNotificationType = 5
+	SNInGracePeriod             SubscriptionNotificationType = 6
+	SNRestarted                 SubscriptionNotificationType = 7
+	SNPriceChangeConfirmed      SubscriptionNotificationType = 8
+	SNDeferred                  SubscriptionNotificationType = 9
+	SNPaused                    SubscriptionNotificationType = 10
+	SNPauseScheduleChanged      SubscriptionNotificationType = 11
+	SNRevoked                   SubscriptionNotificationType = 12
+	SNExpired                   SubscriptionNotificationType = 13
+	SNItemsChanged              SubscriptionNotificationType = 17
+	SNCancellationScheduled     SubscriptionNotificationType = 18
+	SNPriceChangeUpdated        SubscriptionNotificationType = 19
+	SNPendingPurchaseCanceled   SubscriptionNotificationType = 20
+	SNPriceStepUpConsentUpdated SubscriptionNotificationType = 22
+)
+
+// OneTimeProductNotificationType is the data type for one-time product
+// notification types.
+type OneTimeProductNotificationType int
+
+// List of one-time product notification types.
+const (
+	OTPPurchased OneTimeProductNotificationType = 1
+	OTPCanceled  OneTimeProductNotificationType = 2
+)
+
+// VoidedProductType is the data type for the product types of voided
+// purchase notifications.
+type VoidedProductType int
+
+// List of voided product types.
+const (
+	VPTSubscription VoidedProductType = 1
+	VPTOneTime      VoidedProductType = 2
+)
+
+// RefundType is the data type for refund types.
+type RefundType int
+
+// List of refund types.
+const (
+	RTFullRefund                 RefundType = 1
+	RTQuantityBasedPartialRefund RefundType = 2
+)
+
+// PushMessage is the envelope of a Cloud Pub/Sub push request.
+type PushMessage struct {
+	Message struct {
+		Attributes  map[string]string `json:"attributes"`
+		Data        []byte            `json:"data"`
+		MessageID   string            `json:"messageId"`
+		PublishTime string            `json:"publishTime"`
+	} `json:"message"`
+	Subscription string `json:"subscription"`
+}
+
+// DeveloperNotification is a Real-time Developer Notification. Exactly one of
+// the notification fields is set.
+type DeveloperNotification struct {
+	Version                    string                      `json:"version"`
+	PackageName                string                      `json:"packageName"`
+	EventTimeMillis            int64                       `json:"eventTimeMillis,string"`
+	SubscriptionNotification   *SubscriptionNotification   `json:"subscriptionNotification"`
+	OneTimeProductNotification *OneTimeProductNotification `json:"oneTimeProductNotification"`
+	VoidedPurchaseNotification *VoidedPurchaseNotification `json:"voidedPurchaseNotification"`
+	TestNotification           *TestNotification           `json:"testNotification"`
+}
+
+// SubscriptionNotification notifies about a change of a subscription purchase.
+type SubscriptionNotification struct {
+	Version          string                       `json:"version"`
+	NotificationType SubscriptionNotificationType `json:"notificationType"`
+	PurchaseToken    string                       `json:"purchaseToken"`
+	SubscriptionID   string                       `json:"subscriptionId"`
+}
+
+// OneTimeProductNotification notifies about a change of an in-app product
+// purchase.
+type OneTimeProductNotification struct {
+	Version          string                         `json:"version"`
+	NotificationType OneTimeProductNotificationType `json:"notificationType"`
+	PurchaseToken    string                         `json:"purchaseToken"`
+	SKU              string                         `json:"sku"`
+}
+
+// VoidedPurchaseNotification notifies about a voided purchase.
+type VoidedPurchaseNotification struct {
+	PurchaseToken string            `json:"purchaseToken"`
+	OrderID       string            `json:"orderId"`
+	ProductType   VoidedProductType `json:"productType"`
+	RefundType    RefundType        `json:"refundType"`
+}
+
+// TestNotification is sent when a test notification is published from the
+// Play Console.
+type TestNotification struct {
+	Version string `json:"version"`
+}
+
+// ParseNotification decodes a Pub/Sub push request body into a developer
+// notification.
+func ParseNotification(r io.Reader) (*DeveloperNotification, error) {
+	var m PushMessage
+
+	decoder := json.NewDecoder(r)
+	if err := decoder.Decode(&m); err != nil {
+		return nil, err
+	}
+
+	if len(m.Message.Data) == 0 {
+		return nil, errors.New("push message has no data")
+	}
+
+	var n DeveloperNotification
+	if err := json.Unmarshal(m.Message.Data, &n); err != nil {
+		return nil, err
+	}
+
+	return &n, nil
+}
+
+// NotificationHandler receives Real-time Developer Notifications pushed by
+// Cloud Pub/Sub and dispatches them to the callbacks.
+//
+// When Fetch is set, the purchase the notification refers to is fetched
+// through Client before the callback is called; otherwise the purchase
+// argument is nil. Callback errors are reported with a 500 status so that
+// Pub/Sub redelivers the message.
+//
+// Fetch errors returned by the Play API that are not retryable, such as
+// iap.ErrInvalidToken or iap.ErrPurchaseNotFound, would fail on every
+// redelivery. Such notifications are acknowledged without calling the
+// callback and reported to OnFetchError when it is set. Other fetch errors
+// are reported with a 500 status.
+//
+// When Auth is set, requests without a valid Pub/Sub OIDC token are rejected
+// with a 401 status.
+type NotificationHandler struct {
+	Client *Client
+	Fetch  bool
+	Auth   *OIDCVerifier
+
+	OnSubscription   func(ctx context.Context, n *DeveloperNotification, s *Subscription) error
+	OnOneTimeProduct func(ctx context.Context, n *DeveloperNotification, p *Product) error
+	OnVoidedPurchase func(ctx context.Context, n *DeveloperNotification) error
+	OnTest           func(ctx context.Context, n *DeveloperNotification) error
+	OnFetchError     func(ctx context.Context, n *DeveloperNotification, err error) error
+}
+
+// ServeHTTP implements http.Handler.
+func (h *NotificationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.Auth != nil {
+		if _, err := h.Auth.VerifyRequest(r); err != nil {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+	}
+
+	n, err := ParseNotification(http.MaxBytesReader(w, r.Body, maxNotificationBody))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.dispatch(r.Context(), n); err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var errNoClient = errors.New("notification handler has no client to fetch purchases")
+
+func (h *NotificationHandler) dispatch(ctx context.Context, n *DeveloperNotification) error {
+	switch {
+	case n.SubscriptionNotification != nil:
+		if h.OnSubscription == nil {
+			return nil
+		}
+
+		var s *Subscription
+		if h.Fetch {
+			if h.Client == nil {
+				return errNoClient
+			}
+
+			sn := n.SubscriptionNotification
+
+			var err error
+			s, err = h.Client.GetSubscriptionContext(ctx, n.PackageName, sn.SubscriptionID, sn.PurchaseToken)
+			if err != nil {
+				return h.fetchError(ctx, n, err)
+			}
+		}
+
+		return h.OnSubscription(ctx, n, s)
+	case n.OneTimeProductNotification != nil:
+		if h.OnOneTimeProduct == nil {
+			return nil
+		}
+
+		var p *Product
+		if h.Fetch {
+			if h.Client == nil {
+				return errNoClient
+			}
+
+			pn := n.OneTimeProductNotification
+
+			var err error
+			p, err = h.Client.GetProductContext(ctx, n.PackageName, pn.SKU, pn.PurchaseToken)
+			if err != nil {
+				return h.fetchError(ctx, n, err)
+			}
+		}
+
+		return h.OnOneTimeProduct(ctx, n, p)
+	case n.VoidedPurchaseNotification != nil:
+		if h.OnVoidedPurchase == nil {
+			return nil
+		}
+
+		return h.OnVoidedPurchase(ctx, n)
+	case n.TestNotification != nil:
+		if h.OnTest == nil {
+			return nil
+		}
+
+		return h.OnTest(ctx, n)
+	default:
+		return nil
+	}
+}
+
+// fetchError returns err if fetching the purchase of n should be retried.
+// Otherwise the notification is acknowledged and err reported to
+// OnFetchError.
+func (h *NotificationHandler) fetchError(ctx context.Context, n *DeveloperNotification, err error) error {
+	var e *iap.Error
+	if !errors.As(err, &e) || e.Retryable {
+		return err
+	}
+
+	if h.OnFetchError == nil {
+		return nil
+	}
+
+	return h.OnFetchError(ctx, n, err)
+}
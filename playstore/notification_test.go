@@ -0,0 +1,333 @@
+package playstore_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brainleap/iap"
+	"github.com/brainleap/iap/iaptest"
+	"github.com/brainleap/iap/playstore"
+)
+
+const testPackage = "com.example.app"
+
+func pushBody(t *testing.T, n *playstore.DeveloperNotification) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"message":      map[string]interface{}{"data": data, "messageId": "1"},
+		"subscription": "projects/example/subscriptions/rtdn",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return body
+}
+
+func serveNotification(h http.Handler, method string, body []byte) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, "/rtdn", bytes.NewReader(body)))
+	return w
+}
+
+func TestNotificationHandler(t *testing.T) {
+	var called string
+	h := &playstore.NotificationHandler{
+		OnSubscription: func(ctx context.Context, n *playstore.DeveloperNotification, s *playstore.Subscription) error {
+			called = "subscription"
+			if s != nil {
+				t.Errorf("subscription = %+v, want nil without Fetch", s)
+			}
+			if n.SubscriptionNotification.SubscriptionID == "failing" {
+				return errors.New("database is down")
+			}
+			return nil
+		},
+		OnOneTimeProduct: func(ctx context.Context, n *playstore.DeveloperNotification, p *playstore.Product) error {
+			called = "product"
+			if p != nil {
+				t.Errorf("product = %+v, want nil without Fetch", p)
+			}
+			return nil
+		},
+		OnVoidedPurchase: func(ctx context.Context, n *playstore.DeveloperNotification) error {
+			called = "voided"
+			return nil
+		},
+		OnTest: func(ctx context.Context, n *playstore.DeveloperNotification) error {
+			called = "test"
+			return nil
+		},
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		body       []byte
+		wantStatus int
+		wantCalled string
+	}{
+		{
+			name: "subscription",
+			body: pushBody(t, &playstore.DeveloperNotification{
+				PackageName: testPackage,
+				SubscriptionNotification: &playstore.SubscriptionNotification{
+					NotificationType: playstore.SNRenewed,
+					PurchaseToken:    "token",
+					SubscriptionID:   "monthly",
+				},
+			}),
+			wantStatus: http.StatusNoContent,
+			wantCalled: "subscription",
+		},
+		{
+			name: "one-time product",
+			body: pushBody(t, &playstore.DeveloperNotification{
+				PackageName: testPackage,
+				OneTimeProductNotification: &playstore.OneTimeProductNotification{
+					NotificationType: playstore.OTPPurchased,
+					PurchaseToken:    "token",
+					SKU:              "coins_100",
+				},
+			}),
+			wantStatus: http.StatusNoContent,
+			wantCalled: "product",
+		},
+		{
+			name: "voided purchase",
+			body: pushBody(t, &playstore.DeveloperNotification{
+				PackageName:                testPackage,
+				VoidedPurchaseNotification: &playstore.VoidedPurchaseNotification{PurchaseToken: "token", OrderID: "GPA.1"},
+			}),
+			wantStatus: http.StatusNoContent,
+			wantCalled: "voided",
+		},
+		{
+			name: "test",
+			body: pushBody(t, &playstore.DeveloperNotification{
+				PackageName:      testPackage,
+				TestNotification: &playstore.TestNotification{Version: "1.0"},
+			}),
+			wantStatus: http.StatusNoContent,
+			wantCalled: "test",
+		},
+		{
+			name:       "unknown notification",
+			body:       pushBody(t, &playstore.DeveloperNotification{PackageName: testPackage}),
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name: "callback error",
+			body: pushBody(t, &playstore.DeveloperNotification{
+				PackageName: testPackage,
+				SubscriptionNotification: &playstore.SubscriptionNotification{
+					NotificationType: playstore.SNRenewed,
+					PurchaseToken:    "token",
+					SubscriptionID:   "failing",
+				},
+			}),
+			wantStatus: http.StatusInternalServerError,
+			wantCalled: "subscription",
+		},
+		{
+			name:       "method not allowed",
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "malformed body",
+			body:       []byte(`{"message":`),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "no data",
+			body:       []byte(`{"message":{"messageId":"1"}}`),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "body too large",
+			body:       []byte(`{"message":{"data":"` + strings.Repeat("A", 2<<20) + `"}}`),
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called = ""
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+
+			w := serveNotification(h, method, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+
+			if called != tt.wantCalled {
+				t.Errorf("called = %q, want %q", called, tt.wantCalled)
+			}
+
+			// Error responses must not leak error details.
+			if w.Code >= 400 && strings.TrimSpace(w.Body.String()) != http.StatusText(w.Code) {
+				t.Errorf("body = %q, want %q", w.Body, http.StatusText(w.Code))
+			}
+		})
+	}
+}
+
+func TestNotificationHandlerFetch(t *testing.T) {
+	store := iaptest.NewPlayStore()
+	defer store.Close()
+
+	store.AddProduct(testPackage, "coins_100", "product-token", &playstore.Product{OrderID: "GPA.1"})
+	store.AddSubscription(testPackage, "monthly", "subscription-token", &playstore.Subscription{OrderID: "GPA.2"})
+
+	c, err := store.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Fetch the access token, so that failures only hit purchase requests.
+	if _, err := c.GetProduct(testPackage, "coins_100", "product-token"); err != nil {
+		t.Fatal(err)
+	}
+
+	subscription := func(token string) []byte {
+		return pushBody(t, &playstore.DeveloperNotification{
+			PackageName: testPackage,
+			SubscriptionNotification: &playstore.SubscriptionNotification{
+				NotificationType: playstore.SNRenewed,
+				PurchaseToken:    token,
+				SubscriptionID:   "monthly",
+			},
+		})
+	}
+
+	product := func(token string) []byte {
+		return pushBody(t, &playstore.DeveloperNotification{
+			PackageName: testPackage,
+			OneTimeProductNotification: &playstore.OneTimeProductNotification{
+				NotificationType: playstore.OTPPurchased,
+				PurchaseToken:    token,
+				SKU:              "coins_100",
+			},
+		})
+	}
+
+	tests := []struct {
+		name           string
+		client         *playstore.Client
+		body           []byte
+		failNext       int
+		onFetchError   error
+		wantStatus     int
+		wantOrderID    string
+		wantFetchError error
+	}{
+		{
+			name:        "subscription",
+			client:      c,
+			body:        subscription("subscription-token"),
+			wantStatus:  http.StatusNoContent,
+			wantOrderID: "GPA.2",
+		},
+		{
+			name:        "product",
+			client:      c,
+			body:        product("product-token"),
+			wantStatus:  http.StatusNoContent,
+			wantOrderID: "GPA.1",
+		},
+		{
+			name:           "unknown subscription",
+			client:         c,
+			body:           subscription("unknown"),
+			wantStatus:     http.StatusNoContent,
+			wantFetchError: iap.ErrPurchaseNotFound,
+		},
+		{
+			name:           "unknown product",
+			client:         c,
+			body:           product("unknown"),
+			wantStatus:     http.StatusNoContent,
+			wantFetchError: iap.ErrPurchaseNotFound,
+		},
+		{
+			name:           "fetch error callback fails",
+			client:         c,
+			body:           product("unknown"),
+			onFetchError:   errors.New("database is down"),
+			wantStatus:     http.StatusInternalServerError,
+			wantFetchError: iap.ErrPurchaseNotFound,
+		},
+		{
+			name:       "retryable error",
+			client:     c,
+			body:       subscription("subscription-token"),
+			failNext:   http.StatusServiceUnavailable,
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "no client",
+			body:       subscription("subscription-token"),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				orderID  string
+				fetchErr error
+			)
+
+			h := &playstore.NotificationHandler{
+				Client: tt.client,
+				Fetch:  true,
+				OnSubscription: func(ctx context.Context, n *playstore.DeveloperNotification, s *playstore.Subscription) error {
+					orderID = s.OrderID
+					return nil
+				},
+				OnOneTimeProduct: func(ctx context.Context, n *playstore.DeveloperNotification, p *playstore.Product) error {
+					orderID = p.OrderID
+					return nil
+				},
+				OnFetchError: func(ctx context.Context, n *playstore.DeveloperNotification, err error) error {
+					fetchErr = err
+					return tt.onFetchError
+				},
+			}
+
+			if tt.failNext != 0 {
+				store.FailNext(1, tt.failNext, "")
+			}
+
+			w := serveNotification(h, http.MethodPost, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+
+			if orderID != tt.wantOrderID {
+				t.Errorf("order ID = %q, want %q", orderID, tt.wantOrderID)
+			}
+
+			if !errors.Is(fetchErr, tt.wantFetchError) || (fetchErr == nil) != (tt.wantFetchError == nil) {
+				t.Errorf("fetch error = %v, want %v", fetchErr, tt.wantFetchError)
+			}
+		})
+	}
+}
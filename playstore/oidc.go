@@ -0,0 +1,336 @@
+package playstore
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	googleCertsURL = "https://www.googleapis.com/oauth2/v3/certs"
+	clockSkew      = time.Minute
+	defaultKeysTTL = time.Hour
+)
+
+var googleIssuers = []string{"accounts.google.com", "https://accounts.google.com"}
+
+// KeySource provides the RSA public keys that OIDC tokens are signed with,
+// indexed by key ID.
+type KeySource interface {
+	Keys(ctx context.Context) (map[string]*rsa.PublicKey, error)
+}
+
+// JWK is an RSA JSON Web Key.
+type JWK struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// StaticKeys is a fixed key source.
+type StaticKeys map[string]*rsa.PublicKey
+
+// Keys implements KeySource.
+func (k StaticKeys) Keys(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	return k, nil
+}
+
+// ParseJWKS parses a JSON Web Key Set into a static key source. Keys other
+// than RSA are ignored.
+func ParseJWKS(data []byte) (StaticKeys, error) {
+	var set JWKS
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	return set.keys()
+}
+
+func (set *JWKS) keys() (StaticKeys, error) {
+	keys := StaticKeys{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	return keys, nil
+}
+
+// RemoteKeys is a key source fetching a JSON Web Key Set over HTTP. Keys are
+// cached for the max-age of the response. Client defaults to
+// http.DefaultClient.
+type RemoteKeys struct {
+	URL    string
+	Client *http.Client
+
+	mu      sync.Mutex
+	keys    StaticKeys
+	expires time.Time
+}
+
+// NewGoogleKeys creates a key source for the keys Google signs OIDC tokens
+// with, fetched through the given HTTP client.
+func NewGoogleKeys(c *http.Client) *RemoteKeys {
+	return &RemoteKeys{URL: googleCertsURL, Client: c}
+}
+
+// Keys implements KeySource.
+func (k *RemoteKeys) Keys(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.keys != nil && time.Now().Before(k.expires) {
+		return k.keys, nil
+	}
+
+	client := k.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed with status: %d", res.StatusCode)
+	}
+
+	var set JWKS
+
+	decoder := json.NewDecoder(res.Body)
+	if err := decoder.Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys, err := set.keys()
+	if err != nil {
+		return nil, err
+	}
+
+	k.keys = keys
+	k.expires = time.Now().Add(maxAge(res.Header.Get("Cache-Control")))
+
+	return keys, nil
+}
+
+func maxAge(cacheControl string) time.Duration {
+	for _, d := range strings.Split(cacheControl, ",") {
+		d = strings.TrimSpace(d)
+		if !strings.HasPrefix(d, "max-age=") {
+			continue
+		}
+
+		s, err := strconv.Atoi(strings.TrimPrefix(d, "max-age="))
+		if err == nil && s > 0 {
+			return time.Duration(s) * time.Second
+		}
+	}
+
+	return defaultKeysTTL
+}
+
+// OIDCClaims are the claims of a Pub/Sub push OIDC token.
+type OIDCClaims struct {
+	Issuer        string `json:"iss"`
+	Subject       string `json:"sub"`
+	Audience      string `json:"aud"`
+	AuthorizedBy  string `json:"azp"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	IssuedAt      int64  `json:"iat"`
+	ExpiresAt     int64  `json:"exp"`
+}
+
+// OIDCVerifier verifies the OIDC tokens Cloud Pub/Sub attaches to push
+// requests.
+//
+// Audience is the audience configured on the push subscription and Email is
+// the service account the subscription pushes as; both are required. Tokens
+// must carry a verified email. Issuers defaults to Google's issuers.
+type OIDCVerifier struct {
+	Keys     KeySource
+	Audience string
+	Email    string
+	Issuers  []string
+}
+
+// NewOIDCVerifier creates a new OIDC verifier fetching Google's keys through
+// the given HTTP client, typically the client of the NotificationHandler.
+func NewOIDCVerifier(audience, email string, c *http.Client) (*OIDCVerifier, error) {
+	if audience == "" {
+		return nil, errors.New("audience is required")
+	}
+
+	if email == "" {
+		return nil, errors.New("service account email is required")
+	}
+
+	return &OIDCVerifier{
+		Keys:     NewGoogleKeys(c),
+		Audience: audience,
+		Email:    email,
+	}, nil
+}
+
+// VerifyRequest verifies the bearer token in the Authorization header of a
+// push request. Keys are fetched with the context of the request.
+func (v *OIDCVerifier) VerifyRequest(r *http.Request) (*OIDCClaims, error) {
+	h := r.Header.Get("Authorization")
+	if len(h) < 7 || !strings.EqualFold(h[:7], "Bearer ") {
+		return nil, errors.New("missing bearer token")
+	}
+
+	return v.VerifyContext(r.Context(), h[7:])
+}
+
+// Verify verifies the signature and claims of a token.
+func (v *OIDCVerifier) Verify(token string) (*OIDCClaims, error) {
+	return v.VerifyContext(context.Background(), token)
+}
+
+// VerifyContext is like Verify but takes a context.
+func (v *OIDCVerifier) VerifyContext(ctx context.Context, token string) (*OIDCClaims, error) {
+	if v.Audience == "" || v.Email == "" {
+		return nil, errors.New("verifier has no audience or email")
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("unexpected signing algorithm: %s", header.Alg)
+	}
+
+	keys, err := v.Keys.Keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := keys[header.Kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %s", header.Kid)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return nil, err
+	}
+
+	var c OIDCClaims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return nil, err
+	}
+
+	if err := v.validate(&c, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func (v *OIDCVerifier) validate(c *OIDCClaims, now time.Time) error {
+	issuers := v.Issuers
+	if len(issuers) == 0 {
+		issuers = googleIssuers
+	}
+
+	valid := false
+	for _, iss := range issuers {
+		if c.Issuer == iss {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return fmt.Errorf("unexpected issuer: %s", c.Issuer)
+	}
+
+	if c.Audience != v.Audience {
+		return fmt.Errorf("unexpected audience: %s", c.Audience)
+	}
+
+	if !now.Before(time.Unix(c.ExpiresAt, 0).Add(clockSkew)) {
+		return errors.New("token is expired")
+	}
+
+	if now.Add(clockSkew).Before(time.Unix(c.IssuedAt, 0)) {
+		return errors.New("token is issued in the future")
+	}
+
+	if c.Email != v.Email {
+		return fmt.Errorf("unexpected email: %s", c.Email)
+	}
+
+	if !c.EmailVerified {
+		return errors.New("email is not verified")
+	}
+
+	return nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
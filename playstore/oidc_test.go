@@ -0,0 +1,321 @@
+package playstore_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brainleap/iap/playstore"
+)
+
+const (
+	testAudience = "https://example.com/rtdn"
+	testEmail    = "pubsub@example.iam.gserviceaccount.com"
+)
+
+func generateRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, header, claims interface{}) string {
+	t.Helper()
+
+	h, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func validClaims() playstore.OIDCClaims {
+	now := time.Now()
+
+	return playstore.OIDCClaims{
+		Issuer:        "https://accounts.google.com",
+		Subject:       "1234",
+		Audience:      testAudience,
+		Email:         testEmail,
+		EmailVerified: true,
+		IssuedAt:      now.Add(-time.Minute).Unix(),
+		ExpiresAt:     now.Add(time.Hour).Unix(),
+	}
+}
+
+func TestOIDCVerifierVerify(t *testing.T) {
+	key := generateRSAKey(t)
+	other := generateRSAKey(t)
+
+	v := &playstore.OIDCVerifier{
+		Keys:     playstore.StaticKeys{"kid1": &key.PublicKey},
+		Audience: testAudience,
+		Email:    testEmail,
+	}
+
+	header := map[string]string{"alg": "RS256", "kid": "kid1", "typ": "JWT"}
+
+	tests := []struct {
+		name    string
+		key     *rsa.PrivateKey
+		header  map[string]string
+		claims  func(c *playstore.OIDCClaims)
+		wantErr bool
+	}{
+		{name: "valid"},
+		{name: "legacy issuer", claims: func(c *playstore.OIDCClaims) { c.Issuer = "accounts.google.com" }},
+		{name: "unexpected issuer", claims: func(c *playstore.OIDCClaims) { c.Issuer = "https://example.com" }, wantErr: true},
+		{name: "unexpected audience", claims: func(c *playstore.OIDCClaims) { c.Audience = "https://example.com/other" }, wantErr: true},
+		{name: "unexpected email", claims: func(c *playstore.OIDCClaims) { c.Email = "other@example.com" }, wantErr: true},
+		{name: "unverified email", claims: func(c *playstore.OIDCClaims) { c.EmailVerified = false }, wantErr: true},
+		{name: "expired", claims: func(c *playstore.OIDCClaims) { c.ExpiresAt = time.Now().Add(-2 * time.Minute).Unix() }, wantErr: true},
+		{name: "issued in the future", claims: func(c *playstore.OIDCClaims) { c.IssuedAt = time.Now().Add(time.Hour).Unix() }, wantErr: true},
+		{name: "wrong key", key: other, wantErr: true},
+		{name: "unknown key id", header: map[string]string{"alg": "RS256", "kid": "kid2"}, wantErr: true},
+		{name: "unexpected algorithm", header: map[string]string{"alg": "HS256", "kid": "kid1"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validClaims()
+			if tt.claims != nil {
+				tt.claims(&c)
+			}
+
+			k, h := key, header
+			if tt.key != nil {
+				k = tt.key
+			}
+			if tt.header != nil {
+				h = tt.header
+			}
+
+			got, err := v.Verify(signRS256(t, k, h, &c))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Verify() succeeded, want error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+
+			if got.Subject != c.Subject {
+				t.Errorf("Verify() subject = %q, want %q", got.Subject, c.Subject)
+			}
+		})
+	}
+}
+
+func TestOIDCVerifierVerifyMalformed(t *testing.T) {
+	key := generateRSAKey(t)
+
+	v := &playstore.OIDCVerifier{
+		Keys:     playstore.StaticKeys{"kid1": &key.PublicKey},
+		Audience: testAudience,
+		Email:    testEmail,
+	}
+
+	for _, token := range []string{"", "a.b", "a.b.c", "!.!.!"} {
+		if _, err := v.Verify(token); err == nil {
+			t.Errorf("Verify(%q) succeeded, want error", token)
+		}
+	}
+}
+
+func TestOIDCVerifierRequiresEmail(t *testing.T) {
+	key := generateRSAKey(t)
+	c := validClaims()
+	token := signRS256(t, key, map[string]string{"alg": "RS256", "kid": "kid1"}, &c)
+
+	v := &playstore.OIDCVerifier{
+		Keys:     playstore.StaticKeys{"kid1": &key.PublicKey},
+		Audience: testAudience,
+	}
+
+	if _, err := v.Verify(token); err == nil {
+		t.Error("Verify() without email succeeded, want error")
+	}
+
+	if _, err := playstore.NewOIDCVerifier(testAudience, "", nil); err == nil {
+		t.Error("NewOIDCVerifier() without email succeeded, want error")
+	}
+
+	if _, err := playstore.NewOIDCVerifier("", testEmail, nil); err == nil {
+		t.Error("NewOIDCVerifier() without audience succeeded, want error")
+	}
+}
+
+func jwksHandler(t *testing.T, kid string, pub *rsa.PublicKey, fetches *int) http.Handler {
+	t.Helper()
+
+	set := playstore.JWKS{Keys: []playstore.JWK{
+		{Kty: "EC", Kid: "ignored"},
+		{
+			Kid: kid,
+			Kty: "RSA",
+			Alg: "RS256",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		},
+	}}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*fetches++
+
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		json.NewEncoder(w).Encode(&set)
+	})
+}
+
+func TestRemoteKeys(t *testing.T) {
+	key := generateRSAKey(t)
+
+	fetches := 0
+	srv := httptest.NewServer(jwksHandler(t, "kid1", &key.PublicKey, &fetches))
+	defer srv.Close()
+
+	k := &playstore.RemoteKeys{URL: srv.URL, Client: srv.Client()}
+
+	for i := 0; i < 2; i++ {
+		keys, err := k.Keys(context.Background())
+		if err != nil {
+			t.Fatalf("Keys() error = %v", err)
+		}
+
+		if len(keys) != 1 || keys["kid1"] == nil || keys["kid1"].N.Cmp(key.N) != 0 {
+			t.Fatalf("Keys() = %v, want kid1", keys)
+		}
+	}
+
+	if fetches != 1 {
+		t.Errorf("fetched keys %d times, want 1", fetches)
+	}
+}
+
+func TestRemoteKeysContext(t *testing.T) {
+	key := generateRSAKey(t)
+
+	fetches := 0
+	srv := httptest.NewServer(jwksHandler(t, "kid1", &key.PublicKey, &fetches))
+	defer srv.Close()
+
+	k := &playstore.RemoteKeys{URL: srv.URL, Client: srv.Client()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := k.Keys(ctx); err == nil {
+		t.Error("Keys() with canceled context succeeded, want error")
+	}
+}
+
+func TestNotificationHandlerAuth(t *testing.T) {
+	key := generateRSAKey(t)
+
+	fetches := 0
+	srv := httptest.NewServer(jwksHandler(t, "kid1", &key.PublicKey, &fetches))
+	defer srv.Close()
+
+	v, err := playstore.NewOIDCVerifier(testAudience, testEmail, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Keys.(*playstore.RemoteKeys).URL = srv.URL
+
+	called := false
+	h := &playstore.NotificationHandler{
+		Auth: v,
+		OnTest: func(ctx context.Context, n *playstore.DeveloperNotification) error {
+			called = true
+			return nil
+		},
+	}
+
+	c := validClaims()
+	valid := signRS256(t, key, map[string]string{"alg": "RS256", "kid": "kid1"}, &c)
+
+	c.EmailVerified = false
+	unverified := signRS256(t, key, map[string]string{"alg": "RS256", "kid": "kid1"}, &c)
+
+	data, err := json.Marshal(&playstore.DeveloperNotification{
+		Version:          "1.0",
+		PackageName:      "com.example.app",
+		TestNotification: &playstore.TestNotification{Version: "1.0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"message":      map[string]interface{}{"data": data, "messageId": "1"},
+		"subscription": "projects/example/subscriptions/rtdn",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+	}{
+		{name: "valid", authorization: "Bearer " + valid, wantStatus: http.StatusNoContent},
+		{name: "missing", wantStatus: http.StatusUnauthorized},
+		{name: "unverified email", authorization: "Bearer " + unverified, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called = false
+
+			r := httptest.NewRequest(http.MethodPost, "/rtdn", strings.NewReader(string(body)))
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+
+			if called != (tt.wantStatus == http.StatusNoContent) {
+				t.Errorf("OnTest called = %v", called)
+			}
+		})
+	}
+}
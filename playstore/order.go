@@ -0,0 +1,224 @@
+package playstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ErrNoOrderID is returned when a purchase has no order ID, as is the case
+// for test purchases.
+var ErrNoOrderID = errors.New("purchase has no order id")
+
+// OrderState is the data type for order states.
+type OrderState string
+
+// List of order states.
+const (
+	OSTPending           OrderState = "PENDING"
+	OSTProcessed         OrderState = "PROCESSED"
+	OSTCanceled          OrderState = "CANCELED"
+	OSTPendingRefund     OrderState = "PENDING_REFUND"
+	OSTPartiallyRefunded OrderState = "PARTIALLY_REFUNDED"
+	OSTRefunded          OrderState = "REFUNDED"
+)
+
+// Order is a Google Play order of an in-app product or subscription.
+type Order struct {
+	OrderID                         string          `json:"orderId"`
+	PurchaseToken                   string          `json:"purchaseToken"`
+	State                           OrderState      `json:"state"`
+	CreateTime                      time.Time       `json:"createTime"`
+	LastEventTime                   time.Time       `json:"lastEventTime"`
+	BuyerAddress                    *BuyerAddress   `json:"buyerAddress"`
+	Total                           *Money          `json:"total"`
+	Tax                             *Money          `json:"tax"`
+	DeveloperRevenueInBuyerCurrency *Money          `json:"developerRevenueInBuyerCurrency"`
+	LineItems                       []OrderLineItem `json:"lineItems"`
+	OrderHistory                    *OrderHistory   `json:"orderHistory"`
+}
+
+// BuyerAddress is the coarse address of the buyer of an order.
+type BuyerAddress struct {
+	BuyerState    string `json:"buyerState"`
+	BuyerCountry  string `json:"buyerCountry"`
+	BuyerPostcode string `json:"buyerPostcode"`
+}
+
+// OrderLineItem is an item of an order.
+type OrderLineItem struct {
+	ProductTitle           string                  `json:"productTitle"`
+	ProductID              string                  `json:"productId"`
+	ListingPrice           *Money                  `json:"listingPrice"`
+	Total                  *Money                  `json:"total"`
+	Tax                    *Money                  `json:"tax"`
+	OneTimePurchaseDetails *OneTimePurchaseDetails `json:"oneTimePurchaseDetails"`
+	SubscriptionDetails    *SubscriptionDetails    `json:"subscriptionDetails"`
+}
+
+// OneTimePurchaseDetails are the details of a one-time purchase line item.
+type OneTimePurchaseDetails struct {
+	Quantity         int    `json:"quantity"`
+	OfferID          string `json:"offerId"`
+	PurchaseOptionID string `json:"purchaseOptionId"`
+}
+
+// SubscriptionDetails are the details of a subscription line item.
+type SubscriptionDetails struct {
+	BasePlanID             string    `json:"basePlanId"`
+	OfferID                string    `json:"offerId"`
+	OfferPhase             string    `json:"offerPhase"`
+	ServicePeriodStartTime time.Time `json:"servicePeriodStartTime"`
+	ServicePeriodEndTime   time.Time `json:"servicePeriodEndTime"`
+}
+
+// OrderHistory contains the events of an order.
+type OrderHistory struct {
+	ProcessedEvent      *OrderEvent          `json:"processedEvent"`
+	CancellationEvent   *OrderEvent          `json:"cancellationEvent"`
+	RefundEvent         *RefundEvent         `json:"refundEvent"`
+	PartialRefundEvents []PartialRefundEvent `json:"partialRefundEvents"`
+}
+
+// OrderEvent is an event of an order.
+type OrderEvent struct {
+	EventTime time.Time `json:"eventTime"`
+}
+
+// RefundEvent is the full refund of an order.
+type RefundEvent struct {
+	EventTime     time.Time      `json:"eventTime"`
+	RefundDetails *RefundDetails `json:"refundDetails"`
+	RefundReason  string         `json:"refundReason"`
+}
+
+// PartialRefundEvent is a partial refund of an order.
+type PartialRefundEvent struct {
+	CreateTime    time.Time      `json:"createTime"`
+	ProcessTime   time.Time      `json:"processTime"`
+	State         string         `json:"state"`
+	RefundDetails *RefundDetails `json:"refundDetails"`
+}
+
+// RefundDetails are the amounts of a refund.
+type RefundDetails struct {
+	Total *Money `json:"total"`
+	Tax   *Money `json:"tax"`
+}
+
+func (c *Client) orderURL(pkg, orderID string) string {
+	return fmt.Sprintf(
+		"%s/applications/%s/orders/%s",
+		c.baseURL(),
+		url.PathEscape(pkg),
+		url.PathEscape(orderID),
+	)
+}
+
+// RefundOrder refunds an order of an in-app product or subscription. If
+// revoke is set, the purchase is revoked as well, so the user loses access to
+// it.
+func (c *Client) RefundOrder(pkg, orderID string, revoke bool) error {
+	return c.RefundOrderContext(context.Background(), pkg, orderID, revoke)
+}
+
+// RefundOrderContext is like RefundOrder but takes a context.
+func (c *Client) RefundOrderContext(ctx context.Context, pkg, orderID string, revoke bool) error {
+	u := c.orderURL(pkg, orderID) + ":refund"
+	if revoke {
+		u += "?revoke=" + strconv.FormatBool(revoke)
+	}
+
+	return c.do(ctx, http.MethodPost, u, nil, nil)
+}
+
+// RefundProduct refunds the order of an in-app product purchase. See
+// RefundOrder.
+func (c *Client) RefundProduct(pkg, prod, token string, revoke bool) error {
+	return c.RefundProductContext(context.Background(), pkg, prod, token, revoke)
+}
+
+// RefundProductContext is like RefundProduct but takes a context.
+func (c *Client) RefundProductContext(ctx context.Context, pkg, prod, token string, revoke bool) error {
+	p, err := c.GetProductContext(ctx, pkg, prod, token)
+	if err != nil {
+		return err
+	}
+
+	if p.OrderID == "" {
+		return ErrNoOrderID
+	}
+
+	return c.RefundOrderContext(ctx, pkg, p.OrderID, revoke)
+}
+
+// RefundSubscriptionOrder refunds the latest order of a subscription
+// purchase. Unlike RefundSubscription, it can revoke the subscription in the
+// same call. See RefundOrder.
+func (c *Client) RefundSubscriptionOrder(pkg, sub, token string, revoke bool) error {
+	return c.RefundSubscriptionOrderContext(context.Background(), pkg, sub, token, revoke)
+}
+
+// RefundSubscriptionOrderContext is like RefundSubscriptionOrder but takes a
+// context.
+func (c *Client) RefundSubscriptionOrderContext(ctx context.Context, pkg, sub, token string, revoke bool) error {
+	s, err := c.GetSubscriptionContext(ctx, pkg, sub, token)
+	if err != nil {
+		return err
+	}
+
+	if s.OrderID == "" {
+		return ErrNoOrderID
+	}
+
+	return c.RefundOrderContext(ctx, pkg, s.OrderID, revoke)
+}
+
+// GetOrder gets an order. Only orders of the last three years can be looked
+// up.
+func (c *Client) GetOrder(pkg, orderID string) (*Order, error) {
+	return c.GetOrderContext(context.Background(), pkg, orderID)
+}
+
+// GetOrderContext is like GetOrder but takes a context.
+func (c *Client) GetOrderContext(ctx context.Context, pkg, orderID string) (*Order, error) {
+	var o Order
+	if err := c.do(ctx, http.MethodGet, c.orderURL(pkg, orderID), nil, &o); err != nil {
+		return nil, err
+	}
+
+	return &o, nil
+}
+
+// GetOrders gets several orders in one request.
+func (c *Client) GetOrders(pkg string, orderIDs []string) ([]Order, error) {
+	return c.GetOrdersContext(context.Background(), pkg, orderIDs)
+}
+
+// GetOrdersContext is like GetOrders but takes a context.
+func (c *Client) GetOrdersContext(ctx context.Context, pkg string, orderIDs []string) ([]Order, error) {
+	v := url.Values{}
+	for _, id := range orderIDs {
+		v.Add("orderIds", id)
+	}
+
+	u := fmt.Sprintf(
+		"%s/applications/%s/orders:batchGet",
+		c.baseURL(),
+		url.PathEscape(pkg),
+	)
+
+	var r struct {
+		Orders []Order `json:"orders"`
+	}
+
+	if err := c.do(ctx, http.MethodGet, withQuery(u, v), nil, &r); err != nil {
+		return nil, err
+	}
+
+	return r.Orders, nil
+}
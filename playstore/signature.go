@@ -0,0 +1,86 @@
+package playstore
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidSignature is returned when purchase data does not match its
+// signature.
+var ErrInvalidSignature = errors.New("invalid purchase signature")
+
+// PurchaseDataState is the data type for purchase states reported on the
+// device.
+type PurchaseDataState int
+
+// List of purchase data states.
+const (
+	PDPurchased PurchaseDataState = 0
+	PDCanceled  PurchaseDataState = 1
+	PDRefunded  PurchaseDataState = 2
+	PDPending   PurchaseDataState = 4
+)
+
+// PurchaseData is the purchase the billing library returns on the device,
+// i.e. INAPP_PURCHASE_DATA or Purchase.getOriginalJson().
+type PurchaseData struct {
+	OrderID             string            `json:"orderId"`
+	PackageName         string            `json:"packageName"`
+	ProductID           string            `json:"productId"`
+	ProductIDs          []string          `json:"productIds"`
+	PurchaseTime        int64             `json:"purchaseTime"`
+	PurchaseState       PurchaseDataState `json:"purchaseState"`
+	PurchaseToken       string            `json:"purchaseToken"`
+	ObfuscatedAccountID string            `json:"obfuscatedAccountId"`
+	ObfuscatedProfileID string            `json:"obfuscatedProfileId"`
+	Quantity            int               `json:"quantity"`
+	Acknowledged        bool              `json:"acknowledged"`
+	AutoRenewing        bool              `json:"autoRenewing"`
+	DeveloperPayload    string            `json:"developerPayload"`
+}
+
+// VerifySignature verifies the SHA1withRSA signature of purchase data with
+// the app's base64 encoded license key and decodes the purchase.
+func VerifySignature(publicKey, data, signature string) (*PurchaseData, error) {
+	der, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	pub, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("license key is not an rsa key")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	digest := sha1.Sum([]byte(data))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA1, digest[:], sig); err != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	var p PurchaseData
+	if err := json.Unmarshal([]byte(data), &p); err != nil {
+		return nil, err
+	}
+
+	// The quantity is only present for multi-quantity purchases.
+	if p.Quantity == 0 {
+		p.Quantity = 1
+	}
+
+	return &p, nil
+}
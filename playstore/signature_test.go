@@ -0,0 +1,116 @@
+package playstore_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"testing"
+
+	"github.com/brainleap/iap/playstore"
+)
+
+func licenseKey(t *testing.T, key *rsa.PrivateKey) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return base64.StdEncoding.EncodeToString(der)
+}
+
+func signSHA1(t *testing.T, key *rsa.PrivateKey, data string) string {
+	t.Helper()
+
+	digest := sha1.Sum([]byte(data))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA1, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+func TestVerifySignature(t *testing.T) {
+	key := generateRSAKey(t)
+	other := generateRSAKey(t)
+
+	data := `{"orderId":"GPA.1234","packageName":"com.example.app","productId":"coins_100","purchaseTime":1700000000000,"purchaseState":0,"purchaseToken":"token","acknowledged":false}`
+	multi := `{"orderId":"GPA.5678","packageName":"com.example.app","productIds":["coins_100"],"purchaseState":0,"purchaseToken":"token2","quantity":3}`
+
+	tests := []struct {
+		name         string
+		publicKey    string
+		data         string
+		signature    string
+		wantErr      error
+		wantProduct  string
+		wantQuantity int
+	}{
+		{
+			name:         "valid",
+			publicKey:    licenseKey(t, key),
+			data:         data,
+			signature:    signSHA1(t, key, data),
+			wantProduct:  "coins_100",
+			wantQuantity: 1,
+		},
+		{
+			name:         "multi-quantity",
+			publicKey:    licenseKey(t, key),
+			data:         multi,
+			signature:    signSHA1(t, key, multi),
+			wantQuantity: 3,
+		},
+		{
+			name:      "other key",
+			publicKey: licenseKey(t, other),
+			data:      data,
+			signature: signSHA1(t, key, data),
+			wantErr:   playstore.ErrInvalidSignature,
+		},
+		{
+			name:      "tampered data",
+			publicKey: licenseKey(t, key),
+			data:      data + " ",
+			signature: signSHA1(t, key, data),
+			wantErr:   playstore.ErrInvalidSignature,
+		},
+		{
+			name:      "malformed signature",
+			publicKey: licenseKey(t, key),
+			data:      data,
+			signature: "!",
+			wantErr:   playstore.ErrInvalidSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := playstore.VerifySignature(tt.publicKey, tt.data, tt.signature)
+			if err != tt.wantErr {
+				t.Fatalf("VerifySignature() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if p.ProductID != tt.wantProduct || p.Quantity != tt.wantQuantity || p.PurchaseToken == "" {
+				t.Errorf("VerifySignature() = %+v", p)
+			}
+		})
+	}
+}
+
+func TestVerifySignatureMalformedKey(t *testing.T) {
+	for _, publicKey := range []string{"!", base64.StdEncoding.EncodeToString([]byte("key"))} {
+		if _, err := playstore.VerifySignature(publicKey, "{}", ""); err == nil {
+			t.Errorf("VerifySignature(%q) succeeded, want error", publicKey)
+		}
+	}
+}
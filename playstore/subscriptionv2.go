@@ -0,0 +1,197 @@
+package playstore
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// SubscriptionState is the data type for subscription states.
+type SubscriptionState string
+
+// List of subscription states.
+const (
+	SSUnspecified             SubscriptionState = "SUBSCRIPTION_STATE_UNSPECIFIED"
+	SSPending                 SubscriptionState = "SUBSCRIPTION_STATE_PENDING"
+	SSActive                  SubscriptionState = "SUBSCRIPTION_STATE_ACTIVE"
+	SSPaused                  SubscriptionState = "SUBSCRIPTION_STATE_PAUSED"
+	SSInGracePeriod           SubscriptionState = "SUBSCRIPTION_STATE_IN_GRACE_PERIOD"
+	SSOnHold                  SubscriptionState = "SUBSCRIPTION_STATE_ON_HOLD"
+	SSCanceled                SubscriptionState = "SUBSCRIPTION_STATE_CANCELED"
+	SSExpired                 SubscriptionState = "SUBSCRIPTION_STATE_EXPIRED"
+	SSPendingPurchaseCanceled SubscriptionState = "SUBSCRIPTION_STATE_PENDING_PURCHASE_CANCELED"
+)
+
+// AcknowledgementStateV2 is the data type for the acknowledgement states of
+// SubscriptionPurchaseV2.
+type AcknowledgementStateV2 string
+
+// List of acknowledgement states of SubscriptionPurchaseV2.
+const (
+	ASUnspecified  AcknowledgementStateV2 = "ACKNOWLEDGEMENT_STATE_UNSPECIFIED"
+	ASPending      AcknowledgementStateV2 = "ACKNOWLEDGEMENT_STATE_PENDING"
+	ASAcknowledged AcknowledgementStateV2 = "ACKNOWLEDGEMENT_STATE_ACKNOWLEDGED"
+)
+
+// SubscriptionPurchaseV2 indicates the status of a subscription purchase,
+// including its base plans, offers and prepaid plans.
+//
+// RegionCode is the ISO 3166-1 alpha-2 billing region of the user at the time
+// the subscription was acquired.
+type SubscriptionPurchaseV2 struct {
+	Kind                       string                         `json:"kind"`
+	RegionCode                 string                         `json:"regionCode"`
+	LineItems                  []SubscriptionPurchaseLineItem `json:"lineItems"`
+	StartTime                  time.Time                      `json:"startTime"`
+	SubscriptionState          SubscriptionState              `json:"subscriptionState"`
+	LatestOrderID              string                         `json:"latestOrderId"`
+	LinkedPurchaseToken        string                         `json:"linkedPurchaseToken"`
+	PausedStateContext         *PausedStateContext            `json:"pausedStateContext"`
+	CanceledStateContext       *CanceledStateContext          `json:"canceledStateContext"`
+	TestPurchase               *struct{}                      `json:"testPurchase"`
+	AcknowledgementState       AcknowledgementStateV2         `json:"acknowledgementState"`
+	ExternalAccountIdentifiers *ExternalAccountIdentifiers    `json:"externalAccountIdentifiers"`
+	SubscribeWithGoogleInfo    *SubscribeWithGoogleInfo       `json:"subscribeWithGoogleInfo"`
+}
+
+// SubscriptionPurchaseLineItem is a subscription item of a subscription
+// purchase.
+type SubscriptionPurchaseLineItem struct {
+	ProductID               string                   `json:"productId"`
+	ExpiryTime              time.Time                `json:"expiryTime"`
+	AutoRenewingPlan        *AutoRenewingPlan        `json:"autoRenewingPlan"`
+	PrepaidPlan             *PrepaidPlan             `json:"prepaidPlan"`
+	OfferDetails            *OfferDetails            `json:"offerDetails"`
+	OfferPhase              *OfferPhase              `json:"offerPhase"`
+	DeferredItemReplacement *DeferredItemReplacement `json:"deferredItemReplacement"`
+	LatestSuccessfulOrderID string                   `json:"latestSuccessfulOrderId"`
+}
+
+// AutoRenewingPlan is the plan of an auto-renewing line item.
+type AutoRenewingPlan struct {
+	AutoRenewEnabled bool   `json:"autoRenewEnabled"`
+	RecurringPrice   *Money `json:"recurringPrice"`
+}
+
+// PrepaidPlan is the plan of a prepaid line item.
+type PrepaidPlan struct {
+	AllowExtendAfterTime time.Time `json:"allowExtendAfterTime"`
+}
+
+// OfferDetails identifies the base plan and offer a line item was purchased
+// with.
+type OfferDetails struct {
+	BasePlanID string   `json:"basePlanId"`
+	OfferID    string   `json:"offerId"`
+	OfferTags  []string `json:"offerTags"`
+}
+
+// OfferPhase is the pricing phase a line item is currently in. Exactly one of
+// the fields is set.
+type OfferPhase struct {
+	BasePlan          *struct{} `json:"basePlan"`
+	FreeTrial         *struct{} `json:"freeTrial"`
+	IntroductoryPrice *struct{} `json:"introductoryPrice"`
+	ProrationPeriod   *struct{} `json:"prorationPeriod"`
+}
+
+// DeferredItemReplacement is the item a line item is replaced with at its
+// next renewal.
+type DeferredItemReplacement struct {
+	ProductID string `json:"productId"`
+}
+
+// PausedStateContext is set while a subscription is paused.
+type PausedStateContext struct {
+	AutoResumeTime time.Time `json:"autoResumeTime"`
+}
+
+// CanceledStateContext describes how a subscription was canceled. Exactly one
+// of the cancellation fields is set.
+type CanceledStateContext struct {
+	UserInitiatedCancellation      *UserInitiatedCancellation `json:"userInitiatedCancellation"`
+	SystemInitiatedCancellation    *struct{}                  `json:"systemInitiatedCancellation"`
+	DeveloperInitiatedCancellation *struct{}                  `json:"developerInitiatedCancellation"`
+	ReplacementCancellation        *struct{}                  `json:"replacementCancellation"`
+}
+
+// UserInitiatedCancellation is the info about a cancellation by the user.
+type UserInitiatedCancellation struct {
+	CancelSurveyResult *CancelSurveyResultV2 `json:"cancelSurveyResult"`
+	CancelTime         time.Time             `json:"cancelTime"`
+}
+
+// CancelSurveyResultV2 is the info provided by the user when they complete
+// the subscription cancellation flow.
+type CancelSurveyResultV2 struct {
+	Reason          string `json:"reason"`
+	ReasonUserInput string `json:"reasonUserInput"`
+}
+
+// ExternalAccountIdentifiers are the user account identifiers set at purchase
+// time.
+type ExternalAccountIdentifiers struct {
+	ExternalAccountID           string `json:"externalAccountId"`
+	ObfuscatedExternalAccountID string `json:"obfuscatedExternalAccountId"`
+	ObfuscatedExternalProfileID string `json:"obfuscatedExternalProfileId"`
+}
+
+// SubscribeWithGoogleInfo is the user profile of a subscription purchased
+// with Subscribe with Google.
+type SubscribeWithGoogleInfo struct {
+	ProfileID    string `json:"profileId"`
+	ProfileName  string `json:"profileName"`
+	EmailAddress string `json:"emailAddress"`
+	GivenName    string `json:"givenName"`
+	FamilyName   string `json:"familyName"`
+}
+
+// LineItem returns the line item of the given subscription product, or nil if
+// there is none.
+func (s *SubscriptionPurchaseV2) LineItem(productID string) *SubscriptionPurchaseLineItem {
+	for i := range s.LineItems {
+		if s.LineItems[i].ProductID == productID {
+			return &s.LineItems[i]
+		}
+	}
+
+	return nil
+}
+
+// ExpiryTime returns the latest expiry time of the line items.
+func (s *SubscriptionPurchaseV2) ExpiryTime() time.Time {
+	var t time.Time
+	for _, li := range s.LineItems {
+		if li.ExpiryTime.After(t) {
+			t = li.ExpiryTime
+		}
+	}
+
+	return t
+}
+
+// GetSubscriptionV2 checks the status of a subscription purchase. Unlike
+// GetSubscription, it does not need the subscription ID and reports base
+// plans, offers and prepaid plans.
+func (c *Client) GetSubscriptionV2(pkg, token string) (*SubscriptionPurchaseV2, error) {
+	return c.GetSubscriptionV2Context(context.Background(), pkg, token)
+}
+
+// GetSubscriptionV2Context is like GetSubscriptionV2 but takes a context.
+func (c *Client) GetSubscriptionV2Context(ctx context.Context, pkg, token string) (*SubscriptionPurchaseV2, error) {
+	url := fmt.Sprintf(
+		"%s/applications/%s/purchases/subscriptionsv2/tokens/%s",
+		c.baseURL(),
+		url.PathEscape(pkg),
+		url.PathEscape(token),
+	)
+
+	var s SubscriptionPurchaseV2
+	if err := c.do(ctx, http.MethodGet, url, nil, &s); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
@@ -0,0 +1,128 @@
+package playstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/brainleap/iap"
+)
+
+// Verifier adapts Client to the iap.Verifier interface.
+type Verifier struct {
+	Client      *Client
+	PackageName string
+}
+
+// NewVerifier creates a new PlayStore verifier for the given package.
+func NewVerifier(c *Client, pkg string) *Verifier {
+	return &Verifier{Client: c, PackageName: pkg}
+}
+
+// Verify validates an in-app product or subscription purchase.
+func (v *Verifier) Verify(r *iap.Request) (*iap.Purchase, error) {
+	return v.VerifyContext(context.Background(), r)
+}
+
+// VerifyContext is like Verify but takes a context.
+func (v *Verifier) VerifyContext(ctx context.Context, r *iap.Request) (*iap.Purchase, error) {
+	if r.Kind == iap.KindSubscription {
+		s, test, err := v.Client.getSubscription(ctx, v.PackageName, r.ProductID, r.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		return subscriptionPurchase(r.ProductID, s, test, time.Now()), nil
+	}
+
+	p, test, err := v.Client.getProduct(ctx, v.PackageName, r.ProductID, r.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	return productPurchase(r.ProductID, p, test), nil
+}
+
+func productPurchase(prod string, p *Product, test bool) *iap.Purchase {
+	var state iap.State
+	switch p.PurchaseState {
+	case PurchaseDone:
+		state = iap.StatePurchased
+	case PurchaseCanceled:
+		state = iap.StateCanceled
+	case PurchasePending:
+		state = iap.StatePending
+	}
+
+	return &iap.Purchase{
+		Store:         iap.PlayStore,
+		Kind:          iap.KindProduct,
+		ProductID:     prod,
+		TransactionID: p.OrderID,
+		PurchaseTime:  iap.Millis(p.PurchaseTimeMillis),
+		State:         state,
+		Environment:   environment(test),
+		Raw:           p,
+	}
+}
+
+func subscriptionPurchase(sub string, s *Subscription, test bool, now time.Time) *iap.Purchase {
+	state := iap.StatePurchased
+	if !iap.Millis(s.ExpiryTimeMillis).After(now) {
+		state = iap.StateExpired
+	}
+
+	return &iap.Purchase{
+		Store:         iap.PlayStore,
+		Kind:          iap.KindSubscription,
+		ProductID:     sub,
+		TransactionID: s.OrderID,
+		PurchaseTime:  iap.Millis(s.StartTimeMillis),
+		ExpiryTime:    iap.Millis(s.ExpiryTimeMillis),
+		State:         state,
+		Environment:   environment(test),
+		Raw:           s,
+	}
+}
+
+func environment(test bool) iap.Environment {
+	if test {
+		return iap.EnvironmentSandbox
+	}
+
+	return iap.EnvironmentProduction
+}
+
+// Extender adapts Client to the iap.Extender interface by deferring the
+// expiry of subscriptions.
+type Extender struct {
+	Client      *Client
+	PackageName string
+}
+
+// NewExtender creates a new PlayStore extender for the given package.
+func NewExtender(c *Client, pkg string) *Extender {
+	return &Extender{Client: c, PackageName: pkg}
+}
+
+// Extend defers the expiry of a subscription by the given number of days and
+// returns the new expiry time.
+func (e *Extender) Extend(r *iap.Request, days int) (time.Time, error) {
+	return e.ExtendContext(context.Background(), r, days)
+}
+
+// ExtendContext is like Extend but takes a context.
+func (e *Extender) ExtendContext(ctx context.Context, r *iap.Request, days int) (time.Time, error) {
+	s, err := e.Client.GetSubscriptionContext(ctx, e.PackageName, r.ProductID, r.Token)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	desired := s.ExpiryTimeMillis + int64(days)*int64(24*time.Hour/time.Millisecond)
+
+	expiry, err := e.Client.DeferSubscriptionContext(ctx, e.PackageName, r.ProductID, r.Token, s.ExpiryTimeMillis, desired)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return iap.Millis(expiry), nil
+}
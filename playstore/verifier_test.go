@@ -0,0 +1,192 @@
+package playstore_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/brainleap/iap"
+	"github.com/brainleap/iap/iaptest"
+	"github.com/brainleap/iap/playstore"
+)
+
+func TestVerifier(t *testing.T) {
+	now := time.Now()
+	ms := func(d time.Duration) int64 {
+		return now.Add(d).UnixNano() / int64(time.Millisecond)
+	}
+
+	store := iaptest.NewPlayStore()
+	defer store.Close()
+
+	store.AddProduct(testPackage, "coins_100", "product", &playstore.Product{OrderID: "GPA.1", PurchaseTimeMillis: ms(-time.Hour)})
+	store.AddProduct(testPackage, "coins_100", "canceled", &playstore.Product{OrderID: "GPA.2", PurchaseState: playstore.PurchaseCanceled})
+	store.AddProduct(testPackage, "coins_100", "pending", &playstore.Product{OrderID: "GPA.3", PurchaseState: playstore.PurchasePending})
+	store.AddProduct(testPackage, "coins_100", "test product", &playstore.Product{OrderID: "GPA.4"})
+	store.AddProduct(testPackage, "coins_100", "promo", &playstore.Product{OrderID: "GPA.5", PurchaseType: playstore.PTPromo})
+	store.SetTestPurchase("test product")
+
+	store.AddSubscription(testPackage, "monthly", "subscription", &playstore.Subscription{
+		OrderID:          "GPA.6",
+		StartTimeMillis:  ms(-time.Hour),
+		ExpiryTimeMillis: ms(24 * time.Hour),
+		AutoRenewing:     true,
+	})
+	store.AddSubscription(testPackage, "monthly", "expired", &playstore.Subscription{OrderID: "GPA.7", ExpiryTimeMillis: ms(-time.Hour)})
+	store.AddSubscription(testPackage, "monthly", "test subscription", &playstore.Subscription{OrderID: "GPA.8", ExpiryTimeMillis: ms(24 * time.Hour)})
+	store.SetTestPurchase("test subscription")
+
+	c, err := store.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := playstore.NewVerifier(c, testPackage)
+
+	tests := []struct {
+		name              string
+		req               iap.Request
+		wantTransactionID string
+		wantState         iap.State
+		wantEnvironment   iap.Environment
+		wantExpiry        int64
+		wantErr           error
+	}{
+		{
+			name:              "product",
+			req:               iap.Request{Kind: iap.KindProduct, ProductID: "coins_100", Token: "product"},
+			wantTransactionID: "GPA.1",
+			wantState:         iap.StatePurchased,
+			wantEnvironment:   iap.EnvironmentProduction,
+		},
+		{
+			name:              "canceled product",
+			req:               iap.Request{Kind: iap.KindProduct, ProductID: "coins_100", Token: "canceled"},
+			wantTransactionID: "GPA.2",
+			wantState:         iap.StateCanceled,
+			wantEnvironment:   iap.EnvironmentProduction,
+		},
+		{
+			name:              "pending product",
+			req:               iap.Request{Kind: iap.KindProduct, ProductID: "coins_100", Token: "pending"},
+			wantTransactionID: "GPA.3",
+			wantState:         iap.StatePending,
+			wantEnvironment:   iap.EnvironmentProduction,
+		},
+		{
+			name:              "test product",
+			req:               iap.Request{Kind: iap.KindProduct, ProductID: "coins_100", Token: "test product"},
+			wantTransactionID: "GPA.4",
+			wantState:         iap.StatePurchased,
+			wantEnvironment:   iap.EnvironmentSandbox,
+		},
+		{
+			name:              "promo product",
+			req:               iap.Request{Kind: iap.KindProduct, ProductID: "coins_100", Token: "promo"},
+			wantTransactionID: "GPA.5",
+			wantState:         iap.StatePurchased,
+			wantEnvironment:   iap.EnvironmentProduction,
+		},
+		{
+			name:              "subscription",
+			req:               iap.Request{Kind: iap.KindSubscription, ProductID: "monthly", Token: "subscription"},
+			wantTransactionID: "GPA.6",
+			wantState:         iap.StatePurchased,
+			wantEnvironment:   iap.EnvironmentProduction,
+			wantExpiry:        ms(24 * time.Hour),
+		},
+		{
+			name:              "expired subscription",
+			req:               iap.Request{Kind: iap.KindSubscription, ProductID: "monthly", Token: "expired"},
+			wantTransactionID: "GPA.7",
+			wantState:         iap.StateExpired,
+			wantEnvironment:   iap.EnvironmentProduction,
+			wantExpiry:        ms(-time.Hour),
+		},
+		{
+			name:              "test subscription",
+			req:               iap.Request{Kind: iap.KindSubscription, ProductID: "monthly", Token: "test subscription"},
+			wantTransactionID: "GPA.8",
+			wantState:         iap.StatePurchased,
+			wantEnvironment:   iap.EnvironmentSandbox,
+			wantExpiry:        ms(24 * time.Hour),
+		},
+		{
+			name:    "unknown token",
+			req:     iap.Request{Kind: iap.KindProduct, ProductID: "coins_100", Token: "unknown"},
+			wantErr: iap.ErrPurchaseNotFound,
+		},
+		{
+			name:    "other product",
+			req:     iap.Request{Kind: iap.KindProduct, ProductID: "coins_500", Token: "product"},
+			wantErr: iap.ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := v.Verify(&tt.req)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+
+			if p.Store != iap.PlayStore || p.Kind != tt.req.Kind || p.ProductID != tt.req.ProductID || p.TransactionID != tt.wantTransactionID {
+				t.Errorf("Verify() = %+v", p)
+			}
+
+			if p.State != tt.wantState {
+				t.Errorf("State = %d, want %d", p.State, tt.wantState)
+			}
+
+			if p.Environment != tt.wantEnvironment {
+				t.Errorf("Environment = %d, want %d", p.Environment, tt.wantEnvironment)
+			}
+
+			if !p.ExpiryTime.Equal(iap.Millis(tt.wantExpiry)) {
+				t.Errorf("ExpiryTime = %v, want %v", p.ExpiryTime, iap.Millis(tt.wantExpiry))
+			}
+		})
+	}
+}
+
+func TestGetProductPurchaseType(t *testing.T) {
+	store := iaptest.NewPlayStore()
+	defer store.Close()
+
+	store.AddProduct(testPackage, "coins_100", "test", &playstore.Product{})
+	store.AddProduct(testPackage, "coins_100", "promo", &playstore.Product{PurchaseType: playstore.PTPromo})
+	store.SetTestPurchase("test")
+
+	c, err := store.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		token string
+		want  playstore.PurchaseType
+	}{
+		{token: "test", want: playstore.PTTest},
+		{token: "promo", want: playstore.PTPromo},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			p, err := c.GetProduct(testPackage, "coins_100", tt.token)
+			if err != nil {
+				t.Fatalf("GetProduct() error = %v", err)
+			}
+
+			if p.PurchaseType != tt.want {
+				t.Errorf("PurchaseType = %d, want %d", p.PurchaseType, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package playstore
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// VoidedSource is the data type for the initiators of voided purchases.
+type VoidedSource int
+
+// List of voided sources.
+const (
+	VSUser      VoidedSource = 0
+	VSDeveloper VoidedSource = 1
+	VSGoogle    VoidedSource = 2
+)
+
+// VoidedReason is the data type for the reasons purchases were voided.
+type VoidedReason int
+
+// List of voided reasons.
+const (
+	VROther              VoidedReason = 0
+	VRRemorse            VoidedReason = 1
+	VRNotReceived        VoidedReason = 2
+	VRDefective          VoidedReason = 3
+	VRAccidentalPurchase VoidedReason = 4
+	VRFraud              VoidedReason = 5
+	VRFriendlyFraud      VoidedReason = 6
+	VRChargeback         VoidedReason = 7
+)
+
+// VoidedQueryType is the data type for the kinds of voided purchases to list.
+type VoidedQueryType int
+
+// List of voided query types.
+const (
+	VQInApp                 VoidedQueryType = 0
+	VQInAppAndSubscriptions VoidedQueryType = 1
+)
+
+// VoidedQuery filters a voided purchases request. Zero values are omitted,
+// in which case the API lists the voided in-app purchases of the last 30
+// days.
+type VoidedQuery struct {
+	StartTimeMillis int64
+	EndTimeMillis   int64
+	Type            VoidedQueryType
+	MaxResults      int
+}
+
+func (q *VoidedQuery) values() url.Values {
+	v := url.Values{}
+	if q == nil {
+		return v
+	}
+
+	if q.StartTimeMillis != 0 {
+		v.Set("startTime", strconv.FormatInt(q.StartTimeMillis, 10))
+	}
+	if q.EndTimeMillis != 0 {
+		v.Set("endTime", strconv.FormatInt(q.EndTimeMillis, 10))
+	}
+	if q.Type != VQInApp {
+		v.Set("type", strconv.Itoa(int(q.Type)))
+	}
+	if q.MaxResults != 0 {
+		v.Set("maxResults", strconv.Itoa(q.MaxResults))
+	}
+
+	return v
+}
+
+// VoidedPurchase is a purchase that was canceled, refunded or charged back.
+type VoidedPurchase struct {
+	Kind               string       `json:"kind"`
+	PurchaseToken      string       `json:"purchaseToken"`
+	PurchaseTimeMillis int64        `json:"purchaseTimeMillis,string"`
+	VoidedTimeMillis   int64        `json:"voidedTimeMillis,string"`
+	OrderID            string       `json:"orderId"`
+	VoidedSource       VoidedSource `json:"voidedSource"`
+	VoidedReason       VoidedReason `json:"voidedReason"`
+	VoidedQuantity     int          `json:"voidedQuantity"`
+}
+
+// VoidedPurchasesResponse is a page of voided purchases. Pass
+// TokenPagination.NextPageToken to the next call to fetch the following page.
+type VoidedPurchasesResponse struct {
+	PageInfo        *PageInfo        `json:"pageInfo"`
+	TokenPagination *TokenPagination `json:"tokenPagination"`
+	VoidedPurchases []VoidedPurchase `json:"voidedPurchases"`
+}
+
+// ListVoidedPurchases lists a page of the voided purchases of a package.
+func (c *Client) ListVoidedPurchases(pkg string, q *VoidedQuery, pageToken string) (*VoidedPurchasesResponse, error) {
+	return c.ListVoidedPurchasesContext(context.Background(), pkg, q, pageToken)
+}
+
+// ListVoidedPurchasesContext is like ListVoidedPurchases but takes a context.
+func (c *Client) ListVoidedPurchasesContext(ctx context.Context, pkg string, q *VoidedQuery, pageToken string) (*VoidedPurchasesResponse, error) {
+	v := q.values()
+	if pageToken != "" {
+		v.Set("token", pageToken)
+	}
+
+	u := fmt.Sprintf(
+		"%s/applications/%s/purchases/voidedpurchases",
+		c.baseURL(),
+		url.PathEscape(pkg),
+	)
+
+	var r VoidedPurchasesResponse
+	if err := c.do(ctx, http.MethodGet, withQuery(u, v), nil, &r); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// ListAllVoidedPurchases lists the voided purchases of a package, following
+// the pagination tokens until all pages are fetched.
+func (c *Client) ListAllVoidedPurchases(pkg string, q *VoidedQuery) ([]VoidedPurchase, error) {
+	return c.ListAllVoidedPurchasesContext(context.Background(), pkg, q)
+}
+
+// ListAllVoidedPurchasesContext is like ListAllVoidedPurchases but takes a
+// context.
+func (c *Client) ListAllVoidedPurchasesContext(ctx context.Context, pkg string, q *VoidedQuery) ([]VoidedPurchase, error) {
+	var all []VoidedPurchase
+
+	token := ""
+	for {
+		r, err := c.ListVoidedPurchasesContext(ctx, pkg, q, token)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, r.VoidedPurchases...)
+
+		if r.TokenPagination == nil || r.TokenPagination.NextPageToken == "" {
+			return all, nil
+		}
+
+		token = r.TokenPagination.NextPageToken
+	}
+}
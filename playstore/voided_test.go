@@ -0,0 +1,108 @@
+package playstore_test
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/brainleap/iap/iaptest"
+	"github.com/brainleap/iap/playstore"
+)
+
+func TestListAllVoidedPurchases(t *testing.T) {
+	store := iaptest.NewPlayStore()
+	defer store.Close()
+
+	var products []string
+	for i := 0; i < 5; i++ {
+		token := "product-" + strconv.Itoa(i)
+		products = append(products, token)
+
+		store.AddProduct(testPackage, "coins_100", token, &playstore.Product{OrderID: "GPA." + strconv.Itoa(i)})
+		store.Refund(token)
+	}
+
+	store.AddSubscription(testPackage, "monthly", "subscription", &playstore.Subscription{OrderID: "GPA.5"})
+	store.Void("subscription", playstore.VSGoogle, playstore.VRChargeback)
+
+	store.AddProduct("com.example.other", "coins_100", "other", &playstore.Product{OrderID: "GPA.6"})
+	store.Refund("other")
+
+	c, err := store.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		q    *playstore.VoidedQuery
+		want []string
+	}{
+		{name: "single page", want: products},
+		{name: "several pages", q: &playstore.VoidedQuery{MaxResults: 2}, want: products},
+		{name: "one per page", q: &playstore.VoidedQuery{MaxResults: 1}, want: products},
+		{
+			name: "subscriptions",
+			q:    &playstore.VoidedQuery{Type: playstore.VQInAppAndSubscriptions, MaxResults: 4},
+			want: append(append([]string(nil), products...), "subscription"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			all, err := c.ListAllVoidedPurchases(testPackage, tt.q)
+			if err != nil {
+				t.Fatalf("ListAllVoidedPurchases() error = %v", err)
+			}
+
+			var got []string
+			for _, v := range all {
+				got = append(got, v.PurchaseToken)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListAllVoidedPurchases() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListVoidedPurchasesPageToken(t *testing.T) {
+	store := iaptest.NewPlayStore()
+	defer store.Close()
+
+	for i := 0; i < 3; i++ {
+		token := "product-" + strconv.Itoa(i)
+		store.AddProduct(testPackage, "coins_100", token, &playstore.Product{})
+		store.Refund(token)
+	}
+
+	c, err := store.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := &playstore.VoidedQuery{MaxResults: 2}
+
+	first, err := c.ListVoidedPurchases(testPackage, q, "")
+	if err != nil {
+		t.Fatalf("ListVoidedPurchases() error = %v", err)
+	}
+
+	if len(first.VoidedPurchases) != 2 || first.TokenPagination == nil || first.TokenPagination.NextPageToken == "" {
+		t.Fatalf("first page = %+v", first)
+	}
+
+	second, err := c.ListVoidedPurchases(testPackage, q, first.TokenPagination.NextPageToken)
+	if err != nil {
+		t.Fatalf("ListVoidedPurchases() error = %v", err)
+	}
+
+	if len(second.VoidedPurchases) != 1 || second.VoidedPurchases[0].PurchaseToken != "product-2" || second.TokenPagination != nil {
+		t.Errorf("second page = %+v", second)
+	}
+
+	if _, err := c.ListVoidedPurchases(testPackage, q, "invalid"); err == nil {
+		t.Error("ListVoidedPurchases() with invalid page token succeeded, want error")
+	}
+}